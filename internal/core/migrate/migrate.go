@@ -0,0 +1,174 @@
+// Package migrate applies ordered, versioned schema migrations to a SQLite
+// database. Each feature keeps its own version sequence in the shared
+// schema_migrations table, so repositories can evolve independently.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidMigration = errors.New("migrate: invalid migration")
+	ErrDatabaseTooNew   = errors.New("migrate: database schema is newer than this binary")
+)
+
+// Migration is one up-step of a feature's schema. Versions start at 1 and
+// must be contiguous.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+// SQL returns an Up function that executes stmts as a single script.
+func SQL(stmts string) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, stmts)
+		return err
+	}
+}
+
+// DatabaseTooNewError reports a database that was migrated by a newer build.
+type DatabaseTooNewError struct {
+	Feature         string
+	DatabaseVersion int
+	KnownVersion    int
+}
+
+func (e *DatabaseTooNewError) Error() string {
+	return fmt.Sprintf("migrate: %s schema is at version %d, this binary knows up to %d", e.Feature, e.DatabaseVersion, e.KnownVersion)
+}
+
+func (e *DatabaseTooNewError) Is(target error) bool { return target == ErrDatabaseTooNew }
+
+const schema = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	feature TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	applied_at_ms INTEGER NOT NULL,
+	PRIMARY KEY(feature, version)
+);
+`
+
+// Apply brings feature up to the last of migrations. Every pending migration
+// runs in its own transaction together with its bookkeeping row, so a failed
+// step leaves the database at the previous version.
+func Apply(ctx context.Context, db *sql.DB, feature string, migrations []Migration) error {
+	if db == nil {
+		return errors.New("migrate: db is nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	feature = strings.TrimSpace(feature)
+	if feature == "" {
+		return errors.Join(ErrInvalidMigration, errors.New("feature is required"))
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return errors.Join(ErrInvalidMigration, fmt.Errorf("%s: migration #%d has version %d", feature, i+1, m.Version))
+		}
+		if m.Up == nil {
+			return errors.Join(ErrInvalidMigration, fmt.Errorf("%s: migration %d has no up step", feature, m.Version))
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+	current, err := Version(ctx, db, feature)
+	if err != nil {
+		return err
+	}
+	if current > len(migrations) {
+		return &DatabaseTooNewError{Feature: feature, DatabaseVersion: current, KnownVersion: len(migrations)}
+	}
+	for _, m := range migrations[current:] {
+		if err := apply(ctx, db, feature, m); err != nil {
+			return fmt.Errorf("migrate: %s version %d (%s): %w", feature, m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, feature string, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := m.Up(ctx, tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations(feature, version, name, applied_at_ms) VALUES(?, ?, ?, ?)`,
+		feature, m.Version, m.Name, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Version returns the highest applied migration of feature, or 0 when none
+// has been recorded.
+func Version(ctx context.Context, db *sql.DB, feature string) (int, error) {
+	if db == nil {
+		return 0, errors.New("migrate: db is nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var exists int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists == 0 {
+		return 0, nil
+	}
+	var v sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations WHERE feature = ?`, feature).Scan(&v); err != nil {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+// EnsureColumn adds column to table unless it already exists. Migrations use
+// it so that databases which picked up the column before versioning was in
+// place still migrate cleanly.
+func EnsureColumn(ctx context.Context, tx *sql.Tx, table, column, def string) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return err
+	}
+	found := false
+	for rows.Next() {
+		var (
+			cid          int
+			name, typ    string
+			notNull, pk  int
+			defaultValue sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		if strings.EqualFold(name, column) {
+			found = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+	if found {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, def))
+	return err
+}
@@ -0,0 +1,111 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/Xiaoxinkeji/WX/internal/core/migrate"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	name := fmt.Sprintf("file:migrate_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := sql.Open("sqlite", name)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestApply_RunsPendingMigrationsInOrder(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	steps := []migrate.Migration{
+		{Version: 1, Name: "create", Up: migrate.SQL(`CREATE TABLE notes (id TEXT PRIMARY KEY);`)},
+		{Version: 2, Name: "add body", Up: func(ctx context.Context, tx *sql.Tx) error {
+			return migrate.EnsureColumn(ctx, tx, "notes", "body", "TEXT NOT NULL DEFAULT ''")
+		}},
+	}
+
+	if err := migrate.Apply(ctx, db, "notes", steps[:1]); err != nil {
+		t.Fatalf("apply v1: %v", err)
+	}
+	if v, err := migrate.Version(ctx, db, "notes"); err != nil || v != 1 {
+		t.Fatalf("expected version 1, got %d (%v)", v, err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO notes(id) VALUES('n1')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := migrate.Apply(ctx, db, "notes", steps); err != nil {
+			t.Fatalf("apply v2 (run %d): %v", i, err)
+		}
+	}
+	if v, _ := migrate.Version(ctx, db, "notes"); v != 2 {
+		t.Fatalf("expected version 2, got %d", v)
+	}
+	var body string
+	if err := db.QueryRowContext(ctx, `SELECT body FROM notes WHERE id = 'n1'`).Scan(&body); err != nil || body != "" {
+		t.Fatalf("expected migrated row, got %q (%v)", body, err)
+	}
+	if v, _ := migrate.Version(ctx, db, "other"); v != 0 {
+		t.Fatalf("features must be tracked separately, got %d", v)
+	}
+}
+
+func TestApply_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	steps := []migrate.Migration{
+		{Version: 1, Name: "create", Up: migrate.SQL(`CREATE TABLE notes (id TEXT PRIMARY KEY);`)},
+		{Version: 2, Name: "broken", Up: migrate.SQL(`CREATE TABLE extra (id TEXT); SELECT * FROM missing;`)},
+	}
+	if err := migrate.Apply(ctx, db, "notes", steps); err == nil {
+		t.Fatalf("expected error")
+	}
+	if v, _ := migrate.Version(ctx, db, "notes"); v != 1 {
+		t.Fatalf("expected version 1 after failure, got %d", v)
+	}
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'extra'`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("expected failed step to be rolled back, got %d (%v)", n, err)
+	}
+}
+
+func TestApply_RejectsNewerDatabaseAndBadMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	steps := []migrate.Migration{
+		{Version: 1, Name: "one", Up: migrate.SQL(`CREATE TABLE a (id TEXT);`)},
+		{Version: 2, Name: "two", Up: migrate.SQL(`CREATE TABLE b (id TEXT);`)},
+	}
+	if err := migrate.Apply(ctx, db, "f", steps); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	err := migrate.Apply(ctx, db, "f", steps[:1])
+	var tooNew *migrate.DatabaseTooNewError
+	if !errors.Is(err, migrate.ErrDatabaseTooNew) || !errors.As(err, &tooNew) {
+		t.Fatalf("expected ErrDatabaseTooNew, got %v", err)
+	}
+	if tooNew.DatabaseVersion != 2 || tooNew.KnownVersion != 1 {
+		t.Fatalf("unexpected error details: %+v", tooNew)
+	}
+
+	gap := []migrate.Migration{steps[0], {Version: 3, Name: "three", Up: steps[1].Up}}
+	if err := migrate.Apply(ctx, db, "g", gap); !errors.Is(err, migrate.ErrInvalidMigration) {
+		t.Fatalf("expected ErrInvalidMigration for gap, got %v", err)
+	}
+	if err := migrate.Apply(ctx, db, "g", []migrate.Migration{{Version: 1}}); !errors.Is(err, migrate.ErrInvalidMigration) {
+		t.Fatalf("expected ErrInvalidMigration for missing up, got %v", err)
+	}
+}
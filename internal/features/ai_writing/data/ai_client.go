@@ -26,6 +26,7 @@ type OpenAIClient struct {
 	HTTPClient   HTTPDoer
 	DefaultModel string
 	UserAgent    string
+	Retry        RetryPolicy
 }
 
 func (c OpenAIClient) ProviderName() string { return "openai" }
@@ -49,6 +50,22 @@ type openAIChatRequest struct {
 	Temperature float64         `json:"temperature,omitempty"`
 	MaxTokens   int             `json:"max_tokens,omitempty"`
 	Stream      bool            `json:"stream,omitempty"`
+	// StreamOptions asks for a final chunk carrying token usage.
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func (u openAIUsage) toDomain() domain.TokenUsage {
+	return domain.TokenUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
 }
 
 type openAIChatResponse struct {
@@ -57,11 +74,7 @@ type openAIChatResponse struct {
 		Message      openAIMessage `json:"message"`
 		FinishReason string        `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage openAIUsage `json:"usage"`
 }
 
 type openAIStreamChunk struct {
@@ -72,6 +85,7 @@ type openAIStreamChunk struct {
 		} `json:"delta"`
 		FinishReason *string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
 }
 
 func (c OpenAIClient) doChat(ctx context.Context, req domain.ChatRequest, onDelta func(delta string) error) (domain.ChatResponse, error) {
@@ -95,6 +109,9 @@ func (c OpenAIClient) doChat(ctx context.Context, req domain.ChatRequest, onDelt
 		MaxTokens:   req.MaxTokens,
 		Stream:      onDelta != nil,
 	}
+	if payload.Stream {
+		payload.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
 
 	b, err := json.Marshal(payload)
 	if err != nil {
@@ -106,37 +123,29 @@ func (c OpenAIClient) doChat(ctx context.Context, req domain.ChatRequest, onDelt
 		return domain.ChatResponse{}, err
 	}
 
-	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(b))
+	resp, err := sendWithRetry(ctx, client, c.Retry, c.ProviderName(), func() (*http.Request, error) {
+		hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		hreq.Header.Set("Content-Type", "application/json")
+		if ua := strings.TrimSpace(c.UserAgent); ua != "" {
+			hreq.Header.Set("User-Agent", ua)
+		}
+		if key := strings.TrimSpace(c.APIKey); key != "" {
+			hreq.Header.Set("Authorization", "Bearer "+key)
+		}
+		return hreq, nil
+	})
 	if err != nil {
 		return domain.ChatResponse{}, err
 	}
-	hreq.Header.Set("Content-Type", "application/json")
-	if ua := strings.TrimSpace(c.UserAgent); ua != "" {
-		hreq.Header.Set("User-Agent", ua)
-	}
-	if key := strings.TrimSpace(c.APIKey); key != "" {
-		hreq.Header.Set("Authorization", "Bearer "+key)
-	}
-
-	resp, err := client.Do(hreq)
-	if err != nil {
-		return domain.ChatResponse{}, errors.Join(domain.ErrProvider, err)
-	}
 	defer func() {
 		// Ensure response body is fully read before closing to allow connection reuse
 		io.Copy(io.Discard, resp.Body)
 		resp.Body.Close()
 	}()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		msg := readSmallBody(resp.Body, 1024) // Reduced from 64KB to 1KB
-		if msg == "" {
-			msg = resp.Status
-		}
-		// Log detailed error but return generic message to user
-		return domain.ChatResponse{}, errors.Join(domain.ErrProvider, fmt.Errorf("openai: status %d", resp.StatusCode))
-	}
-
 	if onDelta == nil {
 		var out openAIChatResponse
 		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
@@ -152,11 +161,7 @@ func (c OpenAIClient) doChat(ctx context.Context, req domain.ChatRequest, onDelt
 			Model:        out.Model,
 			Content:      content,
 			FinishReason: finish,
-			Usage: domain.TokenUsage{
-				PromptTokens:     out.Usage.PromptTokens,
-				CompletionTokens: out.Usage.CompletionTokens,
-				TotalTokens:      out.Usage.TotalTokens,
-			},
+			Usage:        out.Usage.toDomain(),
 		}, nil
 	}
 
@@ -176,6 +181,7 @@ func streamOpenAI(r io.Reader, providerName, model string, onDelta func(delta st
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
 	var content strings.Builder
+	var usage domain.TokenUsage
 	finishReason := ""
 	modelOut := model
 
@@ -197,6 +203,10 @@ func streamOpenAI(r io.Reader, providerName, model string, onDelta func(delta st
 			if chunk.Model != "" {
 				modelOut = chunk.Model
 			}
+			// With include_usage the last chunk has usage and no choices.
+			if chunk.Usage != nil {
+				usage = chunk.Usage.toDomain()
+			}
 			if len(chunk.Choices) == 0 {
 				continue
 			}
@@ -221,18 +231,20 @@ func streamOpenAI(r io.Reader, providerName, model string, onDelta func(delta st
 		Model:        modelOut,
 		Content:      content.String(),
 		FinishReason: finishReason,
+		Usage:        usage,
 	}, nil
 }
 
 type ClaudeClient struct {
-	BaseURL           string
-	APIKey            string
-	HTTPClient        HTTPDoer
-	DefaultModel      string
-	AnthropicVersion  string
-	UserAgent         string
-	DefaultMaxTokens  int
+	BaseURL            string
+	APIKey             string
+	HTTPClient         HTTPDoer
+	DefaultModel       string
+	AnthropicVersion   string
+	UserAgent          string
+	DefaultMaxTokens   int
 	DefaultTemperature float64
+	Retry              RetryPolicy
 }
 
 func (c ClaudeClient) ProviderName() string { return "claude" }
@@ -251,12 +263,12 @@ type claudeMessage struct {
 }
 
 type claudeChatRequest struct {
-	Model       string         `json:"model"`
-	MaxTokens   int            `json:"max_tokens"`
+	Model       string          `json:"model"`
+	MaxTokens   int             `json:"max_tokens"`
 	Messages    []claudeMessage `json:"messages"`
-	System      string         `json:"system,omitempty"`
-	Temperature float64        `json:"temperature,omitempty"`
-	Stream      bool           `json:"stream,omitempty"`
+	System      string          `json:"system,omitempty"`
+	Temperature float64         `json:"temperature,omitempty"`
+	Stream      bool            `json:"stream,omitempty"`
 }
 
 type claudeChatResponse struct {
@@ -266,10 +278,12 @@ type claudeChatResponse struct {
 		Type string `json:"type"`
 		Text string `json:"text"`
 	} `json:"content"`
-	Usage struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	Usage claudeUsage `json:"usage"`
+}
+
+type claudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 func (c ClaudeClient) doChat(ctx context.Context, req domain.ChatRequest, onDelta func(delta string) error) (domain.ChatResponse, error) {
@@ -316,40 +330,33 @@ func (c ClaudeClient) doChat(ctx context.Context, req domain.ChatRequest, onDelt
 		return domain.ChatResponse{}, err
 	}
 
-	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(b))
+	resp, err := sendWithRetry(ctx, client, c.Retry, c.ProviderName(), func() (*http.Request, error) {
+		hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		hreq.Header.Set("Content-Type", "application/json")
+		if ua := strings.TrimSpace(c.UserAgent); ua != "" {
+			hreq.Header.Set("User-Agent", ua)
+		}
+		if key := strings.TrimSpace(c.APIKey); key != "" {
+			hreq.Header.Set("x-api-key", key)
+		}
+		version := strings.TrimSpace(c.AnthropicVersion)
+		if version == "" {
+			version = "2023-06-01"
+		}
+		hreq.Header.Set("anthropic-version", version)
+		return hreq, nil
+	})
 	if err != nil {
 		return domain.ChatResponse{}, err
 	}
-	hreq.Header.Set("Content-Type", "application/json")
-	if ua := strings.TrimSpace(c.UserAgent); ua != "" {
-		hreq.Header.Set("User-Agent", ua)
-	}
-	if key := strings.TrimSpace(c.APIKey); key != "" {
-		hreq.Header.Set("x-api-key", key)
-	}
-	version := strings.TrimSpace(c.AnthropicVersion)
-	if version == "" {
-		version = "2023-06-01"
-	}
-	hreq.Header.Set("anthropic-version", version)
-
-	resp, err := client.Do(hreq)
-	if err != nil {
-		return domain.ChatResponse{}, errors.Join(domain.ErrProvider, err)
-	}
 	defer func() {
 		io.Copy(io.Discard, resp.Body)
 		resp.Body.Close()
 	}()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		msg := readSmallBody(resp.Body, 1024)
-		if msg == "" {
-			msg = resp.Status
-		}
-		return domain.ChatResponse{}, errors.Join(domain.ErrProvider, fmt.Errorf("claude: status %d", resp.StatusCode))
-	}
-
 	if onDelta == nil {
 		var out claudeChatResponse
 		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
@@ -393,6 +400,7 @@ type GeminiClient struct {
 	HTTPClient   HTTPDoer
 	DefaultModel string
 	UserAgent    string
+	Retry        RetryPolicy
 }
 
 func (c GeminiClient) ProviderName() string { return "gemini" }
@@ -420,7 +428,7 @@ type geminiGenerationConfig struct {
 }
 
 type geminiChatRequest struct {
-	Contents          []geminiContent          `json:"contents"`
+	Contents          []geminiContent         `json:"contents"`
 	SystemInstruction *geminiContent          `json:"system_instruction,omitempty"`
 	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
 }
@@ -434,11 +442,24 @@ type geminiChatResponse struct {
 		} `json:"content"`
 		FinishReason string `json:"finishReason"`
 	} `json:"candidates"`
-	UsageMetadata struct {
-		PromptTokenCount     int `json:"promptTokenCount"`
-		CandidatesTokenCount int `json:"candidatesTokenCount"`
-		TotalTokenCount      int `json:"totalTokenCount"`
-	} `json:"usageMetadata"`
+	UsageMetadata *geminiUsageMetadata `json:"usageMetadata"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+func (u *geminiUsageMetadata) toDomain() domain.TokenUsage {
+	if u == nil {
+		return domain.TokenUsage{}
+	}
+	total := u.TotalTokenCount
+	if total == 0 {
+		total = u.PromptTokenCount + u.CandidatesTokenCount
+	}
+	return domain.TokenUsage{PromptTokens: u.PromptTokenCount, CompletionTokens: u.CandidatesTokenCount, TotalTokens: total}
 }
 
 func (c GeminiClient) doChat(ctx context.Context, req domain.ChatRequest, onDelta func(delta string) error) (domain.ChatResponse, error) {
@@ -481,37 +502,34 @@ func (c GeminiClient) doChat(ctx context.Context, req domain.ChatRequest, onDelt
 	if err != nil {
 		return domain.ChatResponse{}, err
 	}
+	if onDelta != nil {
+		// Without alt=sse the stream is a single JSON array, not SSE events.
+		urlStr = addQueryParam(urlStr, "alt", "sse")
+	}
 	urlStr, _ = addAPIKeyQueryParam(urlStr, c.APIKey)
 
-	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(b))
+	resp, err := sendWithRetry(ctx, client, c.Retry, c.ProviderName(), func() (*http.Request, error) {
+		hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		hreq.Header.Set("Content-Type", "application/json")
+		if ua := strings.TrimSpace(c.UserAgent); ua != "" {
+			hreq.Header.Set("User-Agent", ua)
+		}
+		if key := strings.TrimSpace(c.APIKey); key != "" {
+			hreq.Header.Set("x-goog-api-key", key)
+		}
+		return hreq, nil
+	})
 	if err != nil {
 		return domain.ChatResponse{}, err
 	}
-	hreq.Header.Set("Content-Type", "application/json")
-	if ua := strings.TrimSpace(c.UserAgent); ua != "" {
-		hreq.Header.Set("User-Agent", ua)
-	}
-	if key := strings.TrimSpace(c.APIKey); key != "" {
-		hreq.Header.Set("x-goog-api-key", key)
-	}
-
-	resp, err := client.Do(hreq)
-	if err != nil {
-		return domain.ChatResponse{}, errors.Join(domain.ErrProvider, err)
-	}
 	defer func() {
 		io.Copy(io.Discard, resp.Body)
 		resp.Body.Close()
 	}()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		msg := readSmallBody(resp.Body, 1024)
-		if msg == "" {
-			msg = resp.Status
-		}
-		return domain.ChatResponse{}, errors.Join(domain.ErrProvider, fmt.Errorf("gemini: status %d", resp.StatusCode))
-	}
-
 	if onDelta == nil {
 		var out geminiChatResponse
 		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
@@ -523,11 +541,7 @@ func (c GeminiClient) doChat(ctx context.Context, req domain.ChatRequest, onDelt
 			Model:        model,
 			Content:      content,
 			FinishReason: finish,
-			Usage: domain.TokenUsage{
-				PromptTokens:     out.UsageMetadata.PromptTokenCount,
-				CompletionTokens: out.UsageMetadata.CandidatesTokenCount,
-				TotalTokens:      out.UsageMetadata.TotalTokenCount,
-			},
+			Usage:        out.UsageMetadata.toDomain(),
 		}, nil
 	}
 
@@ -562,6 +576,7 @@ func extractGeminiContentAndFinish(out geminiChatResponse) (string, string) {
 
 func streamClaude(r io.Reader, providerName, model string, onDelta func(delta string) error) (domain.ChatResponse, error) {
 	var content strings.Builder
+	var usage domain.TokenUsage
 	finishReason := ""
 	modelOut := model
 
@@ -575,7 +590,8 @@ func streamClaude(r io.Reader, providerName, model string, onDelta func(delta st
 		case "message_start":
 			var payload struct {
 				Message struct {
-					Model string `json:"model"`
+					Model string      `json:"model"`
+					Usage claudeUsage `json:"usage"`
 				} `json:"message"`
 			}
 			if err := json.Unmarshal([]byte(data), &payload); err != nil {
@@ -584,6 +600,8 @@ func streamClaude(r io.Reader, providerName, model string, onDelta func(delta st
 			if payload.Message.Model != "" {
 				modelOut = payload.Message.Model
 			}
+			usage.PromptTokens = payload.Message.Usage.InputTokens
+			usage.CompletionTokens = payload.Message.Usage.OutputTokens
 		case "content_block_delta":
 			var payload struct {
 				Delta struct {
@@ -606,6 +624,7 @@ func streamClaude(r io.Reader, providerName, model string, onDelta func(delta st
 				Delta struct {
 					StopReason string `json:"stop_reason"`
 				} `json:"delta"`
+				Usage *claudeUsage `json:"usage"`
 			}
 			if err := json.Unmarshal([]byte(data), &payload); err != nil {
 				return errors.Join(domain.ErrStream, err)
@@ -613,6 +632,21 @@ func streamClaude(r io.Reader, providerName, model string, onDelta func(delta st
 			if payload.Delta.StopReason != "" {
 				finishReason = payload.Delta.StopReason
 			}
+			// message_delta usage is cumulative for the whole message.
+			if payload.Usage != nil {
+				if payload.Usage.InputTokens > 0 {
+					usage.PromptTokens = payload.Usage.InputTokens
+				}
+				usage.CompletionTokens = payload.Usage.OutputTokens
+			}
+		case "error":
+			var payload struct {
+				Error struct {
+					Type string `json:"type"`
+				} `json:"error"`
+			}
+			_ = json.Unmarshal([]byte(data), &payload)
+			return errors.Join(domain.ErrStream, fmt.Errorf("claude: stream error %s", payload.Error.Type))
 		case "message_stop":
 			return nil
 		default:
@@ -623,11 +657,13 @@ func streamClaude(r io.Reader, providerName, model string, onDelta func(delta st
 	if err != nil {
 		return domain.ChatResponse{}, err
 	}
-	return domain.ChatResponse{Provider: providerName, Model: modelOut, Content: content.String(), FinishReason: finishReason}, nil
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return domain.ChatResponse{Provider: providerName, Model: modelOut, Content: content.String(), FinishReason: finishReason, Usage: usage}, nil
 }
 
 func streamGemini(r io.Reader, providerName, model string, onDelta func(delta string) error) (domain.ChatResponse, error) {
 	var content strings.Builder
+	var usage domain.TokenUsage
 	finishReason := ""
 
 	err := scanSSE(r, func(event, data string) error {
@@ -654,12 +690,16 @@ func streamGemini(r io.Reader, providerName, model string, onDelta func(delta st
 		if finish != "" {
 			finishReason = finish
 		}
+		// Every chunk repeats the running usage; the last one is final.
+		if chunk.UsageMetadata != nil {
+			usage = chunk.UsageMetadata.toDomain()
+		}
 		return nil
 	})
 	if err != nil {
 		return domain.ChatResponse{}, err
 	}
-	return domain.ChatResponse{Provider: providerName, Model: model, Content: content.String(), FinishReason: finishReason}, nil
+	return domain.ChatResponse{Provider: providerName, Model: model, Content: content.String(), FinishReason: finishReason, Usage: usage}, nil
 }
 
 func splitSystemMessages(in []domain.Message) (string, []domain.Message) {
@@ -758,6 +798,17 @@ func buildURL(baseURL, fallback, path string) (string, error) {
 	return u.String(), nil
 }
 
+func addQueryParam(rawURL, key, value string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
 func addAPIKeyQueryParam(rawURL, apiKey string) (string, error) {
 	key := strings.TrimSpace(apiKey)
 	if rawURL == "" || key == "" {
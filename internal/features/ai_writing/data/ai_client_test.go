@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/data"
 	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
@@ -49,6 +50,15 @@ func TestOpenAIClient_Chat(t *testing.T) {
 
 func TestOpenAIClient_StreamChat(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			StreamOptions struct {
+				IncludeUsage bool `json:"include_usage"`
+			} `json:"stream_options"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !req.StreamOptions.IncludeUsage {
+			t.Errorf("expected stream_options.include_usage")
+		}
 		w.Header().Set("Content-Type", "text/event-stream")
 		flusher, ok := w.(http.Flusher)
 		fmt.Fprint(w, "data: {\"model\":\"m1\",\"choices\":[{\"delta\":{\"content\":\"Hel\"},\"finish_reason\":null}]}\n\n")
@@ -59,6 +69,7 @@ func TestOpenAIClient_StreamChat(t *testing.T) {
 		if ok {
 			flusher.Flush()
 		}
+		fmt.Fprint(w, "data: {\"model\":\"m1\",\"choices\":[],\"usage\":{\"prompt_tokens\":4,\"completion_tokens\":2,\"total_tokens\":6}}\n\n")
 		fmt.Fprint(w, "data: [DONE]\n\n")
 		if ok {
 			flusher.Flush()
@@ -88,6 +99,9 @@ func TestOpenAIClient_StreamChat(t *testing.T) {
 	if resp.Model != "m1" {
 		t.Fatalf("unexpected model: %q", resp.Model)
 	}
+	if resp.Usage != (domain.TokenUsage{PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6}) {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
 }
 
 func TestOpenAIClient_StatusError(t *testing.T) {
@@ -184,7 +198,7 @@ func TestClaudeClient_Chat_UsesMockHTTPClient(t *testing.T) {
 func TestClaudeClient_StreamChat_ParsesSSE(t *testing.T) {
 	sse := strings.Join([]string{
 		"event: message_start",
-		"data: {\"message\":{\"model\":\"claude-x\"}}",
+		"data: {\"message\":{\"model\":\"claude-x\",\"usage\":{\"input_tokens\":12,\"output_tokens\":1}}}",
 		"",
 		"event: content_block_delta",
 		"data: {\"delta\":{\"text\":\"Hel\"}}",
@@ -193,7 +207,7 @@ func TestClaudeClient_StreamChat_ParsesSSE(t *testing.T) {
 		"data: {\"delta\":{\"text\":\"lo\"}}",
 		"",
 		"event: message_delta",
-		"data: {\"delta\":{\"stop_reason\":\"end_turn\"}}",
+		"data: {\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":7}}",
 		"",
 		"event: message_stop",
 		"data: {}",
@@ -224,6 +238,9 @@ func TestClaudeClient_StreamChat_ParsesSSE(t *testing.T) {
 	if resp.Model != "claude-x" {
 		t.Fatalf("unexpected model: %q", resp.Model)
 	}
+	if resp.Usage != (domain.TokenUsage{PromptTokens: 12, CompletionTokens: 7, TotalTokens: 19}) {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
 }
 
 func TestGeminiClient_Chat_UsesAPIKeyQueryParam(t *testing.T) {
@@ -250,13 +267,16 @@ func TestGeminiClient_Chat_UsesAPIKeyQueryParam(t *testing.T) {
 
 func TestGeminiClient_StreamChat_ParsesSSE(t *testing.T) {
 	sse := strings.Join([]string{
-		"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"A\"}]} }]}",
+		"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"A\"}]} }],\"usageMetadata\":{\"promptTokenCount\":5,\"candidatesTokenCount\":1,\"totalTokenCount\":6}}",
 		"",
-		"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"B\"}]},\"finishReason\":\"STOP\"}]}",
+		"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"B\"}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":5,\"candidatesTokenCount\":2,\"totalTokenCount\":7}}",
 		"",
 	}, "\n")
 
 	client := data.GeminiClient{BaseURL: "https://example.invalid", HTTPClient: mockDoer{do: func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("alt") != "sse" {
+			t.Errorf("expected alt=sse, got %v", req.URL)
+		}
 		return newResponse(200, sse, "text/event-stream"), nil
 	}}}
 
@@ -277,6 +297,9 @@ func TestGeminiClient_StreamChat_ParsesSSE(t *testing.T) {
 	if resp.FinishReason != "STOP" {
 		t.Fatalf("unexpected finish: %q", resp.FinishReason)
 	}
+	if resp.Usage != (domain.TokenUsage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7}) {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
 }
 
 func TestClaudeClient_StatusError(t *testing.T) {
@@ -298,3 +321,141 @@ func TestGeminiClient_StreamInvalidJSON(t *testing.T) {
 		t.Fatalf("expected ErrStream, got %v", err)
 	}
 }
+
+func recordingRetry(maxRetries int, sleeps *[]time.Duration) data.RetryPolicy {
+	return data.RetryPolicy{
+		MaxRetries: maxRetries,
+		BaseDelay:  100 * time.Millisecond,
+		MaxDelay:   5 * time.Second,
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			*sleeps = append(*sleeps, d)
+			return nil
+		},
+	}
+}
+
+func TestClients_RetryServerAndNetworkErrors(t *testing.T) {
+	calls := 0
+	var sleeps []time.Duration
+	client := data.OpenAIClient{BaseURL: "https://example.invalid", Retry: recordingRetry(3, &sleeps), HTTPClient: mockDoer{do: func(req *http.Request) (*http.Response, error) {
+		calls++
+		b, _ := io.ReadAll(req.Body)
+		if !strings.Contains(string(b), "hello") {
+			t.Fatalf("request body not replayed on attempt %d: %q", calls, b)
+		}
+		switch calls {
+		case 1:
+			return nil, errors.New("connection reset")
+		case 2:
+			return newResponse(503, "overloaded", "text/plain"), nil
+		}
+		return newResponse(200, `{"model":"m1","choices":[{"message":{"role":"assistant","content":"hi"}}]}`, "application/json"), nil
+	}}}
+
+	resp, err := client.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{{Role: domain.RoleUser, Content: "hello"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "hi" || calls != 3 || len(sleeps) != 2 {
+		t.Fatalf("unexpected result: resp=%+v calls=%d sleeps=%v", resp, calls, sleeps)
+	}
+	for i, d := range sleeps {
+		if max := 100 * time.Millisecond << i; d < 0 || d > max {
+			t.Fatalf("sleep %d = %v, want within [0, %v]", i, d, max)
+		}
+	}
+}
+
+func TestClients_RetryHonorsRetryAfter(t *testing.T) {
+	calls := 0
+	var sleeps []time.Duration
+	client := data.ClaudeClient{BaseURL: "https://example.invalid", Retry: recordingRetry(2, &sleeps), HTTPClient: mockDoer{do: func(req *http.Request) (*http.Response, error) {
+		calls++
+		resp := newResponse(429, "slow down", "text/plain")
+		resp.Header.Set("Retry-After", "2")
+		return resp, nil
+	}}}
+
+	_, err := client.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{{Role: domain.RoleUser, Content: "hi"}}})
+	var rateLimit *domain.RateLimitError
+	if !errors.As(err, &rateLimit) || !errors.Is(err, domain.ErrProvider) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if rateLimit.StatusCode != 429 || rateLimit.RetryAfter != 2*time.Second {
+		t.Fatalf("unexpected details: %+v", rateLimit.ProviderError)
+	}
+	if calls != 3 || len(sleeps) != 2 || sleeps[0] != 2*time.Second || sleeps[1] != 2*time.Second {
+		t.Fatalf("unexpected retries: calls=%d sleeps=%v", calls, sleeps)
+	}
+
+	calls, sleeps = 0, nil
+	client.HTTPClient = mockDoer{do: func(req *http.Request) (*http.Response, error) {
+		calls++
+		resp := newResponse(429, "", "text/plain")
+		resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+		return resp, nil
+	}}
+	if _, err := client.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{{Role: domain.RoleUser, Content: "hi"}}}); !errors.As(err, &rateLimit) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if calls != 1 || len(sleeps) != 0 {
+		t.Fatalf("expected no retry beyond MaxDelay: calls=%d sleeps=%v", calls, sleeps)
+	}
+}
+
+func TestClients_RetryReportsCancelledBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	client := data.OpenAIClient{BaseURL: "https://example.invalid", HTTPClient: mockDoer{do: func(req *http.Request) (*http.Response, error) {
+		calls++
+		return newResponse(503, "overloaded", "text/plain"), nil
+	}}}
+	client.Retry = data.RetryPolicy{MaxRetries: 3, Sleep: func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}}
+
+	_, err := client.Chat(ctx, domain.ChatRequest{Messages: []domain.Message{{Role: domain.RoleUser, Content: "hi"}}})
+	var server *domain.ServerError
+	if !errors.Is(err, context.Canceled) || !errors.As(err, &server) {
+		t.Fatalf("expected the cancellation and the last provider error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+}
+
+func TestClients_TypedStatusErrors(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		check  func(error) bool
+	}{
+		{401, "invalid key", func(err error) bool { var e *domain.AuthError; return errors.As(err, &e) }},
+		{400, "unknown field", func(err error) bool { var e *domain.BadRequestError; return errors.As(err, &e) }},
+		{400, `{"error":{"code":"context_length_exceeded"}}`, func(err error) bool { var e *domain.ContextLengthError; return errors.As(err, &e) }},
+		{500, "boom", func(err error) bool { var e *domain.ServerError; return errors.As(err, &e) }},
+	}
+	for _, tc := range cases {
+		calls := 0
+		var sleeps []time.Duration
+		client := data.GeminiClient{BaseURL: "https://example.invalid", Retry: recordingRetry(1, &sleeps), HTTPClient: mockDoer{do: func(req *http.Request) (*http.Response, error) {
+			calls++
+			return newResponse(tc.status, tc.body, "application/json"), nil
+		}}}
+		_, err := client.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{{Role: domain.RoleUser, Content: "hi"}}})
+		if !tc.check(err) || !errors.Is(err, domain.ErrProvider) {
+			t.Fatalf("status %d body %q: unexpected error %v", tc.status, tc.body, err)
+		}
+		if strings.Contains(err.Error(), tc.body) {
+			t.Fatalf("status %d: provider body leaked into error %q", tc.status, err)
+		}
+		wantCalls := 1
+		if domain.IsRetryable(err) {
+			wantCalls = 2
+		}
+		if calls != wantCalls {
+			t.Fatalf("status %d: calls = %d, want %d", tc.status, calls, wantCalls)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+﻿package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+type FallbackTarget struct {
+	Provider domain.Provider
+	// Models maps a requested model name to the name this provider expects.
+	Models map[string]string
+	// DefaultModel is used when the requested model has no mapping.
+	DefaultModel string
+}
+
+// FallbackProvider tries each target in order and moves on to the next one
+// when a call fails with a retryable error. A streaming call never fails over
+// once a delta has been passed to the caller.
+type FallbackProvider struct {
+	Targets []FallbackTarget
+	// Retryable decides whether an error should trigger failover.
+	// Defaults to provider and stream errors other than bad requests, which
+	// would be rejected by the next provider too.
+	Retryable func(err error) bool
+}
+
+func NewFallbackProvider(targets ...FallbackTarget) FallbackProvider {
+	return FallbackProvider{Targets: targets}
+}
+
+func (p FallbackProvider) ProviderName() string { return "fallback" }
+
+func (p FallbackProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	return p.do(ctx, req, nil)
+}
+
+func (p FallbackProvider) StreamChat(ctx context.Context, req domain.ChatRequest, onDelta func(delta string) error) (domain.ChatResponse, error) {
+	if onDelta == nil {
+		return domain.ChatResponse{}, errors.Join(domain.ErrInvalidArgument, errors.New("onDelta is required"))
+	}
+	return p.do(ctx, req, onDelta)
+}
+
+func (p FallbackProvider) do(ctx context.Context, req domain.ChatRequest, onDelta func(delta string) error) (domain.ChatResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = defaultFallbackRetryable
+	}
+
+	var errs []error
+	for _, target := range p.Targets {
+		if target.Provider == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return domain.ChatResponse{}, err
+		}
+
+		attempt := req
+		attempt.Model = target.model(req.Model)
+
+		var (
+			resp    domain.ChatResponse
+			err     error
+			emitted bool
+		)
+		if onDelta == nil {
+			resp, err = target.Provider.Chat(ctx, attempt)
+		} else {
+			resp, err = target.Provider.StreamChat(ctx, attempt, func(delta string) error {
+				emitted = true
+				return onDelta(delta)
+			})
+		}
+		if err == nil {
+			if resp.Provider == "" {
+				resp.Provider = target.Provider.ProviderName()
+			}
+			return resp, nil
+		}
+
+		if emitted || !retryable(err) || ctx.Err() != nil {
+			return domain.ChatResponse{}, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", target.Provider.ProviderName(), err))
+	}
+
+	if len(errs) == 0 {
+		return domain.ChatResponse{}, errors.Join(domain.ErrProvider, errors.New("fallback: no providers configured"))
+	}
+	return domain.ChatResponse{}, errors.Join(append([]error{domain.ErrProvider, errors.New("fallback: all providers failed")}, errs...)...)
+}
+
+func (t FallbackTarget) model(requested string) string {
+	requested = strings.TrimSpace(requested)
+	if mapped, ok := t.Models[requested]; ok && strings.TrimSpace(mapped) != "" {
+		return mapped
+	}
+	if m := strings.TrimSpace(t.DefaultModel); m != "" {
+		return m
+	}
+	return requested
+}
+
+func defaultFallbackRetryable(err error) bool {
+	var badRequest *domain.BadRequestError
+	if errors.As(err, &badRequest) {
+		return false
+	}
+	return errors.Is(err, domain.ErrProvider) || errors.Is(err, domain.ErrStream)
+}
@@ -0,0 +1,141 @@
+﻿package data_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/data"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+type scriptedProvider struct {
+	name   string
+	deltas []string
+	err    error
+
+	calls     int
+	lastModel string
+}
+
+func (p *scriptedProvider) ProviderName() string { return p.name }
+
+func (p *scriptedProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	p.calls++
+	p.lastModel = req.Model
+	if p.err != nil {
+		return domain.ChatResponse{}, p.err
+	}
+	return domain.ChatResponse{Provider: p.name, Model: req.Model, Content: p.name}, nil
+}
+
+func (p *scriptedProvider) StreamChat(ctx context.Context, req domain.ChatRequest, onDelta func(delta string) error) (domain.ChatResponse, error) {
+	p.calls++
+	p.lastModel = req.Model
+	for _, d := range p.deltas {
+		if err := onDelta(d); err != nil {
+			return domain.ChatResponse{}, err
+		}
+	}
+	if p.err != nil {
+		return domain.ChatResponse{}, p.err
+	}
+	return domain.ChatResponse{Provider: p.name, Model: req.Model, Content: p.name}, nil
+}
+
+func TestFallbackProvider_FailsOverAndMapsModels(t *testing.T) {
+	first := &scriptedProvider{name: "openai", err: errors.Join(domain.ErrProvider, errors.New("status 429"))}
+	second := &scriptedProvider{name: "claude"}
+	p := data.NewFallbackProvider(
+		data.FallbackTarget{Provider: first, Models: map[string]string{"smart": "gpt-4o"}},
+		data.FallbackTarget{Provider: second, Models: map[string]string{"smart": "claude-3-5-sonnet"}, DefaultModel: "claude-3-haiku"},
+	)
+
+	resp, err := p.Chat(context.Background(), domain.ChatRequest{Model: "smart"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Provider != "claude" {
+		t.Fatalf("expected answer from claude, got %q", resp.Provider)
+	}
+	if first.lastModel != "gpt-4o" || second.lastModel != "claude-3-5-sonnet" {
+		t.Fatalf("unexpected model mapping: %q, %q", first.lastModel, second.lastModel)
+	}
+
+	if _, err := p.Chat(context.Background(), domain.ChatRequest{Model: "other"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.lastModel != "other" || second.lastModel != "claude-3-haiku" {
+		t.Fatalf("unexpected unmapped models: %q, %q", first.lastModel, second.lastModel)
+	}
+}
+
+func TestFallbackProvider_DoesNotFailOverOnNonRetryableError(t *testing.T) {
+	first := &scriptedProvider{name: "openai", err: errors.Join(domain.ErrInvalidArgument, errors.New("bad"))}
+	second := &scriptedProvider{name: "claude"}
+	p := data.NewFallbackProvider(data.FallbackTarget{Provider: first}, data.FallbackTarget{Provider: second})
+
+	if _, err := p.Chat(context.Background(), domain.ChatRequest{}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+	if second.calls != 0 {
+		t.Fatalf("second provider should not be called")
+	}
+}
+
+func TestFallbackProvider_NeverFailsOverMidStream(t *testing.T) {
+	first := &scriptedProvider{name: "openai", deltas: []string{"par"}, err: errors.Join(domain.ErrStream, errors.New("reset"))}
+	second := &scriptedProvider{name: "claude", deltas: []string{"full"}}
+	p := data.NewFallbackProvider(data.FallbackTarget{Provider: first}, data.FallbackTarget{Provider: second})
+
+	var got []string
+	_, err := p.StreamChat(context.Background(), domain.ChatRequest{}, func(delta string) error {
+		got = append(got, delta)
+		return nil
+	})
+	if !errors.Is(err, domain.ErrStream) {
+		t.Fatalf("expected ErrStream, got %v", err)
+	}
+	if second.calls != 0 || len(got) != 1 {
+		t.Fatalf("stream must not fail over after deltas: calls=%d deltas=%v", second.calls, got)
+	}
+
+	// Failing before any delta is emitted still fails over.
+	first.deltas = nil
+	got = nil
+	resp, err := p.StreamChat(context.Background(), domain.ChatRequest{}, func(delta string) error {
+		got = append(got, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Provider != "claude" || len(got) != 1 || got[0] != "full" {
+		t.Fatalf("unexpected fallback stream: %+v %v", resp, got)
+	}
+}
+
+func TestFallbackProvider_AllFail(t *testing.T) {
+	p := data.NewFallbackProvider(
+		data.FallbackTarget{Provider: &scriptedProvider{name: "a", err: domain.ErrProvider}},
+		data.FallbackTarget{Provider: &scriptedProvider{name: "b", err: domain.ErrProvider}},
+	)
+	if _, err := p.Chat(context.Background(), domain.ChatRequest{}); !errors.Is(err, domain.ErrProvider) {
+		t.Fatalf("expected ErrProvider, got %v", err)
+	}
+}
+
+func TestFallbackProvider_DoesNotFailOverBadRequests(t *testing.T) {
+	first := &scriptedProvider{name: "openai", err: &domain.BadRequestError{ProviderError: domain.ProviderError{Provider: "openai", StatusCode: 400}}}
+	second := &scriptedProvider{name: "claude"}
+	p := data.NewFallbackProvider(data.FallbackTarget{Provider: first}, data.FallbackTarget{Provider: second})
+
+	_, err := p.Chat(context.Background(), domain.ChatRequest{})
+	var badRequest *domain.BadRequestError
+	if !errors.As(err, &badRequest) {
+		t.Fatalf("expected BadRequestError, got %v", err)
+	}
+	if second.calls != 0 {
+		t.Fatalf("bad request should not fail over, second provider called %d times", second.calls)
+	}
+}
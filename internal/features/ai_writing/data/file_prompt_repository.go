@@ -0,0 +1,276 @@
+﻿package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/data/models"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+// PromptFileError describes a prompt file that could not be loaded.
+// MessageIndex is -1 when the problem is not tied to a single message.
+type PromptFileError struct {
+	Path         string
+	MessageIndex int
+	Err          error
+}
+
+func (e *PromptFileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *PromptFileError) Unwrap() error { return e.Err }
+
+type promptFileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// FilePromptRepository serves prompts from a directory holding one YAML or
+// JSON file per prompt. A file without a name uses its base file name.
+// Files that fail to load are reported by Errors; a prompt that was loaded
+// earlier from the same file stays available until the file is fixed.
+type FilePromptRepository struct {
+	dir string
+
+	mu      sync.RWMutex
+	prompts map[string]domain.Prompt
+	byPath  map[string]domain.Prompt
+	errs    []PromptFileError
+	stamps  map[string]promptFileStamp
+}
+
+func NewFilePromptRepository(dir string) (*FilePromptRepository, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, errors.New("file prompt repository: dir is empty")
+	}
+	repo := &FilePromptRepository{
+		dir:     dir,
+		prompts: map[string]domain.Prompt{},
+		byPath:  map[string]domain.Prompt{},
+	}
+	if _, err := repo.Reload(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *FilePromptRepository) GetPrompt(ctx context.Context, name string) (domain.Prompt, error) {
+	_ = ctx
+	key := promptKey(name)
+	if key == "" {
+		return domain.Prompt{}, errors.Join(domain.ErrInvalidArgument, errors.New("prompt name is required"))
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.prompts[key]
+	if !ok {
+		return domain.Prompt{}, errors.Join(domain.ErrNotFound, errors.New("prompt not found"))
+	}
+	return p, nil
+}
+
+func (r *FilePromptRepository) ListPrompts(ctx context.Context) ([]domain.Prompt, error) {
+	_ = ctx
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]string, 0, len(r.prompts))
+	for k := range r.prompts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]domain.Prompt, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, r.prompts[k])
+	}
+	return out, nil
+}
+
+// Errors returns the problems found by the most recent reload.
+func (r *FilePromptRepository) Errors() []PromptFileError {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]PromptFileError, len(r.errs))
+	copy(out, r.errs)
+	return out
+}
+
+// Reload re-reads every prompt file in the directory. The returned error is
+// only set when the directory itself cannot be read.
+func (r *FilePromptRepository) Reload() ([]PromptFileError, error) {
+	paths, stamps, err := r.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prompts := make(map[string]domain.Prompt, len(paths))
+	byPath := make(map[string]domain.Prompt, len(paths))
+	owners := make(map[string]string, len(paths))
+	var errs []PromptFileError
+
+	for _, path := range paths {
+		p, err := loadPromptFile(path)
+		if err != nil {
+			errs = append(errs, newPromptFileError(path, err))
+			prev, ok := r.byPath[path]
+			if !ok {
+				continue
+			}
+			p = prev
+		}
+
+		key := promptKey(p.Name)
+		if owner, dup := owners[key]; dup {
+			errs = append(errs, PromptFileError{Path: path, MessageIndex: -1, Err: fmt.Errorf("duplicate prompt name %q (also defined in %s)", p.Name, filepath.Base(owner))})
+			continue
+		}
+		owners[key] = path
+		prompts[key] = p
+		byPath[path] = p
+	}
+
+	r.prompts = prompts
+	r.byPath = byPath
+	r.errs = errs
+	r.stamps = stamps
+
+	out := make([]PromptFileError, len(errs))
+	copy(out, errs)
+	return out, nil
+}
+
+// Watch polls the directory every interval and reloads when a prompt file was
+// added, removed or modified. onReload, if set, receives the reload errors.
+// Watch blocks until ctx is done.
+func (r *FilePromptRepository) Watch(ctx context.Context, interval time.Duration, onReload func(errs []PromptFileError)) error {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		changed, err := r.changed()
+		if err != nil || !changed {
+			continue
+		}
+		errs, err := r.Reload()
+		if err != nil {
+			continue
+		}
+		if onReload != nil {
+			onReload(errs)
+		}
+	}
+}
+
+func (r *FilePromptRepository) changed() (bool, error) {
+	_, stamps, err := r.scan()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(stamps) != len(r.stamps) {
+		return true, nil
+	}
+	for path, s := range stamps {
+		prev, ok := r.stamps[path]
+		if !ok || !prev.modTime.Equal(s.modTime) || prev.size != s.size {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *FilePromptRepository) scan() ([]string, map[string]promptFileStamp, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	stamps := make(map[string]promptFileStamp, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !isPromptFile(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(r.dir, name)
+		paths = append(paths, path)
+		stamps[path] = promptFileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	sort.Strings(paths)
+	return paths, stamps, nil
+}
+
+func isPromptFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+func loadPromptFile(path string) (domain.Prompt, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return domain.Prompt{}, err
+	}
+
+	var dto models.PromptDTO
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&dto)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		err = dec.Decode(&dto)
+	}
+	if err != nil {
+		return domain.Prompt{}, fmt.Errorf("parse: %w", err)
+	}
+
+	if strings.TrimSpace(dto.Name) == "" {
+		base := filepath.Base(path)
+		dto.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	dto.Name = strings.TrimSpace(dto.Name)
+	return dto.ToDomain()
+}
+
+func newPromptFileError(path string, err error) PromptFileError {
+	out := PromptFileError{Path: path, MessageIndex: -1, Err: err}
+	var msgErr *domain.PromptMessageError
+	if errors.As(err, &msgErr) {
+		out.MessageIndex = msgErr.Index
+	}
+	return out
+}
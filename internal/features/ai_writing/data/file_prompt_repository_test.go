@@ -0,0 +1,144 @@
+﻿package data_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/data"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+func writePromptFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestFilePromptRepository_LoadsYAMLAndJSON(t *testing.T) {
+	dir := t.TempDir()
+	writePromptFile(t, dir, "headline.yaml", `
+name: headline
+messages:
+  - role: system
+    template: You write headlines.
+  - role: user
+    template: "Headline for {{topic}}"
+`)
+	writePromptFile(t, dir, "outline.json", `{"messages":[{"role":"user","template":"Outline {{topic}}"}]}`)
+	writePromptFile(t, dir, "notes.txt", "ignored")
+
+	repo, err := data.NewFilePromptRepository(dir)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	if errs := repo.Errors(); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	p, err := repo.GetPrompt(context.Background(), "HEADLINE")
+	if err != nil {
+		t.Fatalf("get headline: %v", err)
+	}
+	if len(p.Messages) != 2 || p.Messages[1].Template != "Headline for {{topic}}" {
+		t.Fatalf("unexpected prompt: %+v", p)
+	}
+
+	outline, err := repo.GetPrompt(context.Background(), "outline")
+	if err != nil {
+		t.Fatalf("file name should be used as prompt name: %v", err)
+	}
+	if outline.Name != "outline" {
+		t.Fatalf("unexpected name: %q", outline.Name)
+	}
+
+	all, _ := repo.ListPrompts(context.Background())
+	if len(all) != 2 {
+		t.Fatalf("expected 2 prompts, got %d", len(all))
+	}
+	if _, err := repo.GetPrompt(context.Background(), "notes"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFilePromptRepository_ReportsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	bad := writePromptFile(t, dir, "bad.yaml", `
+name: bad
+messages:
+  - role: user
+    template: ok
+  - role: user
+    template: "  "
+`)
+	writePromptFile(t, dir, "broken.json", `{"name": `)
+
+	repo, err := data.NewFilePromptRepository(dir)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	errs := repo.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if errs[0].Path != bad || errs[0].MessageIndex != 1 {
+		t.Fatalf("unexpected error for bad.yaml: %+v", errs[0])
+	}
+	if errs[1].MessageIndex != -1 {
+		t.Fatalf("parse errors should not carry a message index: %+v", errs[1])
+	}
+}
+
+func waitForReload(t *testing.T, ch <-chan []data.PromptFileError, done func(errs []data.PromptFileError) bool) {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case errs := <-ch:
+			if done(errs) {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("watch did not reload")
+		}
+	}
+}
+
+func TestFilePromptRepository_WatchPicksUpChanges(t *testing.T) {
+	dir := t.TempDir()
+	writePromptFile(t, dir, "p.yaml", "messages:\n  - role: user\n    template: v1\n")
+
+	repo, err := data.NewFilePromptRepository(dir)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan []data.PromptFileError, 16)
+	go func() {
+		_ = repo.Watch(ctx, 10*time.Millisecond, func(errs []data.PromptFileError) { reloaded <- errs })
+	}()
+
+	writePromptFile(t, dir, "p.yaml", "messages:\n  - role: user\n    template: version two\n")
+	waitForReload(t, reloaded, func(errs []data.PromptFileError) bool {
+		p, err := repo.GetPrompt(context.Background(), "p")
+		return err == nil && p.Messages[0].Template == "version two"
+	})
+
+	// A broken edit is reported but keeps serving the last good prompt.
+	writePromptFile(t, dir, "p.yaml", "messages:\n  - role: \"\"\n    template: broken edit\n")
+	waitForReload(t, reloaded, func(errs []data.PromptFileError) bool {
+		return len(errs) == 1 && errs[0].MessageIndex == 0
+	})
+	p, err := repo.GetPrompt(context.Background(), "p")
+	if err != nil || p.Messages[0].Template != "version two" {
+		t.Fatalf("expected last good prompt, got %+v, %v", p, err)
+	}
+}
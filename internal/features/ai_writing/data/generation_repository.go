@@ -0,0 +1,159 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/core/migrate"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/data/models"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+type SQLiteGenerationRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteGenerationRepository(db *sql.DB) (*SQLiteGenerationRepository, error) {
+	if db == nil {
+		return nil, errors.New("generation repository: db is nil")
+	}
+	repo := &SQLiteGenerationRepository{db: db}
+	if err := repo.EnsureSchema(context.Background()); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *SQLiteGenerationRepository) EnsureSchema(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return migrate.Apply(ctx, r.db, MigrationFeature, Migrations())
+}
+
+func (r *SQLiteGenerationRepository) CreateGeneration(ctx context.Context, g domain.Generation) (domain.Generation, error) {
+	if strings.TrimSpace(g.ID) == "" {
+		return domain.Generation{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	if !g.Type.Valid() {
+		return domain.Generation{}, errors.Join(domain.ErrInvalidArgument, errors.New("invalid generation type"))
+	}
+
+	dto := models.FromGeneration(g)
+	if g.CreatedAt.IsZero() {
+		dto.CreatedAtMs = systemClock{}.Now().UnixMilli()
+	}
+	if dto.TotalTokens == 0 {
+		dto.TotalTokens = dto.PromptTokens + dto.CompletionTokens
+	}
+
+	if _, err := r.db.ExecContext(ctx, `
+INSERT INTO ai_generations(id, type, prompt_name, provider, model, input_text, output_text, article_id, prompt_tokens, completion_tokens, total_tokens, created_at_ms)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, dto.ID, dto.Type, dto.PromptName, dto.Provider, dto.Model, dto.InputText, dto.OutputText, dto.ArticleID, dto.PromptTokens, dto.CompletionTokens, dto.TotalTokens, dto.CreatedAtMs); err != nil {
+		if isUniqueConstraintErr(err) {
+			return domain.Generation{}, errors.Join(domain.ErrConflict, err)
+		}
+		return domain.Generation{}, err
+	}
+	return dto.ToDomain(), nil
+}
+
+func (r *SQLiteGenerationRepository) GetGeneration(ctx context.Context, id string) (domain.Generation, error) {
+	if strings.TrimSpace(id) == "" {
+		return domain.Generation{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+
+	var dto models.GenerationDTO
+	if err := r.db.QueryRowContext(ctx, `
+SELECT id, type, prompt_name, provider, model, input_text, output_text, article_id, prompt_tokens, completion_tokens, total_tokens, created_at_ms
+FROM ai_generations
+WHERE id = ?
+`, id).Scan(&dto.ID, &dto.Type, &dto.PromptName, &dto.Provider, &dto.Model, &dto.InputText, &dto.OutputText, &dto.ArticleID, &dto.PromptTokens, &dto.CompletionTokens, &dto.TotalTokens, &dto.CreatedAtMs); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Generation{}, domain.ErrNotFound
+		}
+		return domain.Generation{}, err
+	}
+	return dto.ToDomain(), nil
+}
+
+func (r *SQLiteGenerationRepository) ListGenerations(ctx context.Context, query domain.ListGenerationsQuery) ([]domain.Generation, error) {
+	if query.Type != nil && !query.Type.Valid() {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("invalid generation type"))
+	}
+	if query.Since != nil && query.Until != nil && query.Until.Before(*query.Since) {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("until must not be before since"))
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := query.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	var b strings.Builder
+	args := make([]any, 0, 7)
+	b.WriteString(`SELECT id, type, prompt_name, provider, model, input_text, output_text, article_id, prompt_tokens, completion_tokens, total_tokens, created_at_ms FROM ai_generations WHERE 1=1`)
+	if query.ArticleID != nil {
+		b.WriteString(" AND article_id = ?")
+		args = append(args, strings.TrimSpace(*query.ArticleID))
+	}
+	if query.Type != nil {
+		b.WriteString(" AND type = ?")
+		args = append(args, string(*query.Type))
+	}
+	if query.Provider != nil {
+		b.WriteString(" AND provider = ?")
+		args = append(args, strings.TrimSpace(*query.Provider))
+	}
+	if query.Since != nil {
+		b.WriteString(" AND created_at_ms >= ?")
+		args = append(args, query.Since.UTC().UnixMilli())
+	}
+	if query.Until != nil {
+		b.WriteString(" AND created_at_ms < ?")
+		args = append(args, query.Until.UTC().UnixMilli())
+	}
+	b.WriteString(" ORDER BY created_at_ms DESC, id ASC LIMIT ? OFFSET ?")
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Generation, 0)
+	for rows.Next() {
+		var dto models.GenerationDTO
+		if err := rows.Scan(&dto.ID, &dto.Type, &dto.PromptName, &dto.Provider, &dto.Model, &dto.InputText, &dto.OutputText, &dto.ArticleID, &dto.PromptTokens, &dto.CompletionTokens, &dto.TotalTokens, &dto.CreatedAtMs); err != nil {
+			return nil, err
+		}
+		out = append(out, dto.ToDomain())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now().UTC() }
+
+func isUniqueConstraintErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	msgLower := strings.ToLower(err.Error())
+	return strings.Contains(msgLower, "unique constraint") || strings.Contains(msgLower, "constraint failed")
+}
@@ -0,0 +1,109 @@
+﻿package data_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/data"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	name := fmt.Sprintf("file:ai_writing_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := sql.Open("sqlite", name)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestSQLiteGenerationRepository_CreateGetList(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteGenerationRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 8, 0, 0, 0, time.UTC)
+	seed := []domain.Generation{
+		{ID: "g1", Type: domain.GenerationTypeGenerate, PromptName: "generate_content", Provider: "openai", Model: "m1", InputText: "topic", OutputText: "out1", ArticleID: "a1", Usage: domain.TokenUsage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30}, CreatedAt: base},
+		{ID: "g2", Type: domain.GenerationTypeRewrite, PromptName: "rewrite_content", Provider: "claude", Model: "m2", InputText: "in", OutputText: "out2", ArticleID: "a1", CreatedAt: base.Add(time.Hour)},
+		{ID: "g3", Type: domain.GenerationTypeSummarize, PromptName: "summarize", Provider: "openai", Model: "m1", InputText: "in", OutputText: "out3", CreatedAt: base.Add(2 * time.Hour)},
+	}
+	for _, g := range seed {
+		if _, err := repo.CreateGeneration(ctx, g); err != nil {
+			t.Fatalf("create %s: %v", g.ID, err)
+		}
+	}
+
+	got, err := repo.GetGeneration(ctx, "g1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Usage.TotalTokens != 30 || got.ArticleID != "a1" || !got.CreatedAt.Equal(base) {
+		t.Fatalf("unexpected generation: %+v", got)
+	}
+
+	articleID := "a1"
+	byArticle, err := repo.ListGenerations(ctx, domain.ListGenerationsQuery{ArticleID: &articleID})
+	if err != nil {
+		t.Fatalf("list by article: %v", err)
+	}
+	if len(byArticle) != 2 || byArticle[0].ID != "g2" || byArticle[1].ID != "g1" {
+		t.Fatalf("unexpected list by article: %+v", byArticle)
+	}
+
+	provider := "openai"
+	genType := domain.GenerationTypeSummarize
+	filtered, err := repo.ListGenerations(ctx, domain.ListGenerationsQuery{Provider: &provider, Type: &genType})
+	if err != nil {
+		t.Fatalf("list by provider/type: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != "g3" {
+		t.Fatalf("unexpected list by provider/type: %+v", filtered)
+	}
+
+	since := base.Add(30 * time.Minute)
+	until := base.Add(90 * time.Minute)
+	ranged, err := repo.ListGenerations(ctx, domain.ListGenerationsQuery{Since: &since, Until: &until})
+	if err != nil {
+		t.Fatalf("list by range: %v", err)
+	}
+	if len(ranged) != 1 || ranged[0].ID != "g2" {
+		t.Fatalf("unexpected list by range: %+v", ranged)
+	}
+}
+
+func TestSQLiteGenerationRepository_Errors(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteGenerationRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+
+	if _, err := repo.CreateGeneration(ctx, domain.Generation{Type: domain.GenerationTypeGenerate}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for missing id, got %v", err)
+	}
+	if _, err := repo.CreateGeneration(ctx, domain.Generation{ID: "x", Type: "bad"}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for bad type, got %v", err)
+	}
+	g := domain.Generation{ID: "dup", Type: domain.GenerationTypeGenerate, CreatedAt: time.Now().UTC()}
+	if _, err := repo.CreateGeneration(ctx, g); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := repo.CreateGeneration(ctx, g); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if _, err := repo.GetGeneration(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
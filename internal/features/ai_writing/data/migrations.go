@@ -0,0 +1,75 @@
+﻿package data
+
+import "github.com/Xiaoxinkeji/WX/internal/core/migrate"
+
+// MigrationFeature is the key the AI writing schema is versioned under in
+// schema_migrations. Generations, prompts and usage share one sequence.
+const MigrationFeature = "ai_writing"
+
+// Migrations returns the ordered AI writing schema migrations. Append new
+// steps; never edit one that has shipped.
+func Migrations() []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "baseline", Up: migrate.SQL(`
+CREATE TABLE IF NOT EXISTS ai_generations (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	prompt_name TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	model TEXT NOT NULL,
+	input_text TEXT NOT NULL,
+	output_text TEXT NOT NULL,
+	article_id TEXT NOT NULL DEFAULT '',
+	prompt_tokens INTEGER NOT NULL DEFAULT 0,
+	completion_tokens INTEGER NOT NULL DEFAULT 0,
+	total_tokens INTEGER NOT NULL DEFAULT 0,
+	created_at_ms INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_generations_created_at_ms ON ai_generations(created_at_ms DESC);
+CREATE INDEX IF NOT EXISTS idx_ai_generations_article_id ON ai_generations(article_id, created_at_ms DESC);
+CREATE INDEX IF NOT EXISTS idx_ai_generations_type ON ai_generations(type, created_at_ms DESC);
+
+CREATE TABLE IF NOT EXISTS ai_prompts (
+	name TEXT PRIMARY KEY,
+	current_version INTEGER NOT NULL,
+	created_at_ms INTEGER NOT NULL,
+	updated_at_ms INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ai_prompt_versions (
+	name TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	prompt_json TEXT NOT NULL,
+	created_at_ms INTEGER NOT NULL,
+	PRIMARY KEY(name, version),
+	FOREIGN KEY(name) REFERENCES ai_prompts(name) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS ai_usage (
+	generation_id TEXT PRIMARY KEY,
+	article_id TEXT NOT NULL DEFAULT '',
+	provider TEXT NOT NULL,
+	model TEXT NOT NULL,
+	prompt_tokens INTEGER NOT NULL DEFAULT 0,
+	completion_tokens INTEGER NOT NULL DEFAULT 0,
+	total_tokens INTEGER NOT NULL DEFAULT 0,
+	estimated INTEGER NOT NULL DEFAULT 0,
+	cost_micros INTEGER NOT NULL DEFAULT 0,
+	created_at_ms INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_created_at_ms ON ai_usage(created_at_ms);
+CREATE INDEX IF NOT EXISTS idx_ai_usage_article_id ON ai_usage(article_id, created_at_ms);
+`)},
+		{Version: 2, Name: "prompt seeds", Up: migrate.SQL(`
+CREATE TABLE IF NOT EXISTS ai_prompt_seeds (
+	name TEXT PRIMARY KEY,
+	seeded_at_ms INTEGER NOT NULL
+);
+
+INSERT OR IGNORE INTO ai_prompt_seeds(name, seeded_at_ms)
+SELECT name, created_at_ms FROM ai_prompts;
+`)},
+	}
+}
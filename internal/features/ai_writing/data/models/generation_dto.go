@@ -7,29 +7,35 @@ import (
 )
 
 type GenerationDTO struct {
-	ID         string `json:"id"`
-	Type       string `json:"type"`
-	PromptName string `json:"prompt_name"`
-	Provider   string `json:"provider"`
-	Model      string `json:"model"`
-	InputText  string `json:"input_text"`
-	OutputText string `json:"output_text"`
-	ArticleID  string `json:"article_id"`
-	CreatedAtMs int64 `json:"created_at_ms"`
+	ID               string `json:"id"`
+	Type             string `json:"type"`
+	PromptName       string `json:"prompt_name"`
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	InputText        string `json:"input_text"`
+	OutputText       string `json:"output_text"`
+	ArticleID        string `json:"article_id"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+	CreatedAtMs      int64  `json:"created_at_ms"`
 }
 
 func FromGeneration(g domain.Generation) GenerationDTO {
 	createdAtMs := g.CreatedAt.UTC().UnixMilli()
 	return GenerationDTO{
-		ID:          g.ID,
-		Type:        string(g.Type),
-		PromptName:  g.PromptName,
-		Provider:    g.Provider,
-		Model:       g.Model,
-		InputText:   g.InputText,
-		OutputText:  g.OutputText,
-		ArticleID:   g.ArticleID,
-		CreatedAtMs: createdAtMs,
+		ID:               g.ID,
+		Type:             string(g.Type),
+		PromptName:       g.PromptName,
+		Provider:         g.Provider,
+		Model:            g.Model,
+		InputText:        g.InputText,
+		OutputText:       g.OutputText,
+		ArticleID:        g.ArticleID,
+		PromptTokens:     g.Usage.PromptTokens,
+		CompletionTokens: g.Usage.CompletionTokens,
+		TotalTokens:      g.Usage.TotalTokens,
+		CreatedAtMs:      createdAtMs,
 	}
 }
 
@@ -44,6 +50,11 @@ func (d GenerationDTO) ToDomain() domain.Generation {
 		InputText:  d.InputText,
 		OutputText: d.OutputText,
 		ArticleID:  d.ArticleID,
-		CreatedAt:  created,
+		Usage: domain.TokenUsage{
+			PromptTokens:     d.PromptTokens,
+			CompletionTokens: d.CompletionTokens,
+			TotalTokens:      d.TotalTokens,
+		},
+		CreatedAt: created,
 	}
 }
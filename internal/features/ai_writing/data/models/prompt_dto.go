@@ -5,13 +5,13 @@ import (
 )
 
 type PromptMessageDTO struct {
-	Role     string `json:"role"`
-	Template string `json:"template"`
+	Role     string `json:"role" yaml:"role"`
+	Template string `json:"template" yaml:"template"`
 }
 
 type PromptDTO struct {
-	Name     string            `json:"name"`
-	Messages []PromptMessageDTO `json:"messages"`
+	Name     string             `json:"name" yaml:"name"`
+	Messages []PromptMessageDTO `json:"messages" yaml:"messages"`
 }
 
 func FromPrompt(p domain.Prompt) PromptDTO {
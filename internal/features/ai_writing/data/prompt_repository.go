@@ -24,7 +24,15 @@ func NewInMemoryPromptRepository(prompts []domain.Prompt) (*InMemoryPromptReposi
 }
 
 func NewDefaultPromptRepository() *InMemoryPromptRepository {
-	repo, _ := NewInMemoryPromptRepository([]domain.Prompt{
+	repo, _ := NewInMemoryPromptRepository(DefaultPrompts())
+	if repo == nil {
+		return &InMemoryPromptRepository{prompts: map[string]domain.Prompt{}}
+	}
+	return repo
+}
+
+func DefaultPrompts() []domain.Prompt {
+	return []domain.Prompt{
 		{
 			Name: "generate_content",
 			Messages: []domain.PromptMessage{
@@ -46,11 +54,33 @@ func NewDefaultPromptRepository() *InMemoryPromptRepository {
 				{Role: domain.RoleUser, Template: "Summarize the following text into concise bullet points:\n\n{{text}}"},
 			},
 		},
-	})
-	if repo == nil {
-		return &InMemoryPromptRepository{prompts: map[string]domain.Prompt{}}
+		{
+			Name: "digest",
+			Messages: []domain.PromptMessage{
+				{Role: domain.RoleSystem, Template: "You are a helpful writing assistant."},
+				{Role: domain.RoleUser, Template: "Write a single-paragraph digest of the following article in its own language, at most 120 characters, as plain text without bullet points:\n\n{{text}}"},
+			},
+		},
+		{
+			Name: "continue_writing",
+			Messages: []domain.PromptMessage{
+				{Role: domain.RoleSystem, Template: "You are a helpful writing assistant. Continue the author's draft in the same voice, style and language."},
+				{Role: domain.RoleUser, Template: "Article title: {{title}}\n\nContinue the draft below with {{paragraphs}} more paragraph(s). Reply with the new paragraphs only, without repeating existing text.\n\n{{content}}"},
+			},
+		},
+	}
+}
+
+// DefaultPromptVariables lists the variables each built-in use case passes to
+// its prompt. Edited prompts may only reference these.
+func DefaultPromptVariables() map[string][]string {
+	return map[string][]string{
+		"generate_content": {"topic"},
+		"rewrite_content":  {"text"},
+		"summarize":        {"text"},
+		"digest":           {"text"},
+		"continue_writing": {"title", "content", "paragraphs"},
 	}
-	return repo
 }
 
 func (r *InMemoryPromptRepository) GetPrompt(ctx context.Context, name string) (domain.Prompt, error) {
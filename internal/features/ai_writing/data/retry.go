@@ -0,0 +1,167 @@
+﻿package data
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+// RetryPolicy controls how AI clients retry rate-limited, 5xx and network
+// failures. The zero value disables retries.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// Sleep waits between attempts; defaults to a context-aware timer.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxRetries: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+}
+
+// delay returns the wait before retry number attempt (0-based), and false if
+// the provider asked to wait longer than MaxDelay.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
+	}
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= maxDelay
+	}
+	base := p.BaseDelay
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	backoff := maxDelay
+	if attempt < 30 && base<<attempt > 0 && base<<attempt < maxDelay {
+		backoff = base << attempt
+	}
+	// Full jitter keeps concurrent clients from retrying in lockstep.
+	return time.Duration(rand.Int63n(int64(backoff) + 1)), true
+}
+
+func (p RetryPolicy) sleep(ctx context.Context, d time.Duration) error {
+	if p.Sleep != nil {
+		return p.Sleep(ctx, d)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// sendWithRetry issues the request built by newReq until it gets a 2xx
+// response, a non-retryable error, or runs out of retries. The caller owns
+// the returned response body.
+func sendWithRetry(ctx context.Context, client HTTPDoer, policy RetryPolicy, provider string, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		hreq, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(hreq)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		if err != nil {
+			err = &domain.ProviderError{Provider: provider, Err: err}
+		} else {
+			err = statusError(provider, resp)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if attempt >= policy.MaxRetries || !domain.IsRetryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		var retryAfter time.Duration
+		if d, ok := domain.ProviderErrorDetails(err); ok {
+			retryAfter = d.RetryAfter
+		}
+		wait, ok := policy.delay(attempt, retryAfter)
+		if !ok {
+			return nil, err
+		}
+		if serr := policy.sleep(ctx, wait); serr != nil {
+			return nil, errors.Join(serr, err)
+		}
+	}
+}
+
+// statusError classifies a non-2xx response. The body is only inspected, not
+// included in the error, so provider messages don't leak to users.
+func statusError(provider string, resp *http.Response) error {
+	base := domain.ProviderError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header, time.Now()),
+	}
+	switch code := resp.StatusCode; {
+	case code == http.StatusTooManyRequests:
+		return &domain.RateLimitError{ProviderError: base}
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return &domain.AuthError{ProviderError: base}
+	case code == http.StatusRequestTimeout || code >= 500:
+		return &domain.ServerError{ProviderError: base}
+	case code == http.StatusRequestEntityTooLarge || isContextLengthMessage(readSmallBody(resp.Body, 4096)):
+		return &domain.ContextLengthError{ProviderError: base}
+	default:
+		return &domain.BadRequestError{ProviderError: base}
+	}
+}
+
+var contextLengthHints = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context window",
+	"prompt is too long",
+	"exceeds the maximum number of tokens",
+	"input token count",
+}
+
+func isContextLengthMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, hint := range contextLengthHints {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter reads Retry-After (seconds or HTTP date) and the
+// millisecond variant some providers send.
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	if v := strings.TrimSpace(h.Get("Retry-After-Ms")); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
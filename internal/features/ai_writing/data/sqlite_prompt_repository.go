@@ -0,0 +1,445 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/core/migrate"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/data/models"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+type SQLitePromptRepository struct {
+	db        *sql.DB
+	clock     domain.Clock
+	seeds     []domain.Prompt
+	variables map[string][]string
+}
+
+type PromptRepositoryOption func(*SQLitePromptRepository) error
+
+func WithPromptClock(clock domain.Clock) PromptRepositoryOption {
+	return func(r *SQLitePromptRepository) error {
+		if clock == nil {
+			return errors.New("prompt repository: clock is nil")
+		}
+		r.clock = clock
+		return nil
+	}
+}
+
+// WithSeedPrompts replaces the built-in defaults that are inserted for prompt
+// names missing from the database. Each name is seeded once, so a default the
+// user deletes stays deleted.
+func WithSeedPrompts(prompts ...domain.Prompt) PromptRepositoryOption {
+	return func(r *SQLitePromptRepository) error {
+		r.seeds = prompts
+		return nil
+	}
+}
+
+// WithPromptVariables sets, per prompt name, the variables a saved prompt may
+// reference. Names without an entry accept any variables.
+func WithPromptVariables(vars map[string][]string) PromptRepositoryOption {
+	return func(r *SQLitePromptRepository) error {
+		r.variables = make(map[string][]string, len(vars))
+		for name, v := range vars {
+			r.variables[promptKey(name)] = v
+		}
+		return nil
+	}
+}
+
+func NewSQLitePromptRepository(db *sql.DB, opts ...PromptRepositoryOption) (*SQLitePromptRepository, error) {
+	if db == nil {
+		return nil, errors.New("prompt repository: db is nil")
+	}
+	repo := &SQLitePromptRepository{db: db, clock: systemClock{}, seeds: DefaultPrompts()}
+	if err := WithPromptVariables(DefaultPromptVariables())(repo); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(repo); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := context.Background()
+	if err := repo.EnsureSchema(ctx); err != nil {
+		return nil, err
+	}
+	if err := repo.seed(ctx); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *SQLitePromptRepository) EnsureSchema(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return migrate.Apply(ctx, r.db, MigrationFeature, Migrations())
+}
+
+// seed creates the seed prompts not seeded before and records them in
+// ai_prompt_seeds. The record is written after the prompt, so an interrupted
+// run retries the name instead of losing it.
+func (r *SQLitePromptRepository) seed(ctx context.Context) error {
+	for _, p := range r.seeds {
+		key := promptKey(p.Name)
+		var seeded int
+		if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM ai_prompt_seeds WHERE name = ?`, key).Scan(&seeded); err != nil {
+			return err
+		}
+		if seeded > 0 {
+			continue
+		}
+		if _, err := r.CreatePrompt(ctx, p); err != nil && !errors.Is(err, domain.ErrConflict) {
+			return err
+		}
+		if _, err := r.db.ExecContext(ctx, `INSERT OR IGNORE INTO ai_prompt_seeds(name, seeded_at_ms) VALUES(?, ?)`, key, r.clock.Now().UTC().UnixMilli()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLitePromptRepository) GetPrompt(ctx context.Context, name string) (domain.Prompt, error) {
+	key := promptKey(name)
+	if key == "" {
+		return domain.Prompt{}, errors.Join(domain.ErrInvalidArgument, errors.New("prompt name is required"))
+	}
+	v, err := r.getVersion(ctx, r.db, key, 0)
+	if err != nil {
+		return domain.Prompt{}, err
+	}
+	return v.Prompt, nil
+}
+
+func (r *SQLitePromptRepository) ListPrompts(ctx context.Context) ([]domain.Prompt, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT v.prompt_json
+FROM ai_prompts p
+JOIN ai_prompt_versions v ON v.name = p.name AND v.version = p.current_version
+ORDER BY p.name ASC
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Prompt, 0)
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		p, err := decodePrompt(raw)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *SQLitePromptRepository) CreatePrompt(ctx context.Context, p domain.Prompt) (domain.PromptVersion, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if err := r.validate(p); err != nil {
+		return domain.PromptVersion{}, err
+	}
+	raw, err := encodePrompt(p)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+
+	key := promptKey(p.Name)
+	nowMs := r.clock.Now().UTC().UnixMilli()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO ai_prompts(name, current_version, created_at_ms, updated_at_ms)
+VALUES(?, 1, ?, ?)
+`, key, nowMs, nowMs); err != nil {
+		if isUniqueConstraintErr(err) {
+			return domain.PromptVersion{}, errors.Join(domain.ErrConflict, err)
+		}
+		return domain.PromptVersion{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO ai_prompt_versions(name, version, prompt_json, created_at_ms)
+VALUES(?, 1, ?, ?)
+`, key, raw, nowMs); err != nil {
+		return domain.PromptVersion{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.PromptVersion{}, err
+	}
+	return domain.PromptVersion{Name: p.Name, Version: 1, Prompt: p, CreatedAt: time.UnixMilli(nowMs).UTC()}, nil
+}
+
+// UpdatePrompt stores p as a new version of the prompt with the same name.
+// Saving content identical to the current version does not add a version.
+func (r *SQLitePromptRepository) UpdatePrompt(ctx context.Context, p domain.Prompt) (domain.PromptVersion, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if err := r.validate(p); err != nil {
+		return domain.PromptVersion{}, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := r.getVersion(ctx, tx, promptKey(p.Name), 0)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	if reflect.DeepEqual(current.Prompt, p) {
+		return current, nil
+	}
+
+	v, err := r.appendVersionTx(ctx, tx, current.Version, p)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.PromptVersion{}, err
+	}
+	return v, nil
+}
+
+func (r *SQLitePromptRepository) DeletePrompt(ctx context.Context, name string) error {
+	key := promptKey(name)
+	if key == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("prompt name is required"))
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ai_prompt_versions WHERE name = ?`, key); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM ai_prompts WHERE name = ?`, key)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return tx.Commit()
+}
+
+func (r *SQLitePromptRepository) ListPromptVersions(ctx context.Context, name string) ([]domain.PromptVersion, error) {
+	key := promptKey(name)
+	if key == "" {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("prompt name is required"))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT version, prompt_json, created_at_ms
+FROM ai_prompt_versions
+WHERE name = ?
+ORDER BY version DESC
+`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.PromptVersion, 0)
+	for rows.Next() {
+		var version int
+		var raw string
+		var createdAtMs int64
+		if err := rows.Scan(&version, &raw, &createdAtMs); err != nil {
+			return nil, err
+		}
+		p, err := decodePrompt(raw)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, domain.PromptVersion{Name: p.Name, Version: version, Prompt: p, CreatedAt: time.UnixMilli(createdAtMs).UTC()})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, domain.ErrNotFound
+	}
+	return out, nil
+}
+
+func (r *SQLitePromptRepository) GetPromptVersion(ctx context.Context, name string, version int) (domain.PromptVersion, error) {
+	key := promptKey(name)
+	if key == "" {
+		return domain.PromptVersion{}, errors.Join(domain.ErrInvalidArgument, errors.New("prompt name is required"))
+	}
+	if version <= 0 {
+		return domain.PromptVersion{}, errors.Join(domain.ErrInvalidArgument, errors.New("version must be positive"))
+	}
+	return r.getVersion(ctx, r.db, key, version)
+}
+
+// RollbackPrompt makes the content of an earlier version current again by
+// appending it as a new version, so the rollback itself can be undone.
+func (r *SQLitePromptRepository) RollbackPrompt(ctx context.Context, name string, version int) (domain.PromptVersion, error) {
+	key := promptKey(name)
+	if key == "" {
+		return domain.PromptVersion{}, errors.Join(domain.ErrInvalidArgument, errors.New("prompt name is required"))
+	}
+	if version <= 0 {
+		return domain.PromptVersion{}, errors.Join(domain.ErrInvalidArgument, errors.New("version must be positive"))
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := r.getVersion(ctx, tx, key, 0)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	target, err := r.getVersion(ctx, tx, key, version)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	if err := r.validate(target.Prompt); err != nil {
+		return domain.PromptVersion{}, err
+	}
+
+	v, err := r.appendVersionTx(ctx, tx, current.Version, target.Prompt)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.PromptVersion{}, err
+	}
+	return v, nil
+}
+
+func (r *SQLitePromptRepository) validate(p domain.Prompt) error {
+	if err := p.Validate(); err != nil {
+		return errors.Join(domain.ErrInvalidArgument, err)
+	}
+	if allowed, ok := r.variables[promptKey(p.Name)]; ok {
+		if err := p.ValidateVariables(allowed); err != nil {
+			return errors.Join(domain.ErrInvalidArgument, err)
+		}
+	}
+	return nil
+}
+
+func (r *SQLitePromptRepository) appendVersionTx(ctx context.Context, tx *sql.Tx, currentVersion int, p domain.Prompt) (domain.PromptVersion, error) {
+	raw, err := encodePrompt(p)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	key := promptKey(p.Name)
+	nowMs := r.clock.Now().UTC().UnixMilli()
+	newVersion := currentVersion + 1
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO ai_prompt_versions(name, version, prompt_json, created_at_ms)
+VALUES(?, ?, ?, ?)
+`, key, newVersion, raw, nowMs); err != nil {
+		if isUniqueConstraintErr(err) {
+			return domain.PromptVersion{}, errors.Join(domain.ErrConflict, err)
+		}
+		return domain.PromptVersion{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+UPDATE ai_prompts
+SET current_version = ?, updated_at_ms = ?
+WHERE name = ?
+`, newVersion, nowMs, key); err != nil {
+		return domain.PromptVersion{}, err
+	}
+	return domain.PromptVersion{Name: p.Name, Version: newVersion, Prompt: p, CreatedAt: time.UnixMilli(nowMs).UTC()}, nil
+}
+
+type promptQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// getVersion loads one version of a prompt; version 0 means the current one.
+func (r *SQLitePromptRepository) getVersion(ctx context.Context, q promptQueryer, key string, version int) (domain.PromptVersion, error) {
+	var row *sql.Row
+	if version == 0 {
+		row = q.QueryRowContext(ctx, `
+SELECT v.version, v.prompt_json, v.created_at_ms
+FROM ai_prompts p
+JOIN ai_prompt_versions v ON v.name = p.name AND v.version = p.current_version
+WHERE p.name = ?
+`, key)
+	} else {
+		row = q.QueryRowContext(ctx, `
+SELECT version, prompt_json, created_at_ms
+FROM ai_prompt_versions
+WHERE name = ? AND version = ?
+`, key, version)
+	}
+
+	var v int
+	var raw string
+	var createdAtMs int64
+	if err := row.Scan(&v, &raw, &createdAtMs); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.PromptVersion{}, errors.Join(domain.ErrNotFound, errors.New("prompt not found"))
+		}
+		return domain.PromptVersion{}, err
+	}
+	p, err := decodePrompt(raw)
+	if err != nil {
+		return domain.PromptVersion{}, err
+	}
+	return domain.PromptVersion{Name: p.Name, Version: v, Prompt: p, CreatedAt: time.UnixMilli(createdAtMs).UTC()}, nil
+}
+
+func promptKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func encodePrompt(p domain.Prompt) (string, error) {
+	b, err := json.Marshal(models.FromPrompt(p))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func decodePrompt(raw string) (domain.Prompt, error) {
+	var dto models.PromptDTO
+	if err := json.Unmarshal([]byte(raw), &dto); err != nil {
+		return domain.Prompt{}, err
+	}
+	return dto.ToDomain()
+}
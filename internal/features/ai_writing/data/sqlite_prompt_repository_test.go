@@ -0,0 +1,164 @@
+﻿package data_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/data"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+type stepClock struct{ t time.Time }
+
+func (c *stepClock) Now() time.Time {
+	c.t = c.t.Add(time.Second)
+	return c.t
+}
+
+func userPrompt(name, template string) domain.Prompt {
+	return domain.Prompt{Name: name, Messages: []domain.PromptMessage{{Role: domain.RoleUser, Template: template}}}
+}
+
+func TestSQLitePromptRepository_SeedsDefaults(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo, err := data.NewSQLitePromptRepository(db)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	prompts, err := repo.ListPrompts(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(prompts) != len(data.DefaultPrompts()) {
+		t.Fatalf("expected %d seeded prompts, got %d", len(data.DefaultPrompts()), len(prompts))
+	}
+
+	if _, err := repo.UpdatePrompt(ctx, userPrompt("summarize", "TL;DR: {{text}}")); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	// Reopening must not overwrite user edits with the seed data.
+	reopened, err := data.NewSQLitePromptRepository(db)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	p, err := reopened.GetPrompt(ctx, "summarize")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if p.Messages[0].Template != "TL;DR: {{text}}" {
+		t.Fatalf("seed overwrote edit: %+v", p)
+	}
+
+	// Nor bring back a default the user deleted.
+	if err := reopened.DeletePrompt(ctx, "summarize"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	again, err := data.NewSQLitePromptRepository(db)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if _, err := again.GetPrompt(ctx, "summarize"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected the deleted default to stay deleted, got %v", err)
+	}
+}
+
+func TestSQLitePromptRepository_VersionsAndRollback(t *testing.T) {
+	ctx := context.Background()
+	clk := &stepClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repo, err := data.NewSQLitePromptRepository(openTestDB(t), data.WithPromptClock(clk), data.WithSeedPrompts())
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+
+	v1, err := repo.CreatePrompt(ctx, userPrompt("Headline", "v1 {{topic}}"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if v1.Version != 1 {
+		t.Fatalf("expected version 1, got %d", v1.Version)
+	}
+	if _, err := repo.CreatePrompt(ctx, userPrompt("headline", "dup")); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+
+	v2, err := repo.UpdatePrompt(ctx, userPrompt("Headline", "v2 {{topic}}"))
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if v2.Version != 2 {
+		t.Fatalf("expected version 2, got %d", v2.Version)
+	}
+	same, err := repo.UpdatePrompt(ctx, userPrompt("Headline", "v2 {{topic}}"))
+	if err != nil {
+		t.Fatalf("update unchanged: %v", err)
+	}
+	if same.Version != 2 {
+		t.Fatalf("unchanged update should keep version 2, got %d", same.Version)
+	}
+
+	v3, err := repo.RollbackPrompt(ctx, "headline", 1)
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if v3.Version != 3 || v3.Prompt.Messages[0].Template != "v1 {{topic}}" {
+		t.Fatalf("unexpected rollback result: %+v", v3)
+	}
+
+	current, err := repo.GetPrompt(ctx, "HEADLINE")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if current.Messages[0].Template != "v1 {{topic}}" {
+		t.Fatalf("unexpected current prompt: %+v", current)
+	}
+
+	versions, err := repo.ListPromptVersions(ctx, "headline")
+	if err != nil {
+		t.Fatalf("list versions: %v", err)
+	}
+	if len(versions) != 3 || versions[0].Version != 3 || versions[2].Version != 1 {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+	old, err := repo.GetPromptVersion(ctx, "headline", 2)
+	if err != nil {
+		t.Fatalf("get version: %v", err)
+	}
+	if old.Prompt.Messages[0].Template != "v2 {{topic}}" {
+		t.Fatalf("unexpected version 2: %+v", old)
+	}
+
+	if err := repo.DeletePrompt(ctx, "headline"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.GetPrompt(ctx, "headline"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if _, err := repo.ListPromptVersions(ctx, "headline"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected versions to be deleted, got %v", err)
+	}
+	if err := repo.DeletePrompt(ctx, "headline"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSQLitePromptRepository_ValidatesOnSave(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLitePromptRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+
+	if _, err := repo.CreatePrompt(ctx, domain.Prompt{Name: "empty"}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for invalid prompt, got %v", err)
+	}
+	if _, err := repo.UpdatePrompt(ctx, userPrompt("generate_content", "About {{subject}}")); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for unknown variable, got %v", err)
+	}
+	if _, err := repo.UpdatePrompt(ctx, userPrompt("missing", "x")); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
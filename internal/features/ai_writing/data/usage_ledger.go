@@ -0,0 +1,242 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Xiaoxinkeji/WX/internal/core/migrate"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+type SQLiteUsageLedger struct {
+	db     *sql.DB
+	clock  domain.Clock
+	prices domain.PriceTable
+	limits domain.SpendLimits
+}
+
+type UsageLedgerOption func(*SQLiteUsageLedger) error
+
+func WithUsageClock(clock domain.Clock) UsageLedgerOption {
+	return func(l *SQLiteUsageLedger) error {
+		if clock == nil {
+			return errors.New("usage ledger: clock is nil")
+		}
+		l.clock = clock
+		return nil
+	}
+}
+
+// WithPriceTable prices recorded usage. Usage of models missing from the
+// table is recorded at zero cost.
+func WithPriceTable(prices domain.PriceTable) UsageLedgerOption {
+	return func(l *SQLiteUsageLedger) error {
+		for key, p := range prices {
+			if p.PromptPerMillion < 0 || p.CompletionPerMillion < 0 {
+				return fmt.Errorf("usage ledger: negative price for %q", key)
+			}
+		}
+		l.prices = prices
+		return nil
+	}
+}
+
+func WithSpendLimits(limits domain.SpendLimits) UsageLedgerOption {
+	return func(l *SQLiteUsageLedger) error {
+		if limits.DailyMicros < 0 || limits.MonthlyMicros < 0 {
+			return errors.New("usage ledger: spend limits must not be negative")
+		}
+		l.limits = limits
+		return nil
+	}
+}
+
+func NewSQLiteUsageLedger(db *sql.DB, opts ...UsageLedgerOption) (*SQLiteUsageLedger, error) {
+	if db == nil {
+		return nil, errors.New("usage ledger: db is nil")
+	}
+	ledger := &SQLiteUsageLedger{db: db, clock: systemClock{}}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(ledger); err != nil {
+			return nil, err
+		}
+	}
+	if err := ledger.EnsureSchema(context.Background()); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+func (l *SQLiteUsageLedger) EnsureSchema(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return migrate.Apply(ctx, l.db, MigrationFeature, Migrations())
+}
+
+// RecordUsage prices rec from the price table and stores it. Any CostMicros
+// set by the caller is replaced.
+func (l *SQLiteUsageLedger) RecordUsage(ctx context.Context, rec domain.UsageRecord) (domain.UsageRecord, error) {
+	if strings.TrimSpace(rec.GenerationID) == "" {
+		return domain.UsageRecord{}, errors.Join(domain.ErrInvalidArgument, errors.New("generation id is required"))
+	}
+	if rec.Usage.PromptTokens < 0 || rec.Usage.CompletionTokens < 0 {
+		return domain.UsageRecord{}, errors.Join(domain.ErrInvalidArgument, errors.New("token counts must not be negative"))
+	}
+	if rec.Usage.TotalTokens == 0 {
+		rec.Usage.TotalTokens = rec.Usage.PromptTokens + rec.Usage.CompletionTokens
+	}
+	rec.CostMicros = 0
+	if price, ok := l.prices.Price(rec.Provider, rec.Model); ok {
+		rec.CostMicros = price.CostMicros(rec.Usage)
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = l.clock.Now()
+	}
+	rec.CreatedAt = time.UnixMilli(rec.CreatedAt.UTC().UnixMilli()).UTC()
+
+	estimated := 0
+	if rec.Estimated {
+		estimated = 1
+	}
+	if _, err := l.db.ExecContext(ctx, `
+INSERT INTO ai_usage(generation_id, article_id, provider, model, prompt_tokens, completion_tokens, total_tokens, estimated, cost_micros, created_at_ms)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, rec.GenerationID, rec.ArticleID, rec.Provider, rec.Model, rec.Usage.PromptTokens, rec.Usage.CompletionTokens, rec.Usage.TotalTokens, estimated, rec.CostMicros, rec.CreatedAt.UnixMilli()); err != nil {
+		if isUniqueConstraintErr(err) {
+			return domain.UsageRecord{}, errors.Join(domain.ErrConflict, err)
+		}
+		return domain.UsageRecord{}, err
+	}
+	return rec, nil
+}
+
+// CheckBudget returns a *domain.BudgetExceededError once the spend of the
+// current day or month has reached its limit.
+func (l *SQLiteUsageLedger) CheckBudget(ctx context.Context) error {
+	loc := l.limits.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := l.clock.Now().In(loc)
+	checks := []struct {
+		period string
+		limit  int64
+		since  time.Time
+	}{
+		{"daily", l.limits.DailyMicros, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)},
+		{"monthly", l.limits.MonthlyMicros, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)},
+	}
+	for _, c := range checks {
+		if c.limit <= 0 {
+			continue
+		}
+		var spent int64
+		if err := l.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(cost_micros), 0) FROM ai_usage WHERE created_at_ms >= ?`, c.since.UnixMilli()).Scan(&spent); err != nil {
+			return err
+		}
+		if spent >= c.limit {
+			return &domain.BudgetExceededError{Period: c.period, LimitMicros: c.limit, SpentMicros: spent}
+		}
+	}
+	return nil
+}
+
+func (l *SQLiteUsageLedger) SummarizeUsage(ctx context.Context, query domain.UsageQuery) ([]domain.UsageSummary, error) {
+	if !query.GroupBy.Valid() {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("invalid usage grouping"))
+	}
+	if query.Since != nil && query.Until != nil && query.Until.Before(*query.Since) {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("until must not be before since"))
+	}
+
+	key := "''"
+	switch query.GroupBy {
+	case domain.UsageGroupArticle:
+		key = "article_id"
+	case domain.UsageGroupModel:
+		key = "provider || '/' || model"
+	case domain.UsageGroupDay:
+		key = "strftime('%Y-%m-%d', created_at_ms / 1000, 'unixepoch')"
+	}
+
+	var b strings.Builder
+	args := make([]any, 0, 5)
+	b.WriteString(`SELECT ` + key + `, COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost_micros), 0) FROM ai_usage WHERE 1=1`)
+	if query.ArticleID != nil {
+		b.WriteString(" AND article_id = ?")
+		args = append(args, strings.TrimSpace(*query.ArticleID))
+	}
+	if query.Provider != nil {
+		b.WriteString(" AND provider = ?")
+		args = append(args, strings.TrimSpace(*query.Provider))
+	}
+	if query.Model != nil {
+		b.WriteString(" AND model = ?")
+		args = append(args, strings.TrimSpace(*query.Model))
+	}
+	if query.Since != nil {
+		b.WriteString(" AND created_at_ms >= ?")
+		args = append(args, query.Since.UTC().UnixMilli())
+	}
+	if query.Until != nil {
+		b.WriteString(" AND created_at_ms < ?")
+		args = append(args, query.Until.UTC().UnixMilli())
+	}
+	if query.GroupBy != domain.UsageGroupNone {
+		b.WriteString(" GROUP BY 1 ORDER BY 1")
+	}
+
+	rows, err := l.db.QueryContext(ctx, b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.UsageSummary, 0)
+	for rows.Next() {
+		var s domain.UsageSummary
+		if err := rows.Scan(&s.Key, &s.Requests, &s.Usage.PromptTokens, &s.Usage.CompletionTokens, &s.Usage.TotalTokens, &s.CostMicros); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LoadPriceTable reads a price table from a YAML or JSON file mapping
+// "provider/model" to per-million token prices.
+func LoadPriceTable(path string) (domain.PriceTable, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var table domain.PriceTable
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &table)
+	case ".json":
+		err = json.Unmarshal(b, &table)
+	default:
+		return nil, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("unsupported price table format %q", filepath.Ext(path)))
+	}
+	if err != nil {
+		return nil, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("%s: %w", path, err))
+	}
+	return table, nil
+}
@@ -0,0 +1,123 @@
+﻿package data_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/data"
+	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+)
+
+func TestSQLiteUsageLedger_PricesAndSummarizes(t *testing.T) {
+	ctx := context.Background()
+	prices := domain.PriceTable{
+		"openai/gpt-4o": {PromptPerMillion: 2.5, CompletionPerMillion: 10},
+		"claude/*":      {PromptPerMillion: 3, CompletionPerMillion: 15},
+	}
+	ledger, err := data.NewSQLiteUsageLedger(openTestDB(t), data.WithPriceTable(prices))
+	if err != nil {
+		t.Fatalf("new ledger: %v", err)
+	}
+
+	day := time.Date(2025, 5, 1, 8, 0, 0, 0, time.UTC)
+	records := []domain.UsageRecord{
+		{GenerationID: "g1", ArticleID: "a1", Provider: "openai", Model: "gpt-4o", Usage: domain.TokenUsage{PromptTokens: 1000, CompletionTokens: 500}, CreatedAt: day},
+		{GenerationID: "g2", ArticleID: "a1", Provider: "claude", Model: "claude-3-haiku", Usage: domain.TokenUsage{PromptTokens: 100, CompletionTokens: 100}, CreatedAt: day.Add(time.Hour)},
+		{GenerationID: "g3", ArticleID: "a2", Provider: "gemini", Model: "flash", Usage: domain.TokenUsage{PromptTokens: 10}, CreatedAt: day.Add(24 * time.Hour)},
+	}
+	for _, rec := range records {
+		if _, err := ledger.RecordUsage(ctx, rec); err != nil {
+			t.Fatalf("record %s: %v", rec.GenerationID, err)
+		}
+	}
+	if _, err := ledger.RecordUsage(ctx, records[0]); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict on duplicate, got %v", err)
+	}
+
+	a1 := "a1"
+	got, err := ledger.SummarizeUsage(ctx, domain.UsageQuery{ArticleID: &a1})
+	if err != nil {
+		t.Fatalf("summarize article: %v", err)
+	}
+	// 1000*2.5 + 500*10 = 7500 micros, 100*3 + 100*15 = 1800 micros.
+	if len(got) != 1 || got[0].Requests != 2 || got[0].CostMicros != 9300 || got[0].Usage.TotalTokens != 1700 {
+		t.Fatalf("unexpected article summary: %+v", got)
+	}
+
+	since := day.Add(24 * time.Hour)
+	got, err = ledger.SummarizeUsage(ctx, domain.UsageQuery{Since: &since})
+	if err != nil {
+		t.Fatalf("summarize period: %v", err)
+	}
+	if len(got) != 1 || got[0].Requests != 1 || got[0].CostMicros != 0 {
+		t.Fatalf("unpriced model should cost nothing, got %+v", got)
+	}
+
+	got, err = ledger.SummarizeUsage(ctx, domain.UsageQuery{GroupBy: domain.UsageGroupDay})
+	if err != nil {
+		t.Fatalf("summarize by day: %v", err)
+	}
+	if len(got) != 2 || got[0].Key != "2025-05-01" || got[0].CostMicros != 9300 || got[1].Key != "2025-05-02" {
+		t.Fatalf("unexpected daily summary: %+v", got)
+	}
+
+	if _, err := ledger.SummarizeUsage(ctx, domain.UsageQuery{GroupBy: "week"}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestSQLiteUsageLedger_CheckBudget(t *testing.T) {
+	ctx := context.Background()
+	clk := &stepClock{t: time.Date(2025, 5, 20, 12, 0, 0, 0, time.UTC)}
+	ledger, err := data.NewSQLiteUsageLedger(openTestDB(t),
+		data.WithUsageClock(clk),
+		data.WithPriceTable(domain.PriceTable{"openai/*": {PromptPerMillion: 1, CompletionPerMillion: 1}}),
+		data.WithSpendLimits(domain.SpendLimits{DailyMicros: 1000, MonthlyMicros: 1500}),
+	)
+	if err != nil {
+		t.Fatalf("new ledger: %v", err)
+	}
+
+	if err := ledger.CheckBudget(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Yesterday's spend only counts toward the monthly limit.
+	if _, err := ledger.RecordUsage(ctx, domain.UsageRecord{GenerationID: "g1", Provider: "openai", Model: "m", Usage: domain.TokenUsage{PromptTokens: 900}, CreatedAt: clk.t.Add(-24 * time.Hour)}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := ledger.CheckBudget(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := ledger.RecordUsage(ctx, domain.UsageRecord{GenerationID: "g2", Provider: "openai", Model: "m", Usage: domain.TokenUsage{CompletionTokens: 600}}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	err = ledger.CheckBudget(ctx)
+	var exceeded *domain.BudgetExceededError
+	if !errors.As(err, &exceeded) || !errors.Is(err, domain.ErrBudgetExceeded) {
+		t.Fatalf("expected BudgetExceededError, got %v", err)
+	}
+	if exceeded.Period != "monthly" || exceeded.SpentMicros != 1500 || exceeded.LimitMicros != 1500 {
+		t.Fatalf("unexpected budget error: %+v", exceeded)
+	}
+}
+
+func TestLoadPriceTable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prices.yaml")
+	if err := os.WriteFile(path, []byte("openai/gpt-4o:\n  prompt_per_million: 2.5\n  completion_per_million: 10\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	table, err := data.LoadPriceTable(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	price, ok := table.Price("OpenAI", "GPT-4o")
+	if !ok || price.CompletionPerMillion != 10 {
+		t.Fatalf("unexpected price: %+v %v", price, ok)
+	}
+}
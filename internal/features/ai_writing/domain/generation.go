@@ -20,9 +20,25 @@ type Generation struct {
 	InputText  string
 	OutputText string
 	ArticleID  string
+	Usage      TokenUsage
 	CreatedAt  time.Time
 }
 
+func (t GenerationType) Valid() bool {
+	switch t {
+	case GenerationTypeGenerate, GenerationTypeRewrite, GenerationTypeSummarize, GenerationTypeContinuous:
+		return true
+	default:
+		return false
+	}
+}
+
+// TextRange selects the runes [Start, End) of a text.
+type TextRange struct {
+	Start int
+	End   int
+}
+
 type ChatRequest struct {
 	Model       string
 	Messages    []Message
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 type Role string
@@ -31,6 +32,25 @@ type Prompt struct {
 	Messages []PromptMessage
 }
 
+type PromptVersion struct {
+	Name      string
+	Version   int
+	Prompt    Prompt
+	CreatedAt time.Time
+}
+
+// PromptMessageError reports which message of a prompt failed validation.
+type PromptMessageError struct {
+	Index int
+	Err   error
+}
+
+func (e *PromptMessageError) Error() string {
+	return fmt.Sprintf("prompt message %d: %v", e.Index, e.Err)
+}
+
+func (e *PromptMessageError) Unwrap() error { return e.Err }
+
 var placeholderRE = regexp.MustCompile(`{{\s*([a-zA-Z0-9_]+)\s*}}`)
 
 func (p Prompt) Validate() error {
@@ -42,10 +62,10 @@ func (p Prompt) Validate() error {
 	}
 	for i, m := range p.Messages {
 		if m.Role == "" {
-			return fmt.Errorf("prompt message %d: role is required", i)
+			return &PromptMessageError{Index: i, Err: errors.New("role is required")}
 		}
 		if strings.TrimSpace(m.Template) == "" {
-			return fmt.Errorf("prompt message %d: template is required", i)
+			return &PromptMessageError{Index: i, Err: errors.New("template is required")}
 		}
 	}
 	return nil
@@ -68,6 +88,25 @@ func (p Prompt) RequiredVariables() []string {
 	return vars
 }
 
+// ValidateVariables reports placeholders that the caller of the prompt will
+// never supply, so a broken template is rejected on save instead of at render time.
+func (p Prompt) ValidateVariables(allowed []string) error {
+	set := make(map[string]struct{}, len(allowed))
+	for _, v := range allowed {
+		set[v] = struct{}{}
+	}
+	var unknown []string
+	for _, v := range p.RequiredVariables() {
+		if _, ok := set[v]; !ok {
+			unknown = append(unknown, v)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown variables: %s", strings.Join(unknown, ","))
+	}
+	return nil
+}
+
 func (p Prompt) Render(vars map[string]string) ([]Message, error) {
 	if err := p.Validate(); err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 ﻿package domain_test
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -49,3 +50,22 @@ func TestPrompt_RequiredVariablesAndRender(t *testing.T) {
 		t.Fatalf("unexpected content: %q", msgs[1].Content)
 	}
 }
+
+func TestPrompt_ValidateVariables(t *testing.T) {
+	p := domain.Prompt{Name: "p", Messages: []domain.PromptMessage{{Role: domain.RoleUser, Template: "{{topic}} {{tone}}"}}}
+	if err := p.ValidateVariables([]string{"topic", "tone", "extra"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.ValidateVariables([]string{"topic"}); err == nil {
+		t.Fatalf("expected error for unknown variable")
+	}
+}
+
+func TestPrompt_ValidateReportsMessageIndex(t *testing.T) {
+	p := domain.Prompt{Name: "p", Messages: []domain.PromptMessage{{Role: domain.RoleUser, Template: "ok"}, {Role: domain.RoleUser}}}
+	err := p.Validate()
+	var msgErr *domain.PromptMessageError
+	if !errors.As(err, &msgErr) || msgErr.Index != 1 {
+		t.Fatalf("expected PromptMessageError at index 1, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+﻿package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ProviderError describes a failed call to an AI provider. StatusCode is 0
+// when no HTTP response was received. The typed variants below embed it, and
+// all of them match ErrProvider with errors.Is.
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ProviderError) Error() string { return e.describe("request failed") }
+
+func (e *ProviderError) Is(target error) bool { return target == ErrProvider }
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+func (e *ProviderError) details() *ProviderError { return e }
+
+func (e *ProviderError) describe(kind string) string {
+	msg := e.Provider + ": " + kind
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+type RateLimitError struct{ ProviderError }
+
+func (e *RateLimitError) Error() string { return e.describe("rate limited") }
+
+type AuthError struct{ ProviderError }
+
+func (e *AuthError) Error() string { return e.describe("authentication failed") }
+
+type BadRequestError struct{ ProviderError }
+
+func (e *BadRequestError) Error() string { return e.describe("bad request") }
+
+type ContextLengthError struct{ ProviderError }
+
+func (e *ContextLengthError) Error() string { return e.describe("context length exceeded") }
+
+type ServerError struct{ ProviderError }
+
+func (e *ServerError) Error() string { return e.describe("server error") }
+
+// ProviderErrorDetails returns the ProviderError carried by err, whichever
+// typed variant wraps it.
+func ProviderErrorDetails(err error) (*ProviderError, bool) {
+	var d interface{ details() *ProviderError }
+	if !errors.As(err, &d) {
+		return nil, false
+	}
+	return d.details(), true
+}
+
+// IsRetryable reports whether repeating the same request may succeed:
+// rate limits, server errors and network failures.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rateLimit *RateLimitError
+	var server *ServerError
+	if errors.As(err, &rateLimit) || errors.As(err, &server) {
+		return true
+	}
+	var network *ProviderError
+	return errors.As(err, &network) && network.StatusCode == 0
+}
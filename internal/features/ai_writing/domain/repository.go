@@ -8,6 +8,7 @@ import (
 
 var (
 	ErrNotFound        = errors.New("ai_writing: not found")
+	ErrConflict        = errors.New("ai_writing: conflict")
 	ErrInvalidArgument = errors.New("ai_writing: invalid argument")
 	ErrProvider        = errors.New("ai_writing: provider error")
 	ErrStream          = errors.New("ai_writing: stream error")
@@ -17,6 +18,57 @@ type PromptRepository interface {
 	GetPrompt(ctx context.Context, name string) (Prompt, error)
 }
 
+type PromptLister interface {
+	ListPrompts(ctx context.Context) ([]Prompt, error)
+}
+
+type PromptWriter interface {
+	CreatePrompt(ctx context.Context, p Prompt) (PromptVersion, error)
+	UpdatePrompt(ctx context.Context, p Prompt) (PromptVersion, error)
+	DeletePrompt(ctx context.Context, name string) error
+}
+
+type PromptVersionLister interface {
+	ListPromptVersions(ctx context.Context, name string) ([]PromptVersion, error)
+	GetPromptVersion(ctx context.Context, name string, version int) (PromptVersion, error)
+	RollbackPrompt(ctx context.Context, name string, version int) (PromptVersion, error)
+}
+
+type PromptStore interface {
+	PromptRepository
+	PromptLister
+	PromptWriter
+	PromptVersionLister
+}
+
+type ListGenerationsQuery struct {
+	ArticleID *string
+	Type      *GenerationType
+	Provider  *string
+	Since     *time.Time
+	Until     *time.Time
+	Limit     int
+	Offset    int
+}
+
+type GenerationCreator interface {
+	CreateGeneration(ctx context.Context, g Generation) (Generation, error)
+}
+
+type GenerationGetter interface {
+	GetGeneration(ctx context.Context, id string) (Generation, error)
+}
+
+type GenerationLister interface {
+	ListGenerations(ctx context.Context, query ListGenerationsQuery) ([]Generation, error)
+}
+
+type GenerationRepository interface {
+	GenerationCreator
+	GenerationGetter
+	GenerationLister
+}
+
 type Clock interface {
 	Now() time.Time
 }
@@ -0,0 +1,161 @@
+﻿package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrBudgetExceeded = errors.New("ai_writing: budget exceeded")
+
+// UsageRecord is one priced entry in the usage ledger. Costs are stored in
+// micro-units (millionths) of the price table's currency.
+type UsageRecord struct {
+	GenerationID string
+	ArticleID    string
+	Provider     string
+	Model        string
+	Usage        TokenUsage
+	Estimated    bool
+	CostMicros   int64
+	CreatedAt    time.Time
+}
+
+// ModelPrice is the price per million prompt and completion tokens.
+type ModelPrice struct {
+	PromptPerMillion     float64 `json:"prompt_per_million" yaml:"prompt_per_million"`
+	CompletionPerMillion float64 `json:"completion_per_million" yaml:"completion_per_million"`
+}
+
+func (p ModelPrice) CostMicros(u TokenUsage) int64 {
+	return int64(float64(u.PromptTokens)*p.PromptPerMillion + float64(u.CompletionTokens)*p.CompletionPerMillion + 0.5)
+}
+
+// PriceTable maps "provider/model" to a price. A "provider/*" entry prices
+// any model of that provider without an exact entry.
+type PriceTable map[string]ModelPrice
+
+func (t PriceTable) Price(provider, model string) (ModelPrice, bool) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	model = strings.ToLower(strings.TrimSpace(model))
+	for key, price := range t {
+		if strings.ToLower(key) == provider+"/"+model {
+			return price, true
+		}
+	}
+	for key, price := range t {
+		if strings.ToLower(key) == provider+"/*" {
+			return price, true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// SpendLimits caps spend per calendar day and month in Location (UTC when
+// nil). Zero limits are unlimited.
+type SpendLimits struct {
+	DailyMicros   int64
+	MonthlyMicros int64
+	Location      *time.Location
+}
+
+type BudgetExceededError struct {
+	Period      string
+	LimitMicros int64
+	SpentMicros int64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("ai_writing: %s budget exceeded: spent %s of %s", e.Period, formatMicros(e.SpentMicros), formatMicros(e.LimitMicros))
+}
+
+func (e *BudgetExceededError) Is(target error) bool { return target == ErrBudgetExceeded }
+
+func formatMicros(v int64) string {
+	return fmt.Sprintf("%.6f", float64(v)/1e6)
+}
+
+type UsageGroup string
+
+const (
+	UsageGroupNone    UsageGroup = ""
+	UsageGroupArticle UsageGroup = "article"
+	UsageGroupModel   UsageGroup = "model"
+	UsageGroupDay     UsageGroup = "day"
+)
+
+func (g UsageGroup) Valid() bool {
+	switch g {
+	case UsageGroupNone, UsageGroupArticle, UsageGroupModel, UsageGroupDay:
+		return true
+	default:
+		return false
+	}
+}
+
+type UsageQuery struct {
+	ArticleID *string
+	Provider  *string
+	Model     *string
+	Since     *time.Time
+	Until     *time.Time
+	GroupBy   UsageGroup
+}
+
+// UsageSummary aggregates ledger entries. Key is the article ID,
+// "provider/model" or UTC date ("2006-01-02") depending on the grouping.
+type UsageSummary struct {
+	Key        string
+	Requests   int
+	Usage      TokenUsage
+	CostMicros int64
+}
+
+type UsageRecorder interface {
+	RecordUsage(ctx context.Context, rec UsageRecord) (UsageRecord, error)
+}
+
+type BudgetChecker interface {
+	CheckBudget(ctx context.Context) error
+}
+
+type UsageTracker interface {
+	UsageRecorder
+	BudgetChecker
+}
+
+type UsageSummarizer interface {
+	SummarizeUsage(ctx context.Context, query UsageQuery) ([]UsageSummary, error)
+}
+
+type UsageLedger interface {
+	UsageTracker
+	UsageSummarizer
+}
+
+// EstimateTokens gives a rough token count for providers that report no
+// usage: one token per CJK character and about four bytes per token otherwise.
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+func EstimateUsage(msgs []Message, output string) TokenUsage {
+	prompt := 0
+	for _, m := range msgs {
+		prompt += EstimateTokens(m.Content) + 4
+	}
+	completion := EstimateTokens(output)
+	return TokenUsage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
@@ -0,0 +1,210 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	ai "github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+	articles "github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+const (
+	defaultContinueParagraphs = 2
+	maxContinueParagraphs     = 10
+	defaultContinueWindow     = 4000
+	maxContinueWindow         = 20000
+)
+
+type ContinueWritingInput struct {
+	ArticleID  string
+	Paragraphs int
+	// WindowRunes limits how much of the end of the draft is sent as context.
+	WindowRunes int
+	PromptName  string
+	Model       string
+	Temperature float64
+	MaxTokens   int
+	OnDelta     func(delta string) error
+
+	// Append adds the continuation to the article as an autosave version.
+	Append bool
+}
+
+type ContinueWritingOutput struct {
+	Generation ai.Generation
+	Article    *articles.Article
+}
+
+type ContinueWritingUseCase struct {
+	Prompts     ai.PromptRepository
+	Provider    ai.Provider
+	Articles    articles.ArticleGetter
+	Updater     articles.ArticleUpdater
+	Generations ai.GenerationCreator
+	Usage       ai.UsageTracker
+	Clock       ai.Clock
+	IDs         ai.IDGenerator
+}
+
+func NewContinueWritingUseCase(prompts ai.PromptRepository, provider ai.Provider, getter articles.ArticleGetter) ContinueWritingUseCase {
+	return ContinueWritingUseCase{Prompts: prompts, Provider: provider, Articles: getter, Clock: systemClock{}, IDs: randomIDGenerator{}}
+}
+
+func (uc ContinueWritingUseCase) Execute(ctx context.Context, in ContinueWritingInput) (ContinueWritingOutput, error) {
+	if uc.Prompts == nil {
+		return ContinueWritingOutput{}, errors.New("continue writing: prompts is nil")
+	}
+	if uc.Provider == nil {
+		return ContinueWritingOutput{}, errors.New("continue writing: provider is nil")
+	}
+	if uc.Articles == nil {
+		return ContinueWritingOutput{}, errors.New("continue writing: articles repo is nil")
+	}
+	if in.Append && uc.Updater == nil {
+		return ContinueWritingOutput{}, errors.New("continue writing: article updater is nil")
+	}
+	if uc.Clock == nil {
+		uc.Clock = systemClock{}
+	}
+	if uc.IDs == nil {
+		uc.IDs = randomIDGenerator{}
+	}
+
+	articleID := strings.TrimSpace(in.ArticleID)
+	if articleID == "" {
+		return ContinueWritingOutput{}, errors.Join(ai.ErrInvalidArgument, errors.New("article id is required"))
+	}
+	paragraphs := in.Paragraphs
+	if paragraphs <= 0 {
+		paragraphs = defaultContinueParagraphs
+	}
+	if paragraphs > maxContinueParagraphs {
+		paragraphs = maxContinueParagraphs
+	}
+	window := in.WindowRunes
+	if window <= 0 {
+		window = defaultContinueWindow
+	}
+	if window > maxContinueWindow {
+		window = maxContinueWindow
+	}
+	promptName := strings.TrimSpace(in.PromptName)
+	if promptName == "" {
+		promptName = "continue_writing"
+	}
+
+	article, err := uc.Articles.GetArticle(ctx, articleID)
+	if err != nil {
+		return ContinueWritingOutput{}, err
+	}
+	draft := tailWindow(strings.TrimSpace(article.Content), window)
+	if draft == "" && strings.TrimSpace(article.Title) == "" {
+		return ContinueWritingOutput{}, errors.Join(ai.ErrInvalidArgument, errors.New("article has no title or content to continue"))
+	}
+
+	prompt, err := uc.Prompts.GetPrompt(ctx, promptName)
+	if err != nil {
+		return ContinueWritingOutput{}, err
+	}
+	msgs, err := prompt.Render(map[string]string{
+		"title":      article.Title,
+		"content":    draft,
+		"paragraphs": strconv.Itoa(paragraphs),
+	})
+	if err != nil {
+		return ContinueWritingOutput{}, errors.Join(ai.ErrInvalidArgument, err)
+	}
+
+	if err := checkBudget(ctx, uc.Usage); err != nil {
+		return ContinueWritingOutput{}, err
+	}
+
+	chatReq := ai.ChatRequest{Model: in.Model, Messages: msgs, Temperature: in.Temperature, MaxTokens: in.MaxTokens}
+	var chatResp ai.ChatResponse
+	if in.OnDelta == nil {
+		chatResp, err = uc.Provider.Chat(ctx, chatReq)
+	} else {
+		chatResp, err = uc.Provider.StreamChat(ctx, chatReq, in.OnDelta)
+	}
+	if err != nil {
+		return ContinueWritingOutput{}, err
+	}
+
+	genID, err := uc.IDs.NewID()
+	if err != nil {
+		return ContinueWritingOutput{}, err
+	}
+	now := uc.Clock.Now()
+	gen := ai.Generation{
+		ID:         genID,
+		Type:       ai.GenerationTypeContinuous,
+		PromptName: promptName,
+		Provider:   chatResp.Provider,
+		Model:      chatResp.Model,
+		InputText:  draft,
+		OutputText: chatResp.Content,
+		ArticleID:  article.ID,
+		Usage:      chatResp.Usage,
+		CreatedAt:  now,
+	}
+	if err := recordUsage(ctx, uc.Usage, gen, msgs); err != nil {
+		return ContinueWritingOutput{}, err
+	}
+
+	var updated *articles.Article
+	if in.Append {
+		continuation := strings.TrimSpace(chatResp.Content)
+		if continuation == "" {
+			return ContinueWritingOutput{}, errors.Join(ai.ErrProvider, errors.New("empty continuation"))
+		}
+		content := strings.TrimRightFunc(article.Content, unicode.IsSpace)
+		if content != "" {
+			content += "\n\n"
+		}
+		content += continuation
+		a, err := uc.Updater.UpdateArticle(ctx, article.ID, articles.UpdateArticleParams{
+			Content:    &content,
+			UpdatedAt:  now,
+			IsAutoSave: true,
+			// Don't clobber edits made while the model was generating.
+			ExpectedVersion: article.CurrentVersion,
+		})
+		if err != nil {
+			return ContinueWritingOutput{}, err
+		}
+		updated = &a
+	}
+
+	if uc.Generations != nil {
+		gen, err = uc.Generations.CreateGeneration(ctx, gen)
+		if err != nil {
+			return ContinueWritingOutput{}, err
+		}
+	}
+	return ContinueWritingOutput{Generation: gen, Article: updated}, nil
+}
+
+// tailWindow returns at most maxRunes runes from the end of s, starting at a
+// paragraph or line boundary when one falls inside the window.
+func tailWindow(s string, maxRunes int) string {
+	if utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	start := len(s)
+	for n := 0; n < maxRunes && start > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(s[:start])
+		start -= size
+	}
+	tail := s[start:]
+	if i := strings.Index(tail, "\n\n"); i >= 0 && i < len(tail)/2 {
+		return strings.TrimSpace(tail[i:])
+	}
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)/2 {
+		return strings.TrimSpace(tail[i:])
+	}
+	return strings.TrimSpace(tail)
+}
@@ -28,11 +28,13 @@ type GenerateContentOutput struct {
 }
 
 type GenerateContentUseCase struct {
-	Prompts  ai.PromptRepository
-	Provider ai.Provider
-	Articles articles.ArticleCreator
-	Clock    ai.Clock
-	IDs      ai.IDGenerator
+	Prompts     ai.PromptRepository
+	Provider    ai.Provider
+	Articles    articles.ArticleCreator
+	Generations ai.GenerationCreator
+	Usage       ai.UsageTracker
+	Clock       ai.Clock
+	IDs         ai.IDGenerator
 }
 
 func NewGenerateContentUseCase(prompts ai.PromptRepository, provider ai.Provider) GenerateContentUseCase {
@@ -71,6 +73,21 @@ func (uc GenerateContentUseCase) Execute(ctx context.Context, in GenerateContent
 		return GenerateContentOutput{}, errors.Join(ai.ErrInvalidArgument, err)
 	}
 
+	var tags []string
+	if in.SaveAsDraft {
+		if uc.Articles == nil {
+			return GenerateContentOutput{}, errors.New("generate content: articles repo is nil")
+		}
+		tags, err = articles.NormalizeTagNames(in.Tags)
+		if err != nil {
+			return GenerateContentOutput{}, errors.Join(articles.ErrInvalidArgument, err)
+		}
+	}
+
+	if err := checkBudget(ctx, uc.Usage); err != nil {
+		return GenerateContentOutput{}, err
+	}
+
 	chatReq := ai.ChatRequest{Model: in.Model, Messages: msgs, Temperature: in.Temperature, MaxTokens: in.MaxTokens}
 	var chatResp ai.ChatResponse
 	if in.OnDelta == nil {
@@ -96,33 +113,30 @@ func (uc GenerateContentUseCase) Execute(ctx context.Context, in GenerateContent
 		Model:      chatResp.Model,
 		InputText:  topic,
 		OutputText: chatResp.Content,
+		Usage:      chatResp.Usage,
 		CreatedAt:  now,
 	}
+	if in.SaveAsDraft {
+		if gen.ArticleID, err = uc.IDs.NewID(); err != nil {
+			return GenerateContentOutput{}, err
+		}
+	}
+	if err := recordUsage(ctx, uc.Usage, gen, msgs); err != nil {
+		return GenerateContentOutput{}, err
+	}
 
 	var createdArticle *articles.Article
 	if in.SaveAsDraft {
-		if uc.Articles == nil {
-			return GenerateContentOutput{}, errors.New("generate content: articles repo is nil")
-		}
 		title := strings.TrimSpace(in.DraftTitle)
 		if title == "" {
 			title = topic
 		}
-		normalizedTags, err := articles.NormalizeTagNames(in.Tags)
-		if err != nil {
-			return GenerateContentOutput{}, errors.Join(articles.ErrInvalidArgument, err)
-		}
-
-		articleID, err := uc.IDs.NewID()
-		if err != nil {
-			return GenerateContentOutput{}, err
-		}
 		article, err := uc.Articles.CreateArticle(ctx, articles.CreateArticleParams{
-			ID:        articleID,
+			ID:        gen.ArticleID,
 			Title:     title,
 			Content:   chatResp.Content,
 			Status:    articles.ArticleStatusDraft,
-			Tags:      normalizedTags,
+			Tags:      tags,
 			CreatedAt: now,
 			UpdatedAt: now,
 		})
@@ -130,7 +144,13 @@ func (uc GenerateContentUseCase) Execute(ctx context.Context, in GenerateContent
 			return GenerateContentOutput{}, err
 		}
 		createdArticle = &article
-		gen.ArticleID = article.ID
+	}
+
+	if uc.Generations != nil {
+		gen, err = uc.Generations.CreateGeneration(ctx, gen)
+		if err != nil {
+			return GenerateContentOutput{}, err
+		}
 	}
 
 	return GenerateContentOutput{Generation: gen, Article: createdArticle}, nil
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+	articles "github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
 )
 
 type RewriteContentInput struct {
@@ -15,17 +16,27 @@ type RewriteContentInput struct {
 	Temperature float64
 	MaxTokens   int
 	OnDelta     func(delta string) error
+
+	// ArticleID rewrites the article's content, or only Range of it, and
+	// saves the result as a new article version. Text is ignored.
+	ArticleID string
+	Range     *domain.TextRange
 }
 
 type RewriteContentOutput struct {
 	Generation domain.Generation
+	Article    *articles.Article
 }
 
 type RewriteContentUseCase struct {
-	Prompts  domain.PromptRepository
-	Provider domain.Provider
-	Clock    domain.Clock
-	IDs      domain.IDGenerator
+	Prompts     domain.PromptRepository
+	Provider    domain.Provider
+	Articles    articles.ArticleGetter
+	Updater     articles.ArticleUpdater
+	Generations domain.GenerationCreator
+	Usage       domain.UsageTracker
+	Clock       domain.Clock
+	IDs         domain.IDGenerator
 }
 
 func NewRewriteContentUseCase(prompts domain.PromptRepository, provider domain.Provider) RewriteContentUseCase {
@@ -46,7 +57,21 @@ func (uc RewriteContentUseCase) Execute(ctx context.Context, in RewriteContentIn
 		uc.IDs = randomIDGenerator{}
 	}
 
+	var article articles.Article
+	var before, selected, after string
 	text := strings.TrimSpace(in.Text)
+	articleID := strings.TrimSpace(in.ArticleID)
+	if articleID != "" {
+		if uc.Articles == nil || uc.Updater == nil {
+			return RewriteContentOutput{}, errors.New("rewrite content: articles repo is nil")
+		}
+		var err error
+		article, before, selected, after, err = articleText(ctx, uc.Articles, articleID, in.Range)
+		if err != nil {
+			return RewriteContentOutput{}, err
+		}
+		text = strings.TrimSpace(selected)
+	}
 	if text == "" {
 		return RewriteContentOutput{}, errors.Join(domain.ErrInvalidArgument, errors.New("text is required"))
 	}
@@ -64,6 +89,10 @@ func (uc RewriteContentUseCase) Execute(ctx context.Context, in RewriteContentIn
 		return RewriteContentOutput{}, errors.Join(domain.ErrInvalidArgument, err)
 	}
 
+	if err := checkBudget(ctx, uc.Usage); err != nil {
+		return RewriteContentOutput{}, err
+	}
+
 	chatReq := domain.ChatRequest{Model: in.Model, Messages: msgs, Temperature: in.Temperature, MaxTokens: in.MaxTokens}
 	var chatResp domain.ChatResponse
 	if in.OnDelta == nil {
@@ -79,6 +108,7 @@ func (uc RewriteContentUseCase) Execute(ctx context.Context, in RewriteContentIn
 	if err != nil {
 		return RewriteContentOutput{}, err
 	}
+	now := uc.Clock.Now()
 	gen := domain.Generation{
 		ID:         id,
 		Type:       domain.GenerationTypeRewrite,
@@ -87,7 +117,32 @@ func (uc RewriteContentUseCase) Execute(ctx context.Context, in RewriteContentIn
 		Model:      chatResp.Model,
 		InputText:  text,
 		OutputText: chatResp.Content,
-		CreatedAt:  uc.Clock.Now(),
+		ArticleID:  article.ID,
+		Usage:      chatResp.Usage,
+		CreatedAt:  now,
+	}
+	if err := recordUsage(ctx, uc.Usage, gen, msgs); err != nil {
+		return RewriteContentOutput{}, err
+	}
+
+	var updated *articles.Article
+	if articleID != "" {
+		if strings.TrimSpace(chatResp.Content) == "" {
+			return RewriteContentOutput{}, errors.Join(domain.ErrProvider, errors.New("empty rewrite"))
+		}
+		content := before + replaceKeepingSpace(selected, chatResp.Content) + after
+		a, err := uc.Updater.UpdateArticle(ctx, article.ID, articles.UpdateArticleParams{Content: &content, UpdatedAt: now, ExpectedVersion: article.CurrentVersion})
+		if err != nil {
+			return RewriteContentOutput{}, err
+		}
+		updated = &a
+	}
+
+	if uc.Generations != nil {
+		gen, err = uc.Generations.CreateGeneration(ctx, gen)
+		if err != nil {
+			return RewriteContentOutput{}, err
+		}
 	}
-	return RewriteContentOutput{Generation: gen}, nil
+	return RewriteContentOutput{Generation: gen, Article: updated}, nil
 }
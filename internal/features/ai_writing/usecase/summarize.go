@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+	articles "github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
 )
 
 type SummarizeInput struct {
@@ -15,17 +16,28 @@ type SummarizeInput struct {
 	Temperature float64
 	MaxTokens   int
 	OnDelta     func(delta string) error
+
+	// ArticleID summarizes the article's content instead of Text.
+	ArticleID string
+	// SaveAsDigest stores the summary as the article's digest in a new
+	// version. It defaults the prompt to "digest".
+	SaveAsDigest bool
 }
 
 type SummarizeOutput struct {
 	Generation domain.Generation
+	Article    *articles.Article
 }
 
 type SummarizeUseCase struct {
-	Prompts  domain.PromptRepository
-	Provider domain.Provider
-	Clock    domain.Clock
-	IDs      domain.IDGenerator
+	Prompts     domain.PromptRepository
+	Provider    domain.Provider
+	Articles    articles.ArticleGetter
+	Updater     articles.ArticleUpdater
+	Generations domain.GenerationCreator
+	Usage       domain.UsageTracker
+	Clock       domain.Clock
+	IDs         domain.IDGenerator
 }
 
 func NewSummarizeUseCase(prompts domain.PromptRepository, provider domain.Provider) SummarizeUseCase {
@@ -46,13 +58,31 @@ func (uc SummarizeUseCase) Execute(ctx context.Context, in SummarizeInput) (Summ
 		uc.IDs = randomIDGenerator{}
 	}
 
+	var article articles.Article
 	text := strings.TrimSpace(in.Text)
+	articleID := strings.TrimSpace(in.ArticleID)
+	if in.SaveAsDigest && articleID == "" {
+		return SummarizeOutput{}, errors.Join(domain.ErrInvalidArgument, errors.New("article id is required to save a digest"))
+	}
+	if articleID != "" {
+		if uc.Articles == nil || (in.SaveAsDigest && uc.Updater == nil) {
+			return SummarizeOutput{}, errors.New("summarize: articles repo is nil")
+		}
+		a, _, content, _, err := articleText(ctx, uc.Articles, articleID, nil)
+		if err != nil {
+			return SummarizeOutput{}, err
+		}
+		article, text = a, strings.TrimSpace(content)
+	}
 	if text == "" {
 		return SummarizeOutput{}, errors.Join(domain.ErrInvalidArgument, errors.New("text is required"))
 	}
 	promptName := strings.TrimSpace(in.PromptName)
 	if promptName == "" {
 		promptName = "summarize"
+		if in.SaveAsDigest {
+			promptName = "digest"
+		}
 	}
 
 	prompt, err := uc.Prompts.GetPrompt(ctx, promptName)
@@ -64,6 +94,10 @@ func (uc SummarizeUseCase) Execute(ctx context.Context, in SummarizeInput) (Summ
 		return SummarizeOutput{}, errors.Join(domain.ErrInvalidArgument, err)
 	}
 
+	if err := checkBudget(ctx, uc.Usage); err != nil {
+		return SummarizeOutput{}, err
+	}
+
 	chatReq := domain.ChatRequest{Model: in.Model, Messages: msgs, Temperature: in.Temperature, MaxTokens: in.MaxTokens}
 	var chatResp domain.ChatResponse
 	if in.OnDelta == nil {
@@ -79,6 +113,7 @@ func (uc SummarizeUseCase) Execute(ctx context.Context, in SummarizeInput) (Summ
 	if err != nil {
 		return SummarizeOutput{}, err
 	}
+	now := uc.Clock.Now()
 	gen := domain.Generation{
 		ID:         id,
 		Type:       domain.GenerationTypeSummarize,
@@ -87,7 +122,32 @@ func (uc SummarizeUseCase) Execute(ctx context.Context, in SummarizeInput) (Summ
 		Model:      chatResp.Model,
 		InputText:  text,
 		OutputText: chatResp.Content,
-		CreatedAt:  uc.Clock.Now(),
+		Usage:      chatResp.Usage,
+		ArticleID:  article.ID,
+		CreatedAt:  now,
+	}
+	if err := recordUsage(ctx, uc.Usage, gen, msgs); err != nil {
+		return SummarizeOutput{}, err
+	}
+
+	var updated *articles.Article
+	if in.SaveAsDigest {
+		digest := truncateRunes(strings.Join(strings.Fields(chatResp.Content), " "), articles.MaxDigestLength)
+		if digest == "" {
+			return SummarizeOutput{}, errors.Join(domain.ErrProvider, errors.New("empty summary"))
+		}
+		a, err := uc.Updater.UpdateArticle(ctx, article.ID, articles.UpdateArticleParams{Digest: &digest, UpdatedAt: now, ExpectedVersion: article.CurrentVersion})
+		if err != nil {
+			return SummarizeOutput{}, err
+		}
+		updated = &a
+	}
+
+	if uc.Generations != nil {
+		gen, err = uc.Generations.CreateGeneration(ctx, gen)
+		if err != nil {
+			return SummarizeOutput{}, err
+		}
 	}
-	return SummarizeOutput{Generation: gen}, nil
+	return SummarizeOutput{Generation: gen, Article: updated}, nil
 }
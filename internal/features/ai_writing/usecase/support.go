@@ -1,9 +1,17 @@
 ﻿package usecase
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+	"unicode"
+
+	ai "github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
+	articles "github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
 )
 
 type systemClock struct{}
@@ -19,3 +27,70 @@ func (randomIDGenerator) NewID() (string, error) {
 	}
 	return hex.EncodeToString(b[:]), nil
 }
+
+func checkBudget(ctx context.Context, usage ai.UsageTracker) error {
+	if usage == nil {
+		return nil
+	}
+	return usage.CheckBudget(ctx)
+}
+
+// recordUsage adds gen to the usage ledger, estimating token counts from the
+// prompt and output when the provider reported none. Callers record as soon
+// as the provider returns: the call is paid for even if saving its output
+// fails afterwards.
+func recordUsage(ctx context.Context, usage ai.UsageTracker, gen ai.Generation, msgs []ai.Message) error {
+	if usage == nil {
+		return nil
+	}
+	rec := ai.UsageRecord{
+		GenerationID: gen.ID,
+		ArticleID:    gen.ArticleID,
+		Provider:     gen.Provider,
+		Model:        gen.Model,
+		Usage:        gen.Usage,
+		CreatedAt:    gen.CreatedAt,
+	}
+	if rec.Usage == (ai.TokenUsage{}) {
+		rec.Usage = ai.EstimateUsage(msgs, gen.OutputText)
+		rec.Estimated = true
+	}
+	_, err := usage.RecordUsage(ctx, rec)
+	return err
+}
+
+// articleText loads an article and splits its content around rng (the whole
+// content when rng is nil).
+func articleText(ctx context.Context, getter articles.ArticleGetter, articleID string, rng *ai.TextRange) (article articles.Article, before, selected, after string, err error) {
+	article, err = getter.GetArticle(ctx, articleID)
+	if err != nil {
+		return articles.Article{}, "", "", "", err
+	}
+	if rng == nil {
+		return article, "", article.Content, "", nil
+	}
+	runes := []rune(article.Content)
+	if rng.Start < 0 || rng.End > len(runes) || rng.Start >= rng.End {
+		return articles.Article{}, "", "", "", errors.Join(ai.ErrInvalidArgument, fmt.Errorf("range [%d, %d) outside content of %d characters", rng.Start, rng.End, len(runes)))
+	}
+	return article, string(runes[:rng.Start]), string(runes[rng.Start:rng.End]), string(runes[rng.End:]), nil
+}
+
+// replaceKeepingSpace substitutes replacement for original while keeping the
+// whitespace that surrounded original.
+func replaceKeepingSpace(original, replacement string) string {
+	lead := original[:len(original)-len(strings.TrimLeftFunc(original, unicode.IsSpace))]
+	trail := original[len(strings.TrimRightFunc(original, unicode.IsSpace)):]
+	if strings.TrimSpace(original) == "" {
+		trail = ""
+	}
+	return lead + strings.TrimSpace(replacement) + trail
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	ai "github.com/Xiaoxinkeji/WX/internal/features/ai_writing/domain"
 	"github.com/Xiaoxinkeji/WX/internal/features/ai_writing/usecase"
@@ -47,17 +49,21 @@ type providerFake struct {
 
 	content string
 	deltas  []string
+	usage   ai.TokenUsage
+	lastReq ai.ChatRequest
 }
 
 func (p *providerFake) ProviderName() string { return "fake" }
 
 func (p *providerFake) Chat(ctx context.Context, req ai.ChatRequest) (ai.ChatResponse, error) {
 	p.calledChat++
-	return ai.ChatResponse{Provider: p.ProviderName(), Model: req.Model, Content: p.content}, nil
+	p.lastReq = req
+	return ai.ChatResponse{Provider: p.ProviderName(), Model: req.Model, Content: p.content, Usage: p.usage}, nil
 }
 
 func (p *providerFake) StreamChat(ctx context.Context, req ai.ChatRequest, onDelta func(delta string) error) (ai.ChatResponse, error) {
 	p.calledStream++
+	p.lastReq = req
 	for _, d := range p.deltas {
 		if err := onDelta(d); err != nil {
 			return ai.ChatResponse{}, err
@@ -66,6 +72,15 @@ func (p *providerFake) StreamChat(ctx context.Context, req ai.ChatRequest, onDel
 	return ai.ChatResponse{Provider: p.ProviderName(), Model: req.Model, Content: p.content, FinishReason: "stop"}, nil
 }
 
+type generationRecorderFake struct {
+	saved []ai.Generation
+}
+
+func (f *generationRecorderFake) CreateGeneration(ctx context.Context, g ai.Generation) (ai.Generation, error) {
+	f.saved = append(f.saved, g)
+	return g, nil
+}
+
 type articleCreatorFake struct {
 	called int
 	params articles.CreateArticleParams
@@ -158,3 +173,245 @@ func TestRewriteAndSummarize_RequireText(t *testing.T) {
 		t.Fatalf("expected ErrInvalidArgument, got %v", err)
 	}
 }
+
+func TestUseCases_RecordGenerationsWithUsage(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	usage := ai.TokenUsage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8}
+	provider := &providerFake{content: "out", usage: usage}
+	recorder := &generationRecorderFake{}
+	ids := &seqIDs{ids: []string{"gen-1", "art-1", "gen-2", "gen-3"}}
+	prompts := &promptRepoFake{prompt: ai.Prompt{Name: "p", Messages: []ai.PromptMessage{{Role: ai.RoleUser, Template: "{{topic}}"}}}}
+
+	genUC := usecase.GenerateContentUseCase{Prompts: prompts, Provider: provider, Articles: &articleCreatorFake{}, Generations: recorder, Clock: fixedClock{t: now}, IDs: ids}
+	if _, err := genUC.Execute(context.Background(), usecase.GenerateContentInput{Topic: "T", SaveAsDraft: true}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	prompts.prompt = ai.Prompt{Name: "p", Messages: []ai.PromptMessage{{Role: ai.RoleUser, Template: "{{text}}"}}}
+	rewriteUC := usecase.RewriteContentUseCase{Prompts: prompts, Provider: provider, Generations: recorder, Clock: fixedClock{t: now}, IDs: ids}
+	if _, err := rewriteUC.Execute(context.Background(), usecase.RewriteContentInput{Text: "x"}); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	sumUC := usecase.SummarizeUseCase{Prompts: prompts, Provider: provider, Generations: recorder, Clock: fixedClock{t: now}, IDs: ids}
+	if _, err := sumUC.Execute(context.Background(), usecase.SummarizeInput{Text: "x"}); err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+
+	if len(recorder.saved) != 3 {
+		t.Fatalf("expected 3 recorded generations, got %d", len(recorder.saved))
+	}
+	if recorder.saved[0].ArticleID != "art-1" {
+		t.Fatalf("expected generation linked to article, got %+v", recorder.saved[0])
+	}
+	wantTypes := []ai.GenerationType{ai.GenerationTypeGenerate, ai.GenerationTypeRewrite, ai.GenerationTypeSummarize}
+	for i, g := range recorder.saved {
+		if g.Type != wantTypes[i] {
+			t.Fatalf("generation %d: unexpected type %q", i, g.Type)
+		}
+		if g.Usage != usage {
+			t.Fatalf("generation %d: unexpected usage %+v", i, g.Usage)
+		}
+	}
+}
+
+type usageTrackerFake struct {
+	budgetErr error
+	records   []ai.UsageRecord
+}
+
+func (f *usageTrackerFake) CheckBudget(ctx context.Context) error { return f.budgetErr }
+
+func (f *usageTrackerFake) RecordUsage(ctx context.Context, rec ai.UsageRecord) (ai.UsageRecord, error) {
+	f.records = append(f.records, rec)
+	return rec, nil
+}
+
+func TestUseCases_TrackUsageAndEnforceBudget(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	provider := &providerFake{content: "short summary"}
+	tracker := &usageTrackerFake{}
+	prompts := &promptRepoFake{prompt: ai.Prompt{Name: "p", Messages: []ai.PromptMessage{{Role: ai.RoleUser, Template: "{{text}}"}}}}
+	uc := usecase.SummarizeUseCase{Prompts: prompts, Provider: provider, Usage: tracker, Clock: fixedClock{t: now}, IDs: &seqIDs{ids: []string{"gen-1"}}}
+
+	if _, err := uc.Execute(context.Background(), usecase.SummarizeInput{Text: "some longer text to summarize"}); err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	if len(tracker.records) != 1 {
+		t.Fatalf("expected 1 usage record, got %d", len(tracker.records))
+	}
+	rec := tracker.records[0]
+	if rec.GenerationID != "gen-1" || !rec.Estimated || rec.Usage.PromptTokens == 0 || rec.Usage.CompletionTokens == 0 {
+		t.Fatalf("expected estimated usage for provider without usage, got %+v", rec)
+	}
+
+	tracker.budgetErr = &ai.BudgetExceededError{Period: "daily", LimitMicros: 1, SpentMicros: 1}
+	_, err := uc.Execute(context.Background(), usecase.SummarizeInput{Text: "more"})
+	if !errors.Is(err, ai.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if provider.calledChat != 1 {
+		t.Fatalf("provider should not be called over budget, calls=%d", provider.calledChat)
+	}
+}
+
+type failingArticleStoreFake struct {
+	articleStoreFake
+	err error
+}
+
+func (f *failingArticleStoreFake) CreateArticle(ctx context.Context, params articles.CreateArticleParams) (articles.Article, error) {
+	return articles.Article{}, f.err
+}
+
+func (f *failingArticleStoreFake) UpdateArticle(ctx context.Context, articleID string, params articles.UpdateArticleParams) (articles.Article, error) {
+	return articles.Article{}, f.err
+}
+
+func TestUseCases_RecordUsageWhenSavingFails(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	usage := ai.TokenUsage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8}
+	provider := &providerFake{content: "out", usage: usage}
+	tracker := &usageTrackerFake{}
+	recorder := &generationRecorderFake{}
+	store := &failingArticleStoreFake{articleStoreFake: articleStoreFake{article: articles.Article{ID: "a1", Content: "draft"}}, err: articles.ErrConflict}
+	prompts := &promptRepoFake{prompt: ai.Prompt{Name: "p", Messages: []ai.PromptMessage{{Role: ai.RoleUser, Template: "{{topic}}"}}}}
+
+	genUC := usecase.GenerateContentUseCase{Prompts: prompts, Provider: provider, Articles: store, Generations: recorder, Usage: tracker, Clock: fixedClock{t: now}, IDs: &seqIDs{ids: []string{"gen-1", "art-1"}}}
+	if _, err := genUC.Execute(context.Background(), usecase.GenerateContentInput{Topic: "T", SaveAsDraft: true}); !errors.Is(err, articles.ErrConflict) {
+		t.Fatalf("expected the draft write to fail, got %v", err)
+	}
+
+	prompts.prompt = ai.Prompt{Name: "p", Messages: []ai.PromptMessage{{Role: ai.RoleUser, Template: "{{text}}"}}}
+	rewriteUC := usecase.RewriteContentUseCase{Prompts: prompts, Provider: provider, Articles: store, Updater: store, Generations: recorder, Usage: tracker, Clock: fixedClock{t: now}, IDs: &seqIDs{ids: []string{"gen-2"}}}
+	if _, err := rewriteUC.Execute(context.Background(), usecase.RewriteContentInput{ArticleID: "a1"}); !errors.Is(err, articles.ErrConflict) {
+		t.Fatalf("expected the rewrite write to fail, got %v", err)
+	}
+
+	if len(recorder.saved) != 0 {
+		t.Fatalf("failed writes must not record generations, got %+v", recorder.saved)
+	}
+	if len(tracker.records) != 2 {
+		t.Fatalf("expected the spend of both calls in the ledger, got %+v", tracker.records)
+	}
+	for i, want := range []struct{ gen, article string }{{"gen-1", "art-1"}, {"gen-2", "a1"}} {
+		rec := tracker.records[i]
+		if rec.GenerationID != want.gen || rec.ArticleID != want.article || rec.Usage != usage || rec.Estimated {
+			t.Fatalf("record %d: unexpected usage record %+v", i, rec)
+		}
+	}
+}
+
+type articleStoreFake struct {
+	article articles.Article
+	updates []articles.UpdateArticleParams
+}
+
+func (f *articleStoreFake) GetArticle(ctx context.Context, articleID string) (articles.Article, error) {
+	if articleID != f.article.ID {
+		return articles.Article{}, articles.ErrNotFound
+	}
+	return f.article, nil
+}
+
+func (f *articleStoreFake) UpdateArticle(ctx context.Context, articleID string, params articles.UpdateArticleParams) (articles.Article, error) {
+	f.updates = append(f.updates, params)
+	if params.Content != nil {
+		f.article.Content = *params.Content
+	}
+	if params.Digest != nil {
+		f.article.Digest = *params.Digest
+	}
+	f.article.CurrentVersion++
+	return f.article, nil
+}
+
+func TestContinueWritingUseCase_StreamsAndAppends(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	long := strings.Repeat("前文", 3000)
+	store := &articleStoreFake{article: articles.Article{ID: "a1", Title: "标题", Content: long + "\n\n最后一段。\n", CurrentVersion: 3}}
+	provider := &providerFake{content: "新的一段。", deltas: []string{"新的", "一段。"}}
+	recorder := &generationRecorderFake{}
+	prompts := &promptRepoFake{prompt: ai.Prompt{Name: "continue_writing", Messages: []ai.PromptMessage{{Role: ai.RoleUser, Template: "{{title}}|{{paragraphs}}|{{content}}"}}}}
+	uc := usecase.ContinueWritingUseCase{Prompts: prompts, Provider: provider, Articles: store, Updater: store, Generations: recorder, Clock: fixedClock{t: now}, IDs: &seqIDs{ids: []string{"gen-1"}}}
+
+	var streamed string
+	out, err := uc.Execute(context.Background(), usecase.ContinueWritingInput{
+		ArticleID:   "a1",
+		Paragraphs:  3,
+		WindowRunes: 100,
+		Append:      true,
+		OnDelta:     func(d string) error { streamed += d; return nil },
+	})
+	if err != nil {
+		t.Fatalf("continue: %v", err)
+	}
+	if prompts.name != "continue_writing" || provider.calledStream != 1 || streamed != "新的一段。" {
+		t.Fatalf("unexpected call: prompt=%q streams=%d streamed=%q", prompts.name, provider.calledStream, streamed)
+	}
+	sent := provider.lastReq.Messages[0].Content
+	if !strings.HasPrefix(sent, "标题|3|") || !strings.HasSuffix(sent, "最后一段。") || utf8.RuneCountInString(sent) > 110 {
+		t.Fatalf("expected trimmed window of the draft end, got %q", sent)
+	}
+	if len(store.updates) != 1 || !store.updates[0].IsAutoSave || !store.updates[0].UpdatedAt.Equal(now) {
+		t.Fatalf("expected one autosave update, got %+v", store.updates)
+	}
+	if want := long + "\n\n最后一段。\n\n新的一段。"; out.Article == nil || out.Article.Content != want {
+		t.Fatalf("unexpected appended content: %+v", out.Article)
+	}
+	g := recorder.saved[0]
+	if g.Type != ai.GenerationTypeContinuous || g.ArticleID != "a1" || g.OutputText != "新的一段。" {
+		t.Fatalf("unexpected generation: %+v", g)
+	}
+
+	if _, err := uc.Execute(context.Background(), usecase.ContinueWritingInput{ArticleID: "missing"}); !errors.Is(err, articles.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRewriteContentUseCase_RewritesArticleRange(t *testing.T) {
+	store := &articleStoreFake{article: articles.Article{ID: "a1", Content: "第一句。 旧的句子。 第三句。", CurrentVersion: 1}}
+	provider := &providerFake{content: "  新的句子。\n"}
+	prompts := &promptRepoFake{prompt: ai.Prompt{Name: "p", Messages: []ai.PromptMessage{{Role: ai.RoleUser, Template: "{{text}}"}}}}
+	uc := usecase.RewriteContentUseCase{Prompts: prompts, Provider: provider, Articles: store, Updater: store, IDs: &seqIDs{ids: []string{"gen-1"}}}
+
+	out, err := uc.Execute(context.Background(), usecase.RewriteContentInput{ArticleID: "a1", Range: &ai.TextRange{Start: 4, End: 11}})
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if got := provider.lastReq.Messages[0].Content; got != "旧的句子。" {
+		t.Fatalf("expected only the range to be sent, got %q", got)
+	}
+	if out.Article == nil || out.Article.Content != "第一句。 新的句子。 第三句。" || out.Article.CurrentVersion != 2 {
+		t.Fatalf("unexpected article: %+v", out.Article)
+	}
+	if len(store.updates) != 1 || store.updates[0].IsAutoSave || out.Generation.ArticleID != "a1" {
+		t.Fatalf("expected one manual version linked to the generation, got %+v", store.updates)
+	}
+
+	_, err = uc.Execute(context.Background(), usecase.RewriteContentInput{ArticleID: "a1", Range: &ai.TextRange{Start: 5, End: 100}})
+	if !errors.Is(err, ai.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for out-of-range, got %v", err)
+	}
+}
+
+func TestSummarizeUseCase_SavesDigest(t *testing.T) {
+	store := &articleStoreFake{article: articles.Article{ID: "a1", Content: "long article text"}}
+	provider := &providerFake{content: strings.Repeat("摘", 130)}
+	prompts := &promptRepoFake{prompt: ai.Prompt{Name: "digest", Messages: []ai.PromptMessage{{Role: ai.RoleUser, Template: "{{text}}"}}}}
+	uc := usecase.SummarizeUseCase{Prompts: prompts, Provider: provider, Articles: store, Updater: store, IDs: &seqIDs{ids: []string{"gen-1"}}}
+
+	out, err := uc.Execute(context.Background(), usecase.SummarizeInput{ArticleID: "a1", SaveAsDigest: true})
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	if prompts.name != "digest" || provider.lastReq.Messages[0].Content != "long article text" {
+		t.Fatalf("unexpected prompt %q / input %q", prompts.name, provider.lastReq.Messages[0].Content)
+	}
+	if out.Article == nil || utf8.RuneCountInString(out.Article.Digest) != articles.MaxDigestLength || out.Article.Content != "long article text" {
+		t.Fatalf("unexpected article: %+v", out.Article)
+	}
+
+	if _, err := uc.Execute(context.Background(), usecase.SummarizeInput{Text: "x", SaveAsDigest: true}); !errors.Is(err, ai.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument without article id, got %v", err)
+	}
+}
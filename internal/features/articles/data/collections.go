@@ -0,0 +1,374 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+const collectionColumns = `c.id, c.title, c.description, c.created_at_ms, c.updated_at_ms`
+
+func (r *SQLiteRepository) CreateCollection(ctx context.Context, params domain.CreateCollectionParams) (domain.Collection, error) {
+	if params.ID == "" {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	if err := domain.ValidateCollectionFields(params.Title, params.Description); err != nil {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	createdAtMs := params.CreatedAt.UTC().UnixMilli()
+	if params.CreatedAt.IsZero() {
+		createdAtMs = time.Now().UTC().UnixMilli()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO collections(id, title, description, created_at_ms, updated_at_ms) VALUES(?, ?, ?, ?, ?)`,
+		params.ID, params.Title, params.Description, createdAtMs, createdAtMs); err != nil {
+		if isUniqueConstraintErr(err) {
+			return domain.Collection{}, errors.Join(domain.ErrConflict, err)
+		}
+		return domain.Collection{}, err
+	}
+	if err := appendMembersTx(ctx, tx, params.ID, params.ArticleIDs, createdAtMs); err != nil {
+		return domain.Collection{}, err
+	}
+	return r.commitCollection(ctx, tx, params.ID)
+}
+
+func (r *SQLiteRepository) GetCollection(ctx context.Context, collectionID string) (domain.Collection, error) {
+	if collectionID == "" {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	out, err := r.queryCollections(ctx, r.db, `SELECT `+collectionColumns+` FROM collections c WHERE c.id = ?`, collectionID)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	if len(out) == 0 {
+		return domain.Collection{}, domain.ErrNotFound
+	}
+	return out[0], nil
+}
+
+func (r *SQLiteRepository) UpdateCollection(ctx context.Context, collectionID string, params domain.UpdateCollectionParams) (domain.Collection, error) {
+	if collectionID == "" {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	defer tx.Rollback()
+
+	var title, description string
+	if err := tx.QueryRowContext(ctx, `SELECT title, description FROM collections WHERE id = ?`, collectionID).Scan(&title, &description); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Collection{}, domain.ErrNotFound
+		}
+		return domain.Collection{}, err
+	}
+	if params.Title != nil {
+		title = *params.Title
+	}
+	if params.Description != nil {
+		description = *params.Description
+	}
+	if err := domain.ValidateCollectionFields(title, description); err != nil {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE collections SET title = ?, description = ?, updated_at_ms = ? WHERE id = ?`,
+		title, description, updatedMs(params.UpdatedAt), collectionID); err != nil {
+		return domain.Collection{}, err
+	}
+	return r.commitCollection(ctx, tx, collectionID)
+}
+
+func (r *SQLiteRepository) DeleteCollection(ctx context.Context, collectionID string) error {
+	if collectionID == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM collection_articles WHERE collection_id = ?`, collectionID); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM collections WHERE id = ?`, collectionID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrNotFound
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteRepository) ListCollections(ctx context.Context, query domain.ListCollectionsQuery) ([]domain.Collection, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := query.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	return r.queryCollections(ctx, r.db, `SELECT `+collectionColumns+` FROM collections c ORDER BY c.updated_at_ms DESC, c.id DESC LIMIT ? OFFSET ?`, limit, offset)
+}
+
+func (r *SQLiteRepository) AddToCollection(ctx context.Context, params domain.CollectionMembersParams) (domain.Collection, error) {
+	return r.editMembers(ctx, params, func(tx *sql.Tx, atMs int64) error {
+		return appendMembersTx(ctx, tx, params.CollectionID, params.ArticleIDs, atMs)
+	})
+}
+
+func (r *SQLiteRepository) RemoveFromCollection(ctx context.Context, params domain.CollectionMembersParams) (domain.Collection, error) {
+	return r.editMembers(ctx, params, func(tx *sql.Tx, atMs int64) error {
+		if len(params.ArticleIDs) == 0 {
+			return nil
+		}
+		args := []any{params.CollectionID}
+		for _, id := range params.ArticleIDs {
+			args = append(args, id)
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM collection_articles WHERE collection_id = ? AND article_id IN (`+placeholders(len(params.ArticleIDs))+`)`, args...)
+		return err
+	})
+}
+
+func (r *SQLiteRepository) ReorderCollection(ctx context.Context, params domain.CollectionMembersParams) (domain.Collection, error) {
+	return r.editMembers(ctx, params, func(tx *sql.Tx, atMs int64) error {
+		live, trashed, err := collectionMembersTx(ctx, tx, params.CollectionID)
+		if err != nil {
+			return err
+		}
+		members := make(map[string]bool, len(live))
+		for _, id := range live {
+			members[id] = true
+		}
+		if len(params.ArticleIDs) != len(live) {
+			return errors.Join(domain.ErrInvalidArgument, fmt.Errorf("new order has %d articles, the collection has %d", len(params.ArticleIDs), len(live)))
+		}
+		for _, id := range params.ArticleIDs {
+			if !members[id] {
+				return errors.Join(domain.ErrInvalidArgument, fmt.Errorf("article %s is not in the collection or listed twice", id))
+			}
+			delete(members, id)
+		}
+		for pos, id := range append(append([]string(nil), params.ArticleIDs...), trashed...) {
+			if _, err := tx.ExecContext(ctx, `UPDATE collection_articles SET position = ? WHERE collection_id = ? AND article_id = ?`, pos, params.CollectionID, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteRepository) CollectionsOfArticle(ctx context.Context, articleID string) ([]domain.Collection, error) {
+	if articleID == "" {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	var exists int
+	if err := r.db.QueryRowContext(ctx, `SELECT 1 FROM articles WHERE id = ?`, articleID).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return r.queryCollections(ctx, r.db, `
+SELECT `+collectionColumns+`
+FROM collections c
+JOIN collection_articles ca ON ca.collection_id = c.id
+WHERE ca.article_id = ?
+ORDER BY c.title, c.id
+`, articleID)
+}
+
+// editMembers runs edit on an existing collection and bumps its updated time.
+func (r *SQLiteRepository) editMembers(ctx context.Context, params domain.CollectionMembersParams, edit func(tx *sql.Tx, atMs int64) error) (domain.Collection, error) {
+	if params.CollectionID == "" {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	atMs := updatedMs(params.At)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE collections SET updated_at_ms = ? WHERE id = ?`, atMs, params.CollectionID)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return domain.Collection{}, err
+	} else if n == 0 {
+		return domain.Collection{}, domain.ErrNotFound
+	}
+	if err := edit(tx, atMs); err != nil {
+		return domain.Collection{}, err
+	}
+	return r.commitCollection(ctx, tx, params.CollectionID)
+}
+
+func (r *SQLiteRepository) commitCollection(ctx context.Context, tx *sql.Tx, collectionID string) (domain.Collection, error) {
+	out, err := r.queryCollections(ctx, tx, `SELECT `+collectionColumns+` FROM collections c WHERE c.id = ?`, collectionID)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	if len(out) == 0 {
+		return domain.Collection{}, domain.ErrNotFound
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Collection{}, err
+	}
+	return out[0], nil
+}
+
+// appendMembersTx adds live articles after the current members, skipping
+// ones already in the collection.
+func appendMembersTx(ctx context.Context, tx *sql.Tx, collectionID string, articleIDs []string, atMs int64) error {
+	var next int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(position) + 1, 0) FROM collection_articles WHERE collection_id = ?`, collectionID).Scan(&next); err != nil {
+		return err
+	}
+	for _, id := range articleIDs {
+		var live int
+		if err := tx.QueryRowContext(ctx, `SELECT 1 FROM articles WHERE id = ? AND deleted_at_ms = 0`, id).Scan(&live); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.Join(domain.ErrNotFound, fmt.Errorf("article %s", id))
+			}
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO collection_articles(collection_id, article_id, position, added_at_ms) VALUES(?, ?, ?, ?)`, collectionID, id, next, atMs)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n > 0 {
+			next++
+		}
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM collection_articles WHERE collection_id = ?`, collectionID).Scan(&count); err != nil {
+		return err
+	}
+	if count > domain.MaxCollectionArticles {
+		return errors.Join(domain.ErrInvalidArgument, fmt.Errorf("too many articles in collection: max %d, got %d", domain.MaxCollectionArticles, count))
+	}
+	return nil
+}
+
+// collectionMembersTx returns the live and trashed members, each in order.
+func collectionMembersTx(ctx context.Context, tx *sql.Tx, collectionID string) (live, trashed []string, err error) {
+	rows, err := tx.QueryContext(ctx, `
+SELECT ca.article_id, a.deleted_at_ms != 0
+FROM collection_articles ca
+JOIN articles a ON a.id = ca.article_id
+WHERE ca.collection_id = ?
+ORDER BY ca.position
+`, collectionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id        string
+			isTrashed bool
+		)
+		if err := rows.Scan(&id, &isTrashed); err != nil {
+			return nil, nil, err
+		}
+		if isTrashed {
+			trashed = append(trashed, id)
+		} else {
+			live = append(live, id)
+		}
+	}
+	return live, trashed, rows.Err()
+}
+
+func (r *SQLiteRepository) queryCollections(ctx context.Context, q queryer, stmt string, args ...any) ([]domain.Collection, error) {
+	rows, err := q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.Collection, 0)
+	for rows.Next() {
+		var (
+			c                    domain.Collection
+			createdMs, updatedMs int64
+		)
+		if err := rows.Scan(&c.ID, &c.Title, &c.Description, &createdMs, &updatedMs); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.CreatedAt = time.UnixMilli(createdMs).UTC()
+		c.UpdatedAt = time.UnixMilli(updatedMs).UTC()
+		c.ArticleIDs = []string{}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	index := make(map[string]int, len(out))
+	args = make([]any, 0, len(out))
+	for i, c := range out {
+		index[c.ID] = i
+		args = append(args, c.ID)
+	}
+	members, err := q.QueryContext(ctx, `
+SELECT ca.collection_id, ca.article_id
+FROM collection_articles ca
+JOIN articles a ON a.id = ca.article_id
+WHERE a.deleted_at_ms = 0 AND ca.collection_id IN (`+placeholders(len(out))+`)
+ORDER BY ca.collection_id, ca.position
+`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer members.Close()
+	for members.Next() {
+		var collectionID, articleID string
+		if err := members.Scan(&collectionID, &articleID); err != nil {
+			return nil, err
+		}
+		c := &out[index[collectionID]]
+		c.ArticleIDs = append(c.ArticleIDs, articleID)
+	}
+	if err := members.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func updatedMs(t time.Time) int64 {
+	if t.IsZero() {
+		return time.Now().UTC().UnixMilli()
+	}
+	return t.UTC().UnixMilli()
+}
@@ -0,0 +1,214 @@
+﻿package data
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+// Highlighting works on the original text rather than article_fts, whose
+// columns hold segmented tokens. Text and terms are both cut into units (a
+// word, or a single CJK character) and a term matches where its units appear
+// consecutively, ignoring separators, which mirrors how the index matches.
+
+type unit struct {
+	text       string
+	start, end int // rune offsets
+}
+
+func splitUnits(s string) []unit {
+	var (
+		out     []unit
+		word    strings.Builder
+		wordPos int
+		pos     int
+	)
+	flush := func() {
+		if word.Len() > 0 {
+			out = append(out, unit{text: strings.ToLower(word.String()), start: wordPos, end: pos})
+			word.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			flush()
+			out = append(out, unit{text: string(r), start: pos, end: pos + 1})
+		case isTokenRune(r):
+			if word.Len() == 0 {
+				wordPos = pos
+			}
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+		pos++
+	}
+	flush()
+	return out
+}
+
+type highlightTerm struct {
+	units  []unit
+	prefix bool
+}
+
+// highlightTerms collects the terms of n that can match in field, skipping
+// excluded subtrees.
+func highlightTerms(n *domain.SearchNode, field string) []highlightTerm {
+	if n == nil {
+		return nil
+	}
+	switch n.Kind {
+	case domain.SearchTermNode:
+		if n.Field != "" && n.Field != field {
+			return nil
+		}
+		units := splitUnits(n.Text)
+		if len(units) == 0 {
+			return nil
+		}
+		return []highlightTerm{{units: units, prefix: n.Prefix}}
+	case domain.SearchAndNode, domain.SearchOrNode:
+		var out []highlightTerm
+		for i := range n.Children {
+			out = append(out, highlightTerms(&n.Children[i], field)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// findMatches returns the merged, sorted rune ranges of text matched by terms.
+func findMatches(text string, terms []highlightTerm) []domain.MatchRange {
+	if len(terms) == 0 || text == "" {
+		return nil
+	}
+	units := splitUnits(text)
+	var out []domain.MatchRange
+	for i := range units {
+		for _, term := range terms {
+			if end, ok := matchAt(units, i, term); ok {
+				out = append(out, domain.MatchRange{Start: units[i].start, End: end})
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Start < out[b].Start })
+	merged := out[:1]
+	for _, m := range out[1:] {
+		last := &merged[len(merged)-1]
+		if m.Start <= last.End {
+			if m.End > last.End {
+				last.End = m.End
+			}
+			continue
+		}
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+func matchAt(units []unit, i int, term highlightTerm) (int, bool) {
+	if i+len(term.units) > len(units) {
+		return 0, false
+	}
+	for k, want := range term.units {
+		got := units[i+k]
+		if k == len(term.units)-1 && term.prefix {
+			if !strings.HasPrefix(got.text, want.text) {
+				return 0, false
+			}
+			continue
+		}
+		if got.text != want.text {
+			return 0, false
+		}
+	}
+	return units[i+len(term.units)-1].end, true
+}
+
+// highlight wraps each range of text in markOpen and markClose, and
+// HTML-escapes the text outside and inside the marks.
+func highlight(text string, ranges []domain.MatchRange, markOpen, markClose string) string {
+	if len(ranges) == 0 {
+		return html.EscapeString(text)
+	}
+	runes := []rune(text)
+	var b strings.Builder
+	b.Grow(len(text) + len(ranges)*(len(markOpen)+len(markClose)))
+	pos := 0
+	for _, m := range ranges {
+		b.WriteString(html.EscapeString(string(runes[pos:m.Start])))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(string(runes[m.Start:m.End])))
+		b.WriteString(markClose)
+		pos = m.End
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:])))
+	return b.String()
+}
+
+const snippetEllipsis = "…"
+
+// snippet cuts a window of at most size runes from text around the first
+// match, and returns the matches that fall inside it relative to the window.
+// text may be a prefix of a longer content of total runes; the window then
+// stays inside the prefix and the cut end is still marked.
+func snippet(text string, total int, ranges []domain.MatchRange, size int) (string, []domain.MatchRange) {
+	runes := []rune(text)
+	n := len(runes)
+	if total < n {
+		total = n
+	}
+	if total <= size {
+		return text, ranges
+	}
+	if n < total && len(ranges) > 0 && ranges[len(ranges)-1].End == n {
+		// The prefix may have cut this word short.
+		ranges = ranges[:len(ranges)-1]
+	}
+	start := 0
+	if len(ranges) > 0 {
+		// Keep a little context before the first hit.
+		start = ranges[0].Start - size/4
+		if start+size > n {
+			start = n - size
+		}
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := min(start+size, n)
+	for start < end && unicode.IsSpace(runes[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(runes[end-1]) {
+		end--
+	}
+
+	var b strings.Builder
+	shift := -start
+	if start > 0 {
+		b.WriteString(snippetEllipsis)
+		shift += utf8.RuneCountInString(snippetEllipsis)
+	}
+	b.WriteString(string(runes[start:end]))
+	if end < total {
+		b.WriteString(snippetEllipsis)
+	}
+
+	var inside []domain.MatchRange
+	for _, m := range ranges {
+		if m.Start >= start && m.End <= end {
+			inside = append(inside, domain.MatchRange{Start: m.Start + shift, End: m.End + shift})
+		}
+	}
+	return b.String(), inside
+}
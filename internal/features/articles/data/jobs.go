@@ -0,0 +1,337 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+const jobColumns = `id, article_id, action, state, run_at_ms, attempts, last_error, from_status, created_at_ms, updated_at_ms`
+
+func scanJob(row interface{ Scan(dest ...any) error }) (domain.ScheduledJob, error) {
+	var (
+		j                           domain.ScheduledJob
+		action, state, from         string
+		runAtMs, createdMs, updated int64
+	)
+	if err := row.Scan(&j.ID, &j.ArticleID, &action, &state, &runAtMs, &j.Attempts, &j.LastError, &from, &createdMs, &updated); err != nil {
+		return domain.ScheduledJob{}, err
+	}
+	j.Action, j.State, j.FromStatus = domain.JobAction(action), domain.JobState(state), domain.ArticleStatus(from)
+	j.RunAt = time.UnixMilli(runAtMs).UTC()
+	j.CreatedAt = time.UnixMilli(createdMs).UTC()
+	j.UpdatedAt = time.UnixMilli(updated).UTC()
+	return j, nil
+}
+
+func (r *SQLiteRepository) SchedulePublish(ctx context.Context, params domain.SchedulePublishParams) (domain.ScheduledJob, error) {
+	if params.ArticleID == "" {
+		return domain.ScheduledJob{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	if params.At.IsZero() {
+		return domain.ScheduledJob{}, errors.Join(domain.ErrInvalidArgument, errors.New("publish time is required"))
+	}
+	now := params.Now
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+	nowMs, atMs := now.UnixMilli(), params.At.UnixMilli()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.ScheduledJob{}, err
+	}
+	defer tx.Rollback()
+
+	var status, title, content string
+	if err := tx.QueryRowContext(ctx, `SELECT status, title, content FROM articles WHERE id = ? AND deleted_at_ms = 0`, params.ArticleID).Scan(&status, &title, &content); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ScheduledJob{}, domain.ErrNotFound
+		}
+		return domain.ScheduledJob{}, err
+	}
+
+	job, err := scanJob(tx.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM article_jobs WHERE article_id = ? AND action = ? AND state = ?`, params.ArticleID, string(domain.JobActionPublish), string(domain.JobStatePending)))
+	switch {
+	case err == nil:
+		if _, err := tx.ExecContext(ctx, `UPDATE article_jobs SET run_at_ms = ?, attempts = 0, last_error = '', updated_at_ms = ? WHERE id = ?`, atMs, nowMs, job.ID); err != nil {
+			return domain.ScheduledJob{}, err
+		}
+		job.RunAt, job.Attempts, job.LastError, job.UpdatedAt = time.UnixMilli(atMs).UTC(), 0, "", time.UnixMilli(nowMs).UTC()
+	case errors.Is(err, sql.ErrNoRows):
+		from := domain.ArticleStatus(status)
+		if from == domain.ArticleStatusScheduled {
+			// Scheduled by a plain update, so there is nothing better to go back to.
+			from = domain.ArticleStatusDraft
+		} else {
+			if err := domain.ValidateArticleTransition(params.Workflow, from, domain.ArticleStatusScheduled, title, content); err != nil {
+				return domain.ScheduledJob{}, errors.Join(domain.ErrInvalidArgument, err)
+			}
+			note := "publish scheduled for " + params.At.UTC().Format(time.RFC3339)
+			if err := r.setStatusTx(ctx, tx, params.ArticleID, from, domain.ArticleStatusScheduled, note, nowMs); err != nil {
+				return domain.ScheduledJob{}, err
+			}
+		}
+		id, err := newRandomID()
+		if err != nil {
+			return domain.ScheduledJob{}, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO article_jobs(`+jobColumns+`)
+VALUES(?, ?, ?, ?, ?, 0, '', ?, ?, ?)
+`, id, params.ArticleID, string(domain.JobActionPublish), string(domain.JobStatePending), atMs, string(from), nowMs, nowMs); err != nil {
+			return domain.ScheduledJob{}, err
+		}
+		job = domain.ScheduledJob{
+			ID:         id,
+			ArticleID:  params.ArticleID,
+			Action:     domain.JobActionPublish,
+			State:      domain.JobStatePending,
+			RunAt:      time.UnixMilli(atMs).UTC(),
+			FromStatus: from,
+			CreatedAt:  time.UnixMilli(nowMs).UTC(),
+			UpdatedAt:  time.UnixMilli(nowMs).UTC(),
+		}
+	default:
+		return domain.ScheduledJob{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.ScheduledJob{}, err
+	}
+	return job, nil
+}
+
+func (r *SQLiteRepository) CancelScheduledPublish(ctx context.Context, articleID string, now time.Time) error {
+	if articleID == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.cancelPublishTx(ctx, tx, articleID, "scheduled publish cancelled", now.UnixMilli()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// cancelPublishTx cancels the article's pending publish job and moves a
+// scheduled article back to the status it was scheduled from. It returns
+// ErrNotFound when nothing is scheduled.
+func (r *SQLiteRepository) cancelPublishTx(ctx context.Context, tx *sql.Tx, articleID, note string, atMs int64) error {
+	job, err := scanJob(tx.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM article_jobs WHERE article_id = ? AND action = ? AND state = ?`, articleID, string(domain.JobActionPublish), string(domain.JobStatePending)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrNotFound
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE article_jobs SET state = ?, updated_at_ms = ? WHERE id = ?`, string(domain.JobStateCancelled), atMs, job.ID); err != nil {
+		return err
+	}
+	var status string
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM articles WHERE id = ?`, articleID).Scan(&status); err != nil {
+		return err
+	}
+	if domain.ArticleStatus(status) == domain.ArticleStatusScheduled {
+		return r.setStatusTx(ctx, tx, articleID, domain.ArticleStatusScheduled, job.FromStatus, note, atMs)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ListJobs(ctx context.Context, query domain.ListJobsQuery) ([]domain.ScheduledJob, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := query.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	if query.ArticleID != "" {
+		where = append(where, `article_id = ?`)
+		args = append(args, query.ArticleID)
+	}
+	if len(query.States) > 0 {
+		for _, s := range query.States {
+			if !s.Valid() {
+				return nil, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("invalid job state %q", s))
+			}
+			args = append(args, string(s))
+		}
+		where = append(where, `state IN (`+placeholders(len(query.States))+`)`)
+	}
+	stmt := `SELECT ` + jobColumns + ` FROM article_jobs`
+	if len(where) > 0 {
+		stmt += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	stmt += ` ORDER BY run_at_ms, id LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+	return r.queryJobs(ctx, stmt, args...)
+}
+
+// DueJobs returns pending jobs whose time has come, oldest first. Jobs missed
+// while the app was closed are due as soon as it starts again. Jobs of
+// trashed articles are skipped rather than failed; trashing cancels them, so
+// only jobs left by older builds are affected.
+func (r *SQLiteRepository) DueJobs(ctx context.Context, now time.Time, limit int) ([]domain.ScheduledJob, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	return r.queryJobs(ctx, `
+SELECT `+jobColumns+` FROM article_jobs
+WHERE state = ? AND run_at_ms <= ? AND article_id NOT IN (SELECT id FROM articles WHERE deleted_at_ms != 0)
+ORDER BY run_at_ms, id LIMIT ?
+`, string(domain.JobStatePending), now.UnixMilli(), limit)
+}
+
+func (r *SQLiteRepository) queryJobs(ctx context.Context, stmt string, args ...any) ([]domain.ScheduledJob, error) {
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.ScheduledJob, 0)
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *SQLiteRepository) RunJob(ctx context.Context, jobID string, now time.Time) error {
+	if jobID == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("job id is required"))
+	}
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	job, err := scanJob(tx.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM article_jobs WHERE id = ? AND state = ?`, jobID, string(domain.JobStatePending)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrNotFound
+		}
+		return err
+	}
+	switch job.Action {
+	case domain.JobActionPublish:
+		var status, title, content string
+		if err := tx.QueryRowContext(ctx, `SELECT status, title, content FROM articles WHERE id = ? AND deleted_at_ms = 0`, job.ArticleID).Scan(&status, &title, &content); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return domain.ErrNotFound
+			}
+			return err
+		}
+		if domain.ArticleStatus(status) != domain.ArticleStatusScheduled {
+			return errors.Join(domain.ErrInvalidArgument, fmt.Errorf("article is %s, not scheduled", status))
+		}
+		if err := domain.ValidateArticleFields(domain.ArticleStatusPublished, title, content); err != nil {
+			return errors.Join(domain.ErrInvalidArgument, err)
+		}
+		if err := r.setStatusTx(ctx, tx, job.ArticleID, domain.ArticleStatusScheduled, domain.ArticleStatusPublished, "published on schedule", now.UnixMilli()); err != nil {
+			return err
+		}
+	default:
+		return errors.Join(domain.ErrInvalidArgument, fmt.Errorf("unknown job action %q", job.Action))
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE article_jobs SET state = ?, attempts = attempts + 1, last_error = '', updated_at_ms = ? WHERE id = ?`, string(domain.JobStateDone), now.UnixMilli(), jobID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteRepository) RecordJobFailure(ctx context.Context, params domain.JobFailureParams) error {
+	if params.JobID == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("job id is required"))
+	}
+	at := params.At
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	state, runAt := domain.JobStatePending, params.RetryAt
+	if runAt.IsZero() {
+		state, runAt = domain.JobStateFailed, at
+	}
+	_, err := r.db.ExecContext(ctx, `
+UPDATE article_jobs
+SET state = ?, run_at_ms = ?, attempts = attempts + 1, last_error = ?, updated_at_ms = ?
+WHERE id = ? AND state = ?
+`, string(state), runAt.UnixMilli(), params.Err, at.UnixMilli(), params.JobID, string(domain.JobStatePending))
+	return err
+}
+
+// cancelJobsTx cancels an article's pending jobs after its status was changed
+// by other means.
+func cancelJobsTx(ctx context.Context, tx *sql.Tx, articleID string, atMs int64) error {
+	_, err := tx.ExecContext(ctx, `UPDATE article_jobs SET state = ?, updated_at_ms = ? WHERE article_id = ? AND state = ?`, string(domain.JobStateCancelled), atMs, articleID, string(domain.JobStatePending))
+	return err
+}
+
+// setStatusTx changes only the status of a live article, recording a new
+// version and the status change like any other save.
+func (r *SQLiteRepository) setStatusTx(ctx context.Context, tx *sql.Tx, articleID string, from, to domain.ArticleStatus, note string, atMs int64) error {
+	res, err := tx.ExecContext(ctx, `
+UPDATE articles
+SET status = ?, updated_at_ms = ?, current_version = current_version + 1
+WHERE id = ? AND status = ? AND deleted_at_ms = 0
+`, string(to), atMs, articleID, string(from))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrNotFound
+	}
+
+	tagNames, err := r.fetchTagNames(ctx, tx, articleID)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	if err := tx.QueryRowContext(ctx, `SELECT current_version FROM articles WHERE id = ?`, articleID).Scan(&version); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO article_versions(article_id, version, title, content, status, digest, author, cover_image, source_url, open_comments, word_count, tags_csv, created_at_ms, is_autosave)
+SELECT id, current_version, title, content, status, digest, author, cover_image, source_url, open_comments, word_count, ?, updated_at_ms, 0
+FROM articles WHERE id = ?
+`, strings.Join(tagNames, ","), articleID); err != nil {
+		return err
+	}
+	return recordStatusChangeTx(ctx, tx, articleID, version, from, to, note, atMs)
+}
@@ -0,0 +1,183 @@
+﻿package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+var sortColumns = map[domain.ArticleSort]string{
+	domain.ArticleSortUpdatedAt: "a.updated_at_ms",
+	domain.ArticleSortCreatedAt: "a.created_at_ms",
+	domain.ArticleSortTitle:     "a.title",
+	domain.ArticleSortWordCount: "a.word_count",
+	// Negated so that the default descending order is the collection order.
+	domain.ArticleSortCollection: "(-ca.position)",
+}
+
+func listSort(q domain.ListArticlesQuery) domain.ArticleSort {
+	switch {
+	case q.Sort != "":
+		return q.Sort
+	case q.CollectionID != "":
+		return domain.ArticleSortCollection
+	default:
+		return domain.ArticleSortUpdatedAt
+	}
+}
+
+// snapshotColumns are the sort columns read from an article's version as of
+// a cursor snapshot, so edits made while paging do not move rows.
+var snapshotColumns = map[domain.ArticleSort]string{
+	domain.ArticleSortUpdatedAt: "sv.created_at_ms",
+	domain.ArticleSortTitle:     "sv.title",
+	domain.ArticleSortWordCount: "sv.word_count",
+}
+
+// listCursor is the position after the last row of a page. It is encoded as
+// base64 JSON; callers treat it as opaque. AsOf is the newest updated_at_ms
+// when the first page was listed; every page is sorted as of then.
+type listCursor struct {
+	Sort      domain.ArticleSort `json:"s"`
+	Ascending bool               `json:"a,omitempty"`
+	AsOf      int64              `json:"at"`
+	Num       int64              `json:"n,omitempty"`
+	Text      string             `json:"t,omitempty"`
+	ID        string             `json:"id"`
+}
+
+func (c listCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, sort domain.ArticleSort, ascending bool) (*listCursor, error) {
+	invalid := errors.Join(domain.ErrInvalidArgument, errors.New("invalid cursor"))
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+	var c listCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == "" {
+		return nil, invalid
+	}
+	if c.Sort != sort || c.Ascending != ascending {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("cursor was issued for a different sort order"))
+	}
+	return &c, nil
+}
+
+func (c listCursor) value() any {
+	if c.Sort == domain.ArticleSortTitle {
+		return c.Text
+	}
+	return c.Num
+}
+
+// listSQL builds the ID query for a listing. Each row is the article ID and
+// its sort value, ordered with the ID as tie-breaker so that keyset cursors
+// are unambiguous.
+//
+// With a cursor, articles are placed by their latest version at cursor.AsOf
+// (every change to an article writes a version stamped with its
+// updated_at_ms) and articles created since are left out; a cursor without
+// an ID starts the first page. This relies on later edits carrying later
+// timestamps, and an autosave compacted away while paging falls back to the
+// version before it.
+func listSQL(q domain.ListArticlesQuery, cursor *listCursor, limit, offset int) (string, []any, error) {
+	sort := listSort(q)
+	col, ok := sortColumns[sort]
+	if !ok {
+		return "", nil, errors.Join(domain.ErrInvalidArgument, errors.New("invalid sort"))
+	}
+	updatedCol := "a.updated_at_ms"
+	if cursor != nil {
+		if c, ok := snapshotColumns[sort]; ok {
+			col = c
+		}
+		updatedCol = snapshotColumns[domain.ArticleSortUpdatedAt]
+	}
+	if sort == domain.ArticleSortCollection && q.CollectionID == "" {
+		return "", nil, errors.Join(domain.ErrInvalidArgument, errors.New("collection order needs a collection"))
+	}
+	match := q.TagMatch
+	if match == "" {
+		match = domain.TagMatchAll
+	}
+	if !match.Valid() {
+		return "", nil, errors.Join(domain.ErrInvalidArgument, errors.New("invalid tag match"))
+	}
+
+	var b strings.Builder
+	args := make([]any, 0, 8)
+	b.WriteString("SELECT a.id, " + col + " FROM articles a")
+	if cursor != nil {
+		b.WriteString(" JOIN article_versions sv ON sv.article_id = a.id AND sv.version = (SELECT MAX(v.version) FROM article_versions v WHERE v.article_id = a.id AND v.created_at_ms <= ?)")
+		args = append(args, cursor.AsOf)
+	}
+	if q.CollectionID != "" {
+		b.WriteString(" JOIN collection_articles ca ON ca.article_id = a.id AND ca.collection_id = ?")
+		args = append(args, q.CollectionID)
+	}
+	b.WriteString(" WHERE a.deleted_at_ms = 0")
+	if q.Status != nil {
+		b.WriteString(" AND a.status = ?")
+		args = append(args, string(*q.Status))
+	}
+	if len(q.Statuses) > 0 {
+		b.WriteString(" AND a.status IN (" + placeholders(len(q.Statuses)) + ")")
+		for _, st := range q.Statuses {
+			args = append(args, string(st))
+		}
+	}
+
+	const hasTag = " AND EXISTS (SELECT 1 FROM article_tags at JOIN tags t ON t.id = at.tag_id WHERE at.article_id = a.id AND t.name "
+	if q.Tag != nil {
+		b.WriteString(hasTag + "= ?)")
+		args = append(args, strings.ToLower(strings.TrimSpace(*q.Tag)))
+	}
+	if len(q.Tags) > 0 {
+		if match == domain.TagMatchAny {
+			b.WriteString(hasTag + "IN (" + placeholders(len(q.Tags)) + "))")
+			for _, tag := range q.Tags {
+				args = append(args, strings.ToLower(strings.TrimSpace(tag)))
+			}
+		} else {
+			for _, tag := range q.Tags {
+				b.WriteString(hasTag + "= ?)")
+				args = append(args, strings.ToLower(strings.TrimSpace(tag)))
+			}
+		}
+	}
+
+	for _, r := range []struct {
+		cond string
+		ms   int64
+		set  bool
+	}{
+		{" AND a.created_at_ms >= ?", q.CreatedFrom.UnixMilli(), !q.CreatedFrom.IsZero()},
+		{" AND a.created_at_ms < ?", q.CreatedTo.UnixMilli(), !q.CreatedTo.IsZero()},
+		{" AND " + updatedCol + " >= ?", q.UpdatedFrom.UnixMilli(), !q.UpdatedFrom.IsZero()},
+		{" AND " + updatedCol + " < ?", q.UpdatedTo.UnixMilli(), !q.UpdatedTo.IsZero()},
+	} {
+		if r.set {
+			b.WriteString(r.cond)
+			args = append(args, r.ms)
+		}
+	}
+
+	dir, cmp := " DESC", "<"
+	if q.Ascending {
+		dir, cmp = " ASC", ">"
+	}
+	if cursor != nil && cursor.ID != "" {
+		b.WriteString(" AND (" + col + " " + cmp + " ? OR (" + col + " = ? AND a.id " + cmp + " ?))")
+		args = append(args, cursor.value(), cursor.value(), cursor.ID)
+	}
+	b.WriteString(" ORDER BY " + col + dir + ", a.id" + dir + " LIMIT ? OFFSET ?")
+	args = append(args, limit, offset)
+	return b.String(), args, nil
+}
@@ -0,0 +1,222 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Xiaoxinkeji/WX/internal/core/migrate"
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+// MigrationFeature is the key the articles schema is versioned under in
+// schema_migrations.
+const MigrationFeature = "articles"
+
+// Migrations returns the ordered articles schema migrations. Append new steps;
+// never edit one that has shipped.
+func Migrations() []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "baseline", Up: migrate.SQL(`
+CREATE TABLE IF NOT EXISTS articles (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	status TEXT NOT NULL,
+	created_at_ms INTEGER NOT NULL,
+	updated_at_ms INTEGER NOT NULL,
+	current_version INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS article_versions (
+	article_id TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	status TEXT NOT NULL,
+	tags_csv TEXT NOT NULL,
+	created_at_ms INTEGER NOT NULL,
+	is_autosave INTEGER NOT NULL,
+	PRIMARY KEY(article_id, version),
+	FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	created_at_ms INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS article_tags (
+	article_id TEXT NOT NULL,
+	tag_id TEXT NOT NULL,
+	PRIMARY KEY(article_id, tag_id),
+	FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE,
+	FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_articles_updated_at_ms ON articles(updated_at_ms DESC);
+CREATE INDEX IF NOT EXISTS idx_article_tags_article_id ON article_tags(article_id);
+CREATE INDEX IF NOT EXISTS idx_article_tags_tag_id ON article_tags(tag_id);
+` + ftsSchema)},
+		{Version: 2, Name: "article metadata", Up: addMetadataColumns},
+		{Version: 3, Name: "cjk search segmentation", Up: func(ctx context.Context, tx *sql.Tx) error {
+			return rebuildFTS(ctx, tx, "")
+		}},
+		{Version: 4, Name: "listing sort columns", Up: addWordCount},
+		{Version: 5, Name: "trash", Up: func(ctx context.Context, tx *sql.Tx) error {
+			if err := migrate.EnsureColumn(ctx, tx, "articles", "deleted_at_ms", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_articles_deleted_at_ms ON articles(deleted_at_ms)`)
+			return err
+		}},
+		{Version: 6, Name: "status history", Up: migrate.SQL(`
+CREATE TABLE IF NOT EXISTS article_status_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	article_id TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	from_status TEXT NOT NULL,
+	to_status TEXT NOT NULL,
+	note TEXT NOT NULL DEFAULT '',
+	changed_at_ms INTEGER NOT NULL,
+	FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_article_status_history_article_id ON article_status_history(article_id, id);
+`)},
+		{Version: 7, Name: "scheduled jobs", Up: migrate.SQL(`
+CREATE TABLE IF NOT EXISTS article_jobs (
+	id TEXT PRIMARY KEY,
+	article_id TEXT NOT NULL,
+	action TEXT NOT NULL,
+	state TEXT NOT NULL,
+	run_at_ms INTEGER NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	from_status TEXT NOT NULL DEFAULT '',
+	created_at_ms INTEGER NOT NULL,
+	updated_at_ms INTEGER NOT NULL,
+	FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_article_jobs_due ON article_jobs(state, run_at_ms);
+CREATE INDEX IF NOT EXISTS idx_article_jobs_article_id ON article_jobs(article_id);
+`)},
+		{Version: 8, Name: "collections", Up: migrate.SQL(`
+CREATE TABLE IF NOT EXISTS collections (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	created_at_ms INTEGER NOT NULL,
+	updated_at_ms INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS collection_articles (
+	collection_id TEXT NOT NULL,
+	article_id TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	added_at_ms INTEGER NOT NULL,
+	PRIMARY KEY(collection_id, article_id),
+	FOREIGN KEY(collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+	FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_articles_position ON collection_articles(collection_id, position);
+CREATE INDEX IF NOT EXISTS idx_collection_articles_article_id ON collection_articles(article_id);
+`)},
+		{Version: 9, Name: "version word counts", Up: addVersionWordCount},
+	}
+}
+
+// addMetadataColumns tolerates columns that already exist, since databases
+// opened by builds that predate versioning added them ad hoc.
+func addMetadataColumns(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range []string{"articles", "article_versions"} {
+		for _, c := range []struct{ column, def string }{
+			{"digest", "TEXT NOT NULL DEFAULT ''"},
+			{"author", "TEXT NOT NULL DEFAULT ''"},
+			{"cover_image", "TEXT NOT NULL DEFAULT ''"},
+			{"source_url", "TEXT NOT NULL DEFAULT ''"},
+			{"open_comments", "INTEGER NOT NULL DEFAULT 0"},
+		} {
+			if err := migrate.EnsureColumn(ctx, tx, table, c.column, c.def); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func addWordCount(ctx context.Context, tx *sql.Tx) error {
+	if err := migrate.EnsureColumn(ctx, tx, "articles", "word_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT id, content FROM articles`)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	for rows.Next() {
+		var id, content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return err
+		}
+		counts[id] = domain.CountWords(content)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+	for id, n := range counts {
+		if _, err := tx.ExecContext(ctx, `UPDATE articles SET word_count = ? WHERE id = ?`, n, id); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+CREATE INDEX IF NOT EXISTS idx_articles_created_at_ms ON articles(created_at_ms DESC);
+CREATE INDEX IF NOT EXISTS idx_articles_title ON articles(title);
+CREATE INDEX IF NOT EXISTS idx_articles_word_count ON articles(word_count);
+`)
+	return err
+}
+
+// addVersionWordCount stores each version's word count, so that cursor
+// listings can sort by the count an article had when paging started.
+func addVersionWordCount(ctx context.Context, tx *sql.Tx) error {
+	if err := migrate.EnsureColumn(ctx, tx, "article_versions", "word_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT article_id, version, content FROM article_versions`)
+	if err != nil {
+		return err
+	}
+	type key struct {
+		id      string
+		version int
+	}
+	counts := make(map[key]int)
+	for rows.Next() {
+		var (
+			k       key
+			content string
+		)
+		if err := rows.Scan(&k.id, &k.version, &content); err != nil {
+			rows.Close()
+			return err
+		}
+		counts[k] = domain.CountWords(content)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+	for k, n := range counts {
+		if _, err := tx.ExecContext(ctx, `UPDATE article_versions SET word_count = ? WHERE article_id = ? AND version = ?`, n, k.id, k.version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
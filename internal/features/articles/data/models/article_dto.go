@@ -12,8 +12,15 @@ type ArticleDTO struct {
 	Title          string
 	Content        string
 	Status         string
+	Digest         string
+	Author         string
+	CoverImage     string
+	SourceURL      string
+	OpenComments   int
+	WordCount      int
 	CreatedAtMs    int64
 	UpdatedAtMs    int64
+	DeletedAtMs    int64
 	CurrentVersion int
 }
 
@@ -25,28 +32,44 @@ func (a ArticleDTO) ToDomain(tags []domain.Tag) (domain.Article, error) {
 
 	createdAt := time.UnixMilli(a.CreatedAtMs).UTC()
 	updatedAt := time.UnixMilli(a.UpdatedAtMs).UTC()
+	var deletedAt time.Time
+	if a.DeletedAtMs != 0 {
+		deletedAt = time.UnixMilli(a.DeletedAtMs).UTC()
+	}
 
 	return domain.Article{
 		ID:             a.ID,
 		Title:          a.Title,
 		Content:        a.Content,
 		Status:         status,
+		Digest:         a.Digest,
+		Author:         a.Author,
+		CoverImage:     a.CoverImage,
+		SourceURL:      a.SourceURL,
+		OpenComments:   a.OpenComments != 0,
+		WordCount:      a.WordCount,
 		Tags:           tags,
 		CreatedAt:      createdAt,
 		UpdatedAt:      updatedAt,
+		DeletedAt:      deletedAt,
 		CurrentVersion: a.CurrentVersion,
 	}, nil
 }
 
 type ArticleVersionDTO struct {
-	ArticleID   string
-	Version     int
-	Title       string
-	Content     string
-	Status      string
-	TagsCSV     string
-	CreatedAtMs int64
-	IsAutoSave  int
+	ArticleID    string
+	Version      int
+	Title        string
+	Content      string
+	Status       string
+	Digest       string
+	Author       string
+	CoverImage   string
+	SourceURL    string
+	OpenComments int
+	TagsCSV      string
+	CreatedAtMs  int64
+	IsAutoSave   int
 }
 
 func (v ArticleVersionDTO) ToDomain() (domain.ArticleVersion, error) {
@@ -57,14 +80,19 @@ func (v ArticleVersionDTO) ToDomain() (domain.ArticleVersion, error) {
 
 	tags := parseTagsCSV(v.TagsCSV)
 	return domain.ArticleVersion{
-		ArticleID:  v.ArticleID,
-		Version:    v.Version,
-		Title:      v.Title,
-		Content:    v.Content,
-		Status:     status,
-		Tags:       tags,
-		CreatedAt:  time.UnixMilli(v.CreatedAtMs).UTC(),
-		IsAutoSave: v.IsAutoSave != 0,
+		ArticleID:    v.ArticleID,
+		Version:      v.Version,
+		Title:        v.Title,
+		Content:      v.Content,
+		Status:       status,
+		Digest:       v.Digest,
+		Author:       v.Author,
+		CoverImage:   v.CoverImage,
+		SourceURL:    v.SourceURL,
+		OpenComments: v.OpenComments != 0,
+		Tags:         tags,
+		CreatedAt:    time.UnixMilli(v.CreatedAtMs).UTC(),
+		IsAutoSave:   v.IsAutoSave != 0,
 	}, nil
 }
 
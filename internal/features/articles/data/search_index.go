@@ -4,37 +4,71 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
 )
 
-// escapeFTS5Query escapes special characters in FTS5 queries to prevent syntax errors
-func escapeFTS5Query(query string) string {
-	// Remove or escape FTS5 special characters
-	replacer := strings.NewReplacer(
-		`"`, `""`,  // Escape double quotes
-		`*`, ``,    // Remove wildcards
-		`-`, ` `,   // Replace minus with space
-		`(`, ``,    // Remove parentheses
-		`)`, ``,
-		`{`, ``,    // Remove braces
-		`}`, ``,
-		`[`, ``,    // Remove brackets
-		`]`, ``,
-		`^`, ``,    // Remove caret
-		`:`, ``,    // Remove colon
-	)
-	escaped := replacer.Replace(query)
-
-	// Trim and wrap in double quotes for phrase matching
-	escaped = strings.TrimSpace(escaped)
-	if escaped == "" {
-		return `""`
-	}
-
-	// Use double quotes for exact phrase matching
-	return `"` + escaped + `"`
+// compileFTS5 renders a parsed search expression as an FTS5 query. Every
+// term becomes a quoted phrase, so no user text reaches FTS5 as syntax.
+func compileFTS5(n domain.SearchNode) (string, error) {
+	switch n.Kind {
+	case domain.SearchTermNode:
+		phrase := segmentPhrase(n.Text, n.Prefix)
+		if phrase == "" {
+			return "", errors.Join(domain.ErrInvalidArgument, fmt.Errorf("search query: %q has nothing to search for", n.Text))
+		}
+		if n.Field != "" {
+			phrase = n.Field + " : " + phrase
+		}
+		return phrase, nil
+	case domain.SearchOrNode:
+		parts := make([]string, 0, len(n.Children))
+		for _, c := range n.Children {
+			part, err := compileFTS5(c)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		return "(" + strings.Join(parts, " OR ") + ")", nil
+	case domain.SearchAndNode:
+		var positive, negative []string
+		for _, c := range n.Children {
+			if c.Kind == domain.SearchNotNode {
+				part, err := compileFTS5(c.Children[0])
+				if err != nil {
+					return "", err
+				}
+				negative = append(negative, part)
+				continue
+			}
+			part, err := compileFTS5(c)
+			if err != nil {
+				return "", err
+			}
+			positive = append(positive, part)
+		}
+		if len(positive) == 0 {
+			return "", errors.Join(domain.ErrInvalidArgument, errors.New("search query: a query cannot consist only of excluded terms"))
+		}
+		expr := "(" + strings.Join(positive, " AND ") + ")"
+		for _, neg := range negative {
+			expr = "(" + expr + " NOT " + neg + ")"
+		}
+		return expr, nil
+	case domain.SearchNotNode:
+		return "", errors.Join(domain.ErrInvalidArgument, errors.New("search query: a query cannot consist only of excluded terms"))
+	}
+	return "", fmt.Errorf("search query: unknown node kind %d", n.Kind)
 }
 
+const ftsSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS article_fts
+USING fts5(title, content, tags, article_id UNINDEXED);
+`
+
 type SQLiteSearchIndex struct {
 	db *sql.DB
 }
@@ -48,10 +82,7 @@ func NewSQLiteSearchIndex(db *sql.DB) (*SQLiteSearchIndex, error) {
 }
 
 func (s *SQLiteSearchIndex) EnsureSchema(ctx context.Context) error {
-	_, err := s.db.ExecContext(ctx, `
-CREATE VIRTUAL TABLE IF NOT EXISTS article_fts
-USING fts5(title, content, tags, article_id UNINDEXED);
-`)
+	_, err := s.db.ExecContext(ctx, ftsSchema)
 	return err
 }
 
@@ -63,11 +94,73 @@ func (s *SQLiteSearchIndex) UpsertTx(ctx context.Context, tx *sql.Tx, articleID,
 	if err != nil {
 		return err
 	}
-	joinedTags := strings.Join(tags, " ")
-	_, err = tx.ExecContext(ctx, `INSERT INTO article_fts(title, content, tags, article_id) VALUES(?, ?, ?, ?)`, title, content, joinedTags, articleID)
+	return insertFTS(ctx, tx, articleID, title, content, strings.Join(tags, " "))
+}
+
+func insertFTS(ctx context.Context, tx *sql.Tx, articleID, title, content, tags string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO article_fts(title, content, tags, article_id) VALUES(?, ?, ?, ?)`,
+		segmentForIndex(title), segmentForIndex(content), segmentForIndex(tags), articleID)
 	return err
 }
 
+// Reindex rebuilds article_fts from the articles outside the trash. Use it
+// after the segmentation rules change.
+func (s *SQLiteSearchIndex) Reindex(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := rebuildFTS(ctx, tx, "WHERE a.deleted_at_ms = 0"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebuildFTS refills article_fts from the articles matching where. It is
+// also used by migrations, so where must only name columns that exist at the
+// caller's schema version.
+func rebuildFTS(ctx context.Context, tx *sql.Tx, where string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM article_fts`); err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, `
+SELECT a.id, a.title, a.content, COALESCE(GROUP_CONCAT(t.name, ' '), '')
+FROM articles a
+LEFT JOIN article_tags at ON at.article_id = a.id
+LEFT JOIN tags t ON t.id = at.tag_id
+`+where+`
+GROUP BY a.id
+`)
+	if err != nil {
+		return err
+	}
+	type entry struct{ id, title, content, tags string }
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.title, &e.content, &e.tags); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+	for _, e := range entries {
+		if err := insertFTS(ctx, tx, e.id, e.title, e.content, e.tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *SQLiteSearchIndex) DeleteTx(ctx context.Context, tx *sql.Tx, articleID string) error {
 	if tx == nil {
 		return errors.New("search index: tx is nil")
@@ -76,46 +169,101 @@ func (s *SQLiteSearchIndex) DeleteTx(ctx context.Context, tx *sql.Tx, articleID
 	return err
 }
 
-func (s *SQLiteSearchIndex) SearchArticleIDs(ctx context.Context, query string, limit, offset int) ([]string, error) {
-	q := strings.TrimSpace(query)
-	if q == "" {
-		return nil, nil
+// SearchArticleIDs returns the IDs of articles matching query, best BM25
+// match first. Filters from the query syntax and from query.Status and
+// query.Tag are applied in SQL, before the limit.
+func (s *SQLiteSearchIndex) SearchArticleIDs(ctx context.Context, query domain.SearchArticlesQuery) ([]string, error) {
+	hits, _, err := s.search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.id)
 	}
+	return ids, nil
+}
+
+type scoredID struct {
+	id    string
+	score float64
+}
+
+func (s *SQLiteSearchIndex) search(ctx context.Context, query domain.SearchArticlesQuery) ([]scoredID, domain.ParsedSearchQuery, error) {
+	if strings.TrimSpace(query.Query) == "" {
+		return nil, domain.ParsedSearchQuery{}, nil
+	}
+	parsed, err := domain.ParseSearchQuery(query.Query)
+	if err != nil {
+		return nil, domain.ParsedSearchQuery{}, err
+	}
+	limit := query.Limit
 	if limit <= 0 {
 		limit = 20
 	}
 	if limit > 100 {
 		limit = 100
 	}
+	offset := query.Offset
 	if offset < 0 {
 		offset = 0
 	}
 
-	// Escape FTS5 special characters to prevent query syntax errors
-	escapedQuery := escapeFTS5Query(q)
+	var b strings.Builder
+	args := make([]any, 0, 8)
+	if parsed.Text != nil {
+		expr, err := compileFTS5(*parsed.Text)
+		if err != nil {
+			return nil, domain.ParsedSearchQuery{}, err
+		}
+		b.WriteString("SELECT a.id, -bm25(article_fts) FROM article_fts JOIN articles a ON a.id = article_fts.article_id WHERE article_fts MATCH ?")
+		args = append(args, expr)
+	} else {
+		b.WriteString("SELECT a.id, 0 FROM articles a WHERE a.deleted_at_ms = 0")
+	}
+	for _, st := range []*domain.ArticleStatus{query.Status, parsed.Status} {
+		if st != nil {
+			b.WriteString(" AND a.status = ?")
+			args = append(args, string(*st))
+		}
+	}
+	tags := parsed.Tags
+	if query.Tag != nil {
+		tags = append([]string{strings.ToLower(strings.TrimSpace(*query.Tag))}, tags...)
+	}
+	const hasTag = "EXISTS (SELECT 1 FROM article_tags at JOIN tags t ON t.id = at.tag_id WHERE at.article_id = a.id AND t.name = ?)"
+	for _, tag := range tags {
+		b.WriteString(" AND " + hasTag)
+		args = append(args, tag)
+	}
+	for _, tag := range parsed.ExcludeTags {
+		b.WriteString(" AND NOT " + hasTag)
+		args = append(args, tag)
+	}
+	if parsed.Text != nil {
+		b.WriteString(" ORDER BY bm25(article_fts)")
+	} else {
+		b.WriteString(" ORDER BY a.updated_at_ms DESC")
+	}
+	b.WriteString(" LIMIT ? OFFSET ?")
+	args = append(args, limit, offset)
 
-	rows, err := s.db.QueryContext(ctx, `
-SELECT article_id
-FROM article_fts
-WHERE article_fts MATCH ?
-ORDER BY bm25(article_fts)
-LIMIT ? OFFSET ?
-`, escapedQuery, limit, offset)
+	rows, err := s.db.QueryContext(ctx, b.String(), args...)
 	if err != nil {
-		return nil, err
+		return nil, domain.ParsedSearchQuery{}, err
 	}
 	defer rows.Close()
 
-	ids := make([]string, 0, limit)
+	hits := make([]scoredID, 0, limit)
 	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
+		var h scoredID
+		if err := rows.Scan(&h.id, &h.score); err != nil {
+			return nil, domain.ParsedSearchQuery{}, err
 		}
-		ids = append(ids, id)
+		hits = append(hits, h)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, domain.ParsedSearchQuery{}, err
 	}
-	return ids, nil
+	return hits, parsed, nil
 }
@@ -0,0 +1,122 @@
+﻿package data
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The unicode61 tokenizer keeps a whole run of CJK characters as a single
+// token, so a two-character word inside a sentence never matches. Text is
+// therefore segmented in Go before it reaches article_fts: each CJK run is
+// written as overlapping bigrams followed by its last character on its own,
+// and everything else is left for unicode61 to split and fold. Queries are
+// segmented the same way so that phrase adjacency lines up with the index.
+
+type segment struct {
+	text string
+	cjk  bool
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isTokenRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// splitSegments breaks s into word and CJK runs, dropping separators.
+func splitSegments(s string) []segment {
+	var (
+		out []segment
+		cur strings.Builder
+		cjk bool
+	)
+	flush := func() {
+		if cur.Len() > 0 {
+			out = append(out, segment{text: cur.String(), cjk: cjk})
+			cur.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+			cur.WriteRune(r)
+		case isTokenRune(r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			cur.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return out
+}
+
+func bigrams(run []rune) []string {
+	out := make([]string, 0, len(run))
+	for i := 0; i+1 < len(run); i++ {
+		out = append(out, string(run[i:i+2]))
+	}
+	return out
+}
+
+// segmentForIndex returns s as space separated tokens for article_fts.
+func segmentForIndex(s string) string {
+	var tokens []string
+	for _, seg := range splitSegments(s) {
+		if !seg.cjk {
+			tokens = append(tokens, seg.text)
+			continue
+		}
+		run := []rune(seg.text)
+		tokens = append(tokens, bigrams(run)...)
+		tokens = append(tokens, string(run[len(run)-1]))
+	}
+	return strings.Join(tokens, " ")
+}
+
+// segmentPhrase turns query text into an FTS5 phrase that matches the text
+// anywhere inside segmented content, with the last token as a prefix when
+// prefix is set. It returns "" when text has no searchable characters.
+func segmentPhrase(text string, prefix bool) string {
+	segs := splitSegments(text)
+	var tokens []string
+	for i, seg := range segs {
+		last := i == len(segs)-1
+		if !seg.cjk {
+			tokens = append(tokens, seg.text)
+			continue
+		}
+		run := []rune(seg.text)
+		tokens = append(tokens, bigrams(run)...)
+		switch {
+		case len(run) == 1:
+			// A lone character may start a bigram in the index, or be the
+			// trailing unigram of a run; a prefix token covers both.
+			tokens = append(tokens, seg.text)
+			if last {
+				prefix = true
+			}
+		case !last:
+			// The index run ends here too, so its trailing unigram sits
+			// between this run and the next token.
+			tokens = append(tokens, string(run[len(run)-1]))
+		}
+	}
+	if len(tokens) == 0 {
+		return ""
+	}
+	phrase := `"` + strings.Join(tokens, " ") + `"`
+	if prefix {
+		phrase += "*"
+	}
+	return phrase
+}
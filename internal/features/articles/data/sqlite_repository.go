@@ -9,17 +9,37 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Xiaoxinkeji/WX/internal/core/migrate"
 	"github.com/Xiaoxinkeji/WX/internal/features/articles/data/models"
 	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
 )
 
 type SQLiteRepository struct {
-	db    *sql.DB
-	index *SQLiteSearchIndex
+	db         *sql.DB
+	index      *SQLiteSearchIndex
+	purgeHooks []PurgeHook
 }
 
-func NewSQLiteRepository(db *sql.DB) (*SQLiteRepository, error) {
+// PurgeHook deletes rows another feature keeps per article. PurgeTrash runs
+// it inside its transaction, before the articles are deleted.
+type PurgeHook func(ctx context.Context, tx *sql.Tx, articleIDs []string) error
+
+type RepositoryOption func(*SQLiteRepository) error
+
+// WithPurgeHook adds a hook that PurgeTrash runs with the purged article IDs.
+func WithPurgeHook(hook PurgeHook) RepositoryOption {
+	return func(r *SQLiteRepository) error {
+		if hook == nil {
+			return errors.New("sqlite repository: purge hook is nil")
+		}
+		r.purgeHooks = append(r.purgeHooks, hook)
+		return nil
+	}
+}
+
+func NewSQLiteRepository(db *sql.DB, opts ...RepositoryOption) (*SQLiteRepository, error) {
 	if db == nil {
 		return nil, errors.New("sqlite repository: db is nil")
 	}
@@ -28,12 +48,21 @@ func NewSQLiteRepository(db *sql.DB) (*SQLiteRepository, error) {
 		return nil, err
 	}
 	repo := &SQLiteRepository{db: db, index: idx}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(repo); err != nil {
+			return nil, err
+		}
+	}
 	if err := repo.EnsureSchema(context.Background()); err != nil {
 		return nil, err
 	}
 	return repo, nil
 }
 
+// EnsureSchema migrates the articles schema to the latest version.
 func (r *SQLiteRepository) EnsureSchema(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
@@ -41,51 +70,19 @@ func (r *SQLiteRepository) EnsureSchema(ctx context.Context) error {
 	if _, err := r.db.ExecContext(ctx, `PRAGMA foreign_keys = ON;`); err != nil {
 		return err
 	}
-	if _, err := r.db.ExecContext(ctx, `
-CREATE TABLE IF NOT EXISTS articles (
-	id TEXT PRIMARY KEY,
-	title TEXT NOT NULL,
-	content TEXT NOT NULL,
-	status TEXT NOT NULL,
-	created_at_ms INTEGER NOT NULL,
-	updated_at_ms INTEGER NOT NULL,
-	current_version INTEGER NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS article_versions (
-	article_id TEXT NOT NULL,
-	version INTEGER NOT NULL,
-	title TEXT NOT NULL,
-	content TEXT NOT NULL,
-	status TEXT NOT NULL,
-	tags_csv TEXT NOT NULL,
-	created_at_ms INTEGER NOT NULL,
-	is_autosave INTEGER NOT NULL,
-	PRIMARY KEY(article_id, version),
-	FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
-);
-
-CREATE TABLE IF NOT EXISTS tags (
-	id TEXT PRIMARY KEY,
-	name TEXT NOT NULL UNIQUE,
-	created_at_ms INTEGER NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS article_tags (
-	article_id TEXT NOT NULL,
-	tag_id TEXT NOT NULL,
-	PRIMARY KEY(article_id, tag_id),
-	FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE,
-	FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_articles_updated_at_ms ON articles(updated_at_ms DESC);
-CREATE INDEX IF NOT EXISTS idx_article_tags_article_id ON article_tags(article_id);
-CREATE INDEX IF NOT EXISTS idx_article_tags_tag_id ON article_tags(tag_id);
-`); err != nil {
-		return err
-	}
-	return r.index.EnsureSchema(ctx)
+	return migrate.Apply(ctx, r.db, MigrationFeature, Migrations())
+}
+
+const articleColumns = `a.id, a.title, a.content, a.status, a.digest, a.author, a.cover_image, a.source_url, a.open_comments, a.word_count, a.created_at_ms, a.updated_at_ms, a.deleted_at_ms, a.current_version`
+
+func scanArticle(row interface{ Scan(dest ...any) error }, dto *models.ArticleDTO) error {
+	return row.Scan(&dto.ID, &dto.Title, &dto.Content, &dto.Status, &dto.Digest, &dto.Author, &dto.CoverImage, &dto.SourceURL, &dto.OpenComments, &dto.WordCount, &dto.CreatedAtMs, &dto.UpdatedAtMs, &dto.DeletedAtMs, &dto.CurrentVersion)
+}
+
+const versionColumns = `article_id, version, title, content, status, digest, author, cover_image, source_url, open_comments, tags_csv, created_at_ms, is_autosave`
+
+func scanVersion(row interface{ Scan(dest ...any) error }, dto *models.ArticleVersionDTO) error {
+	return row.Scan(&dto.ArticleID, &dto.Version, &dto.Title, &dto.Content, &dto.Status, &dto.Digest, &dto.Author, &dto.CoverImage, &dto.SourceURL, &dto.OpenComments, &dto.TagsCSV, &dto.CreatedAtMs, &dto.IsAutoSave)
 }
 
 type queryer interface {
@@ -95,7 +92,10 @@ type queryer interface {
 }
 
 func (r *SQLiteRepository) CreateArticle(ctx context.Context, params domain.CreateArticleParams) (domain.Article, error) {
-	if err := domain.ValidateArticleFields(params.Status, params.Title, params.Content); err != nil {
+	if err := domain.ValidateArticleTransition(params.Workflow, domain.ArticleStatusDraft, params.Status, params.Title, params.Content); err != nil {
+		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	if err := domain.ValidateArticleMetadata(params.Digest, params.Author, params.CoverImage, params.SourceURL); err != nil {
 		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, err)
 	}
 	if params.ID == "" {
@@ -127,9 +127,9 @@ func (r *SQLiteRepository) CreateArticle(ctx context.Context, params domain.Crea
 	}
 
 	if _, err := tx.ExecContext(ctx, `
-INSERT INTO articles(id, title, content, status, created_at_ms, updated_at_ms, current_version)
-VALUES(?, ?, ?, ?, ?, ?, 1)
-`, params.ID, params.Title, params.Content, string(params.Status), createdAtMs, updatedAtMs); err != nil {
+INSERT INTO articles(id, title, content, status, digest, author, cover_image, source_url, open_comments, word_count, created_at_ms, updated_at_ms, current_version)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+`, params.ID, params.Title, params.Content, string(params.Status), params.Digest, params.Author, params.CoverImage, params.SourceURL, boolToInt(params.OpenComments), domain.CountWords(params.Content), createdAtMs, updatedAtMs); err != nil {
 		if isUniqueConstraintErr(err) {
 			return domain.Article{}, errors.Join(domain.ErrConflict, err)
 		}
@@ -140,12 +140,16 @@ VALUES(?, ?, ?, ?, ?, ?, 1)
 	if err != nil {
 		return domain.Article{}, err
 	}
+	normalizedTags = tagNamesOf(tags)
 
 	tagsCSV := strings.Join(normalizedTags, ",")
 	if _, err := tx.ExecContext(ctx, `
-INSERT INTO article_versions(article_id, version, title, content, status, tags_csv, created_at_ms, is_autosave)
-VALUES(?, 1, ?, ?, ?, ?, ?, 0)
-`, params.ID, params.Title, params.Content, string(params.Status), tagsCSV, updatedAtMs); err != nil {
+INSERT INTO article_versions(article_id, version, title, content, status, digest, author, cover_image, source_url, open_comments, word_count, tags_csv, created_at_ms, is_autosave)
+VALUES(?, 1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0)
+`, params.ID, params.Title, params.Content, string(params.Status), params.Digest, params.Author, params.CoverImage, params.SourceURL, boolToInt(params.OpenComments), domain.CountWords(params.Content), tagsCSV, updatedAtMs); err != nil {
+		return domain.Article{}, err
+	}
+	if err := recordStatusChangeTx(ctx, tx, params.ID, 1, "", params.Status, "", createdAtMs); err != nil {
 		return domain.Article{}, err
 	}
 
@@ -162,6 +166,12 @@ VALUES(?, 1, ?, ?, ?, ?, ?, 0)
 		Title:          params.Title,
 		Content:        params.Content,
 		Status:         string(params.Status),
+		Digest:         params.Digest,
+		Author:         params.Author,
+		CoverImage:     params.CoverImage,
+		SourceURL:      params.SourceURL,
+		OpenComments:   boolToInt(params.OpenComments),
+		WordCount:      domain.CountWords(params.Content),
 		CreatedAtMs:    createdAtMs,
 		UpdatedAtMs:    updatedAtMs,
 		CurrentVersion: 1,
@@ -174,11 +184,7 @@ func (r *SQLiteRepository) GetArticle(ctx context.Context, articleID string) (do
 	}
 
 	var dto models.ArticleDTO
-	if err := r.db.QueryRowContext(ctx, `
-SELECT id, title, content, status, created_at_ms, updated_at_ms, current_version
-FROM articles
-WHERE id = ?
-`, articleID).Scan(&dto.ID, &dto.Title, &dto.Content, &dto.Status, &dto.CreatedAtMs, &dto.UpdatedAtMs, &dto.CurrentVersion); err != nil {
+	if err := scanArticle(r.db.QueryRowContext(ctx, `SELECT `+articleColumns+` FROM articles a WHERE a.id = ? AND a.deleted_at_ms = 0`, articleID), &dto); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Article{}, domain.ErrNotFound
 		}
@@ -208,17 +214,17 @@ func (r *SQLiteRepository) UpdateArticle(ctx context.Context, articleID string,
 	}
 
 	var existing models.ArticleDTO
-	if err := tx.QueryRowContext(ctx, `
-SELECT id, title, content, status, created_at_ms, updated_at_ms, current_version
-FROM articles
-WHERE id = ?
-`, articleID).Scan(&existing.ID, &existing.Title, &existing.Content, &existing.Status, &existing.CreatedAtMs, &existing.UpdatedAtMs, &existing.CurrentVersion); err != nil {
+	if err := scanArticle(tx.QueryRowContext(ctx, `SELECT `+articleColumns+` FROM articles a WHERE a.id = ? AND a.deleted_at_ms = 0`, articleID), &existing); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Article{}, domain.ErrNotFound
 		}
 		return domain.Article{}, err
 	}
 
+	if params.ExpectedVersion > 0 && params.ExpectedVersion != existing.CurrentVersion {
+		return domain.Article{}, &domain.VersionConflictError{ArticleID: articleID, ExpectedVersion: params.ExpectedVersion, CurrentVersion: existing.CurrentVersion}
+	}
+
 	newTitle := existing.Title
 	if params.Title != nil {
 		newTitle = *params.Title
@@ -231,6 +237,26 @@ WHERE id = ?
 	if params.Status != nil {
 		newStatus = *params.Status
 	}
+	newDigest := existing.Digest
+	if params.Digest != nil {
+		newDigest = *params.Digest
+	}
+	newAuthor := existing.Author
+	if params.Author != nil {
+		newAuthor = *params.Author
+	}
+	newCoverImage := existing.CoverImage
+	if params.CoverImage != nil {
+		newCoverImage = *params.CoverImage
+	}
+	newSourceURL := existing.SourceURL
+	if params.SourceURL != nil {
+		newSourceURL = *params.SourceURL
+	}
+	newOpenComments := existing.OpenComments
+	if params.OpenComments != nil {
+		newOpenComments = boolToInt(*params.OpenComments)
+	}
 
 	var tagNames []string
 	if params.Tags != nil {
@@ -247,9 +273,16 @@ WHERE id = ?
 		tagNames = existingTags
 	}
 
-	if err := domain.ValidateArticleFields(newStatus, newTitle, newContent); err != nil {
+	oldStatus := domain.ArticleStatus(existing.Status)
+	if err := domain.ValidateArticleTransition(params.Workflow, oldStatus, newStatus, newTitle, newContent); err != nil {
+		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	if err := domain.ValidateArticleMetadata(newDigest, newAuthor, newCoverImage, newSourceURL); err != nil {
 		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, err)
 	}
+	if n := utf8.RuneCountInString(params.StatusNote); n > domain.MaxNoteLength {
+		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("note too long: max %d characters, got %d", domain.MaxNoteLength, n))
+	}
 
 	updatedAtMs := params.UpdatedAt.UTC().UnixMilli()
 	if updatedAtMs == 0 {
@@ -257,11 +290,15 @@ WHERE id = ?
 	}
 
 	newVersion := existing.CurrentVersion + 1
-	if _, err := tx.ExecContext(ctx, `
+	res, err := tx.ExecContext(ctx, `
 UPDATE articles
-SET title = ?, content = ?, status = ?, updated_at_ms = ?, current_version = ?
-WHERE id = ?
-`, newTitle, newContent, string(newStatus), updatedAtMs, newVersion, articleID); err != nil {
+SET title = ?, content = ?, status = ?, digest = ?, author = ?, cover_image = ?, source_url = ?, open_comments = ?, word_count = ?, updated_at_ms = ?, current_version = ?
+WHERE id = ? AND current_version = ?
+`, newTitle, newContent, string(newStatus), newDigest, newAuthor, newCoverImage, newSourceURL, newOpenComments, domain.CountWords(newContent), updatedAtMs, newVersion, articleID, existing.CurrentVersion)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	if err := expectUpdated(res, articleID, existing.CurrentVersion); err != nil {
 		return domain.Article{}, err
 	}
 
@@ -271,6 +308,7 @@ WHERE id = ?
 		if err != nil {
 			return domain.Article{}, err
 		}
+		tagNames = tagNamesOf(tags)
 	} else {
 		tags, err = r.fetchTags(ctx, tx, articleID)
 		if err != nil {
@@ -284,11 +322,21 @@ WHERE id = ?
 		isAutoSave = 1
 	}
 	if _, err := tx.ExecContext(ctx, `
-INSERT INTO article_versions(article_id, version, title, content, status, tags_csv, created_at_ms, is_autosave)
-VALUES(?, ?, ?, ?, ?, ?, ?, ?)
-`, articleID, newVersion, newTitle, newContent, string(newStatus), tagsCSV, updatedAtMs, isAutoSave); err != nil {
+INSERT INTO article_versions(article_id, version, title, content, status, digest, author, cover_image, source_url, open_comments, word_count, tags_csv, created_at_ms, is_autosave)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, articleID, newVersion, newTitle, newContent, string(newStatus), newDigest, newAuthor, newCoverImage, newSourceURL, newOpenComments, domain.CountWords(newContent), tagsCSV, updatedAtMs, isAutoSave); err != nil {
 		return domain.Article{}, err
 	}
+	if newStatus != oldStatus {
+		if err := recordStatusChangeTx(ctx, tx, articleID, newVersion, oldStatus, newStatus, params.StatusNote, updatedAtMs); err != nil {
+			return domain.Article{}, err
+		}
+		if oldStatus == domain.ArticleStatusScheduled {
+			if err := cancelJobsTx(ctx, tx, articleID, updatedAtMs); err != nil {
+				return domain.Article{}, err
+			}
+		}
+	}
 
 	if err := r.index.UpsertTx(ctx, tx, articleID, newTitle, newContent, tagNames); err != nil {
 		return domain.Article{}, err
@@ -303,6 +351,12 @@ VALUES(?, ?, ?, ?, ?, ?, ?, ?)
 		Title:          newTitle,
 		Content:        newContent,
 		Status:         string(newStatus),
+		Digest:         newDigest,
+		Author:         newAuthor,
+		CoverImage:     newCoverImage,
+		SourceURL:      newSourceURL,
+		OpenComments:   newOpenComments,
+		WordCount:      domain.CountWords(newContent),
 		CreatedAtMs:    existing.CreatedAtMs,
 		UpdatedAtMs:    updatedAtMs,
 		CurrentVersion: newVersion,
@@ -313,21 +367,19 @@ func (r *SQLiteRepository) DeleteArticle(ctx context.Context, articleID string)
 	if articleID == "" {
 		return errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
 	}
-	_tx, err := r.db.BeginTx(ctx, nil)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	defer _tx.Rollback()
-
-	if _, err := _tx.ExecContext(ctx, `PRAGMA foreign_keys = ON;`); err != nil {
-		return err
-	}
+	defer tx.Rollback()
 
-	if err := r.index.DeleteTx(ctx, _tx, articleID); err != nil {
+	// A trashed article cannot be published, so its schedule goes with it
+	// and a later restore brings it back unscheduled.
+	nowMs := time.Now().UTC().UnixMilli()
+	if err := r.cancelPublishTx(ctx, tx, articleID, "scheduled publish cancelled: moved to trash", nowMs); err != nil && !errors.Is(err, domain.ErrNotFound) {
 		return err
 	}
-
-	res, err := _tx.ExecContext(ctx, `DELETE FROM articles WHERE id = ?`, articleID)
+	res, err := tx.ExecContext(ctx, `UPDATE articles SET deleted_at_ms = ? WHERE id = ? AND deleted_at_ms = 0`, nowMs, articleID)
 	if err != nil {
 		return err
 	}
@@ -338,8 +390,140 @@ func (r *SQLiteRepository) DeleteArticle(ctx context.Context, articleID string)
 	if affected == 0 {
 		return domain.ErrNotFound
 	}
+	if err := r.index.DeleteTx(ctx, tx, articleID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteRepository) RestoreArticle(ctx context.Context, articleID string) (domain.Article, error) {
+	if articleID == "" {
+		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	defer tx.Rollback()
+
+	var dto models.ArticleDTO
+	if err := scanArticle(tx.QueryRowContext(ctx, `SELECT `+articleColumns+` FROM articles a WHERE a.id = ? AND a.deleted_at_ms != 0`, articleID), &dto); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Article{}, domain.ErrNotFound
+		}
+		return domain.Article{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE articles SET deleted_at_ms = 0 WHERE id = ?`, articleID); err != nil {
+		return domain.Article{}, err
+	}
+	dto.DeletedAtMs = 0
+
+	tags, err := r.fetchTags(ctx, tx, articleID)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	if err := r.index.UpsertTx(ctx, tx, articleID, dto.Title, dto.Content, names); err != nil {
+		return domain.Article{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Article{}, err
+	}
+	return dto.ToDomain(tags)
+}
 
-	return _tx.Commit()
+func (r *SQLiteRepository) ListTrash(ctx context.Context, query domain.ListTrashQuery) ([]domain.Article, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := query.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT a.id
+FROM articles a
+WHERE a.deleted_at_ms != 0
+ORDER BY a.deleted_at_ms DESC, a.id DESC
+LIMIT ? OFFSET ?
+`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0, limit)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return r.getArticlesByIDs(ctx, ids)
+}
+
+func (r *SQLiteRepository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	if deletedBefore.IsZero() {
+		return 0, errors.Join(domain.ErrInvalidArgument, errors.New("cutoff is required"))
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Children are deleted explicitly: foreign_keys is a per-connection
+	// pragma and cannot be switched on inside a transaction.
+	cutoff := deletedBefore.UnixMilli()
+	const expired = `SELECT id FROM articles WHERE deleted_at_ms != 0 AND deleted_at_ms < ?`
+	if len(r.purgeHooks) > 0 {
+		ids, err := queryIDsTx(ctx, tx, expired, cutoff)
+		if err != nil {
+			return 0, err
+		}
+		if len(ids) > 0 {
+			for _, hook := range r.purgeHooks {
+				if err := hook(ctx, tx, ids); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	for _, stmt := range []string{
+		`DELETE FROM article_versions WHERE article_id IN (` + expired + `)`,
+		`DELETE FROM article_tags WHERE article_id IN (` + expired + `)`,
+		`DELETE FROM article_status_history WHERE article_id IN (` + expired + `)`,
+		`DELETE FROM article_jobs WHERE article_id IN (` + expired + `)`,
+		`DELETE FROM collection_articles WHERE article_id IN (` + expired + `)`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, cutoff); err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM articles WHERE deleted_at_ms != 0 AND deleted_at_ms < ?`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(n), nil
 }
 
 func (r *SQLiteRepository) ListArticles(ctx context.Context, query domain.ListArticlesQuery) ([]domain.Article, error) {
@@ -355,14 +539,79 @@ func (r *SQLiteRepository) ListArticles(ctx context.Context, query domain.ListAr
 		offset = 0
 	}
 
-	ids, err := r.listArticleIDs(ctx, query.Status, query.Tag, limit, offset)
+	listed, err := r.listArticleIDs(ctx, query, nil, limit, offset)
 	if err != nil {
 		return nil, err
 	}
-	if len(ids) == 0 {
+	if len(listed) == 0 {
 		return nil, nil
 	}
-	return r.getArticlesByIDs(ctx, ids, query.Status, query.Tag)
+	ids := make([]string, 0, len(listed))
+	for _, l := range listed {
+		ids = append(ids, l.id)
+	}
+	return r.getArticlesByIDs(ctx, ids)
+}
+
+// ListArticlesPage lists like ListArticles but pages with query.Cursor
+// instead of Offset.
+func (r *SQLiteRepository) ListArticlesPage(ctx context.Context, query domain.ListArticlesQuery) (domain.ArticlePage, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	sort := listSort(query)
+	cursor := &listCursor{Sort: sort, Ascending: query.Ascending}
+	if query.Cursor != "" {
+		c, err := decodeCursor(query.Cursor, sort, query.Ascending)
+		if err != nil {
+			return domain.ArticlePage{}, err
+		}
+		cursor = c
+	} else if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(updated_at_ms), 0) FROM articles`).Scan(&cursor.AsOf); err != nil {
+		return domain.ArticlePage{}, err
+	}
+
+	// One extra row tells whether another page follows.
+	listed, err := r.listArticleIDs(ctx, query, cursor, limit+1, 0)
+	if err != nil {
+		return domain.ArticlePage{}, err
+	}
+	var page domain.ArticlePage
+	if len(listed) > limit {
+		listed = listed[:limit]
+		last := listed[limit-1]
+		next := listCursor{Sort: sort, Ascending: query.Ascending, AsOf: cursor.AsOf, ID: last.id}
+		switch v := last.value.(type) {
+		case int64:
+			next.Num = v
+		case string:
+			next.Text = v
+		case []byte:
+			next.Text = string(v)
+		}
+		page.NextCursor = next.encode()
+	}
+	if len(listed) == 0 {
+		return page, nil
+	}
+	ids := make([]string, 0, len(listed))
+	for _, l := range listed {
+		ids = append(ids, l.id)
+	}
+	page.Articles, err = r.getArticlesByIDs(ctx, ids)
+	if err != nil {
+		return domain.ArticlePage{}, err
+	}
+	return page, nil
+}
+
+// Reindex rebuilds the full-text index from the stored articles.
+func (r *SQLiteRepository) Reindex(ctx context.Context) error {
+	return r.index.Reindex(ctx)
 }
 
 func (r *SQLiteRepository) SearchArticles(ctx context.Context, query domain.SearchArticlesQuery) ([]domain.Article, error) {
@@ -378,14 +627,156 @@ func (r *SQLiteRepository) SearchArticles(ctx context.Context, query domain.Sear
 		offset = 0
 	}
 
-	ids, err := r.index.SearchArticleIDs(ctx, query.Query, limit, offset)
+	query.Limit, query.Offset = limit, offset
+	ids, err := r.index.SearchArticleIDs(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	if len(ids) == 0 {
 		return nil, nil
 	}
-	return r.getArticlesByIDs(ctx, ids, query.Status, query.Tag)
+	return r.getArticlesByIDs(ctx, ids)
+}
+
+// SearchArticleResults runs a search like SearchArticles and returns each
+// hit with its score, highlighted title and a content snippet.
+func (r *SQLiteRepository) SearchArticleResults(ctx context.Context, query domain.SearchResultsQuery) ([]domain.SearchResult, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := query.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	size := query.SnippetRunes
+	if size <= 0 {
+		size = domain.DefaultSnippetRunes
+	}
+	if size > domain.MaxSnippetRunes {
+		size = domain.MaxSnippetRunes
+	}
+	markOpen, markClose := query.HighlightOpen, query.HighlightClose
+	if (markOpen == "") != (markClose == "") {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("highlight open and close markers must be set together"))
+	}
+	if markOpen == "" {
+		markOpen, markClose = domain.DefaultHighlightOpen, domain.DefaultHighlightClose
+	}
+
+	search := query.SearchArticlesQuery
+	search.Limit, search.Offset = limit, offset
+	hits, parsed, err := r.index.search(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(hits))
+	scores := make(map[string]float64, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.id)
+		scores[h.id] = h.score
+	}
+	articles, err := r.getSearchHitsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	titleTerms := highlightTerms(parsed.Text, domain.SearchFieldTitle)
+	contentTerms := highlightTerms(parsed.Text, domain.SearchFieldContent)
+	out := make([]domain.SearchResult, 0, len(articles))
+	for _, h := range articles {
+		a := h.article
+		res := domain.SearchResult{Score: scores[a.ID]}
+		res.TitleMatches = findMatches(a.Title, titleTerms)
+		res.HighlightedTitle = highlight(a.Title, res.TitleMatches, markOpen, markClose)
+		res.Snippet, res.SnippetMatches = snippet(a.Content, h.contentRunes, findMatches(a.Content, contentTerms), size)
+		a.Content = ""
+		res.Article = a
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// snippetScanRunes bounds how much of each hit's content is read to find the
+// snippet; a match further in leaves the snippet at the start of the content.
+const snippetScanRunes = 20000
+
+type searchHit struct {
+	article      domain.Article
+	contentRunes int
+}
+
+// getSearchHitsByIDs is getArticlesByIDs for search results: Content holds
+// only the first snippetScanRunes runes, next to the full content length.
+func (r *SQLiteRepository) getSearchHitsByIDs(ctx context.Context, ids []string) ([]searchHit, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, 0, len(ids)*2+1)
+	args = append(args, snippetScanRunes)
+	var b strings.Builder
+	b.WriteString("SELECT a.id, a.title, substr(a.content, 1, ?), length(a.content), a.status, a.digest, a.author, a.cover_image, a.source_url, a.open_comments, a.word_count, a.created_at_ms, a.updated_at_ms, a.deleted_at_ms, a.current_version FROM articles a")
+	b.WriteString(" WHERE a.id IN (")
+	b.WriteString(placeholders(len(ids)))
+	b.WriteString(")")
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	b.WriteString(" ORDER BY ")
+	b.WriteString(caseOrder("a.id", ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	rows, err := r.db.QueryContext(ctx, b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dtos := make([]models.ArticleDTO, 0, len(ids))
+	lengths := make([]int, 0, len(ids))
+	returnedIDs := make([]string, 0, len(ids))
+	for rows.Next() {
+		var (
+			dto    models.ArticleDTO
+			length int
+		)
+		if err := rows.Scan(&dto.ID, &dto.Title, &dto.Content, &length, &dto.Status, &dto.Digest, &dto.Author, &dto.CoverImage, &dto.SourceURL, &dto.OpenComments, &dto.WordCount, &dto.CreatedAtMs, &dto.UpdatedAtMs, &dto.DeletedAtMs, &dto.CurrentVersion); err != nil {
+			return nil, err
+		}
+		dtos = append(dtos, dto)
+		lengths = append(lengths, length)
+		returnedIDs = append(returnedIDs, dto.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(dtos) == 0 {
+		return nil, nil
+	}
+
+	tagsByArticle, err := r.fetchTagsByArticles(ctx, r.db, returnedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]searchHit, 0, len(dtos))
+	for i, dto := range dtos {
+		article, err := dto.ToDomain(tagsByArticle[dto.ID])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, searchHit{article: article, contentRunes: lengths[i]})
+	}
+	return out, nil
 }
 
 func (r *SQLiteRepository) ListTags(ctx context.Context) ([]domain.Tag, error) {
@@ -426,7 +817,7 @@ func (r *SQLiteRepository) ListVersions(ctx context.Context, query domain.ListVe
 	}
 
 	rows, err := r.db.QueryContext(ctx, `
-SELECT article_id, version, title, content, status, tags_csv, created_at_ms, is_autosave
+SELECT `+versionColumns+`
 FROM article_versions
 WHERE article_id = ?
 ORDER BY version DESC
@@ -440,7 +831,7 @@ LIMIT ? OFFSET ?
 	out := make([]domain.ArticleVersion, 0)
 	for rows.Next() {
 		var dto models.ArticleVersionDTO
-		if err := rows.Scan(&dto.ArticleID, &dto.Version, &dto.Title, &dto.Content, &dto.Status, &dto.TagsCSV, &dto.CreatedAtMs, &dto.IsAutoSave); err != nil {
+		if err := scanVersion(rows, &dto); err != nil {
 			return nil, err
 		}
 		v, err := dto.ToDomain()
@@ -464,11 +855,7 @@ func (r *SQLiteRepository) GetVersion(ctx context.Context, articleID string, ver
 	}
 
 	var dto models.ArticleVersionDTO
-	if err := r.db.QueryRowContext(ctx, `
-SELECT article_id, version, title, content, status, tags_csv, created_at_ms, is_autosave
-FROM article_versions
-WHERE article_id = ? AND version = ?
-`, articleID, version).Scan(&dto.ArticleID, &dto.Version, &dto.Title, &dto.Content, &dto.Status, &dto.TagsCSV, &dto.CreatedAtMs, &dto.IsAutoSave); err != nil {
+	if err := scanVersion(r.db.QueryRowContext(ctx, `SELECT `+versionColumns+` FROM article_versions WHERE article_id = ? AND version = ?`, articleID, version), &dto); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.ArticleVersion{}, domain.ErrNotFound
 		}
@@ -481,7 +868,8 @@ WHERE article_id = ? AND version = ?
 	return v, nil
 }
 
-func (r *SQLiteRepository) RestoreVersion(ctx context.Context, articleID string, version int, restoredAt time.Time) (domain.Article, error) {
+func (r *SQLiteRepository) RestoreVersion(ctx context.Context, articleID string, params domain.RestoreVersionParams) (domain.Article, error) {
+	version, restoredAt := params.Version, params.RestoredAt
 	if articleID == "" {
 		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
 	}
@@ -503,23 +891,19 @@ func (r *SQLiteRepository) RestoreVersion(ctx context.Context, articleID string,
 	}
 
 	var existing models.ArticleDTO
-	if err := tx.QueryRowContext(ctx, `
-SELECT id, title, content, status, created_at_ms, updated_at_ms, current_version
-FROM articles
-WHERE id = ?
-`, articleID).Scan(&existing.ID, &existing.Title, &existing.Content, &existing.Status, &existing.CreatedAtMs, &existing.UpdatedAtMs, &existing.CurrentVersion); err != nil {
+	if err := scanArticle(tx.QueryRowContext(ctx, `SELECT `+articleColumns+` FROM articles a WHERE a.id = ? AND a.deleted_at_ms = 0`, articleID), &existing); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Article{}, domain.ErrNotFound
 		}
 		return domain.Article{}, err
 	}
 
+	if params.ExpectedVersion > 0 && params.ExpectedVersion != existing.CurrentVersion {
+		return domain.Article{}, &domain.VersionConflictError{ArticleID: articleID, ExpectedVersion: params.ExpectedVersion, CurrentVersion: existing.CurrentVersion}
+	}
+
 	var vdto models.ArticleVersionDTO
-	if err := tx.QueryRowContext(ctx, `
-SELECT article_id, version, title, content, status, tags_csv, created_at_ms, is_autosave
-FROM article_versions
-WHERE article_id = ? AND version = ?
-`, articleID, version).Scan(&vdto.ArticleID, &vdto.Version, &vdto.Title, &vdto.Content, &vdto.Status, &vdto.TagsCSV, &vdto.CreatedAtMs, &vdto.IsAutoSave); err != nil {
+	if err := scanVersion(tx.QueryRowContext(ctx, `SELECT `+versionColumns+` FROM article_versions WHERE article_id = ? AND version = ?`, articleID, version), &vdto); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Article{}, domain.ErrNotFound
 		}
@@ -530,18 +914,25 @@ WHERE article_id = ? AND version = ?
 		return domain.Article{}, err
 	}
 
-	newStatus := ver.Status
-	if err := domain.ValidateArticleFields(newStatus, ver.Title, ver.Content); err != nil {
+	oldStatus, newStatus := domain.ArticleStatus(existing.Status), ver.Status
+	if err := domain.ValidateArticleTransition(params.Workflow, oldStatus, newStatus, ver.Title, ver.Content); err != nil {
+		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	if err := domain.ValidateArticleMetadata(ver.Digest, ver.Author, ver.CoverImage, ver.SourceURL); err != nil {
 		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, err)
 	}
 
 	newVersion := existing.CurrentVersion + 1
 	updatedAtMs := restoredAt.UTC().UnixMilli()
-	if _, err := tx.ExecContext(ctx, `
+	res, err := tx.ExecContext(ctx, `
 UPDATE articles
-SET title = ?, content = ?, status = ?, updated_at_ms = ?, current_version = ?
-WHERE id = ?
-`, ver.Title, ver.Content, string(newStatus), updatedAtMs, newVersion, articleID); err != nil {
+SET title = ?, content = ?, status = ?, digest = ?, author = ?, cover_image = ?, source_url = ?, open_comments = ?, word_count = ?, updated_at_ms = ?, current_version = ?
+WHERE id = ? AND current_version = ?
+`, ver.Title, ver.Content, string(newStatus), ver.Digest, ver.Author, ver.CoverImage, ver.SourceURL, boolToInt(ver.OpenComments), domain.CountWords(ver.Content), updatedAtMs, newVersion, articleID, existing.CurrentVersion)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	if err := expectUpdated(res, articleID, existing.CurrentVersion); err != nil {
 		return domain.Article{}, err
 	}
 
@@ -553,14 +944,25 @@ WHERE id = ?
 	if err != nil {
 		return domain.Article{}, err
 	}
+	normalizedTags = tagNamesOf(tags)
 
 	tagsCSV := strings.Join(normalizedTags, ",")
 	if _, err := tx.ExecContext(ctx, `
-INSERT INTO article_versions(article_id, version, title, content, status, tags_csv, created_at_ms, is_autosave)
-VALUES(?, ?, ?, ?, ?, ?, ?, 0)
-`, articleID, newVersion, ver.Title, ver.Content, string(newStatus), tagsCSV, updatedAtMs); err != nil {
+INSERT INTO article_versions(article_id, version, title, content, status, digest, author, cover_image, source_url, open_comments, word_count, tags_csv, created_at_ms, is_autosave)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0)
+`, articleID, newVersion, ver.Title, ver.Content, string(newStatus), ver.Digest, ver.Author, ver.CoverImage, ver.SourceURL, boolToInt(ver.OpenComments), domain.CountWords(ver.Content), tagsCSV, updatedAtMs); err != nil {
 		return domain.Article{}, err
 	}
+	if newStatus != oldStatus {
+		if err := recordStatusChangeTx(ctx, tx, articleID, newVersion, oldStatus, newStatus, fmt.Sprintf("restored version %d", version), updatedAtMs); err != nil {
+			return domain.Article{}, err
+		}
+		if oldStatus == domain.ArticleStatusScheduled {
+			if err := cancelJobsTx(ctx, tx, articleID, updatedAtMs); err != nil {
+				return domain.Article{}, err
+			}
+		}
+	}
 
 	if err := r.index.UpsertTx(ctx, tx, articleID, ver.Title, ver.Content, normalizedTags); err != nil {
 		return domain.Article{}, err
@@ -575,44 +977,41 @@ VALUES(?, ?, ?, ?, ?, ?, ?, 0)
 		Title:          ver.Title,
 		Content:        ver.Content,
 		Status:         string(newStatus),
+		Digest:         ver.Digest,
+		Author:         ver.Author,
+		CoverImage:     ver.CoverImage,
+		SourceURL:      ver.SourceURL,
+		OpenComments:   boolToInt(ver.OpenComments),
+		WordCount:      domain.CountWords(ver.Content),
 		CreatedAtMs:    existing.CreatedAtMs,
 		UpdatedAtMs:    updatedAtMs,
 		CurrentVersion: newVersion,
 	}).ToDomain(tags)
 }
 
-func (r *SQLiteRepository) listArticleIDs(ctx context.Context, status *domain.ArticleStatus, tag *string, limit, offset int) ([]string, error) {
-	var b strings.Builder
-	args := make([]any, 0, 4)
-	b.WriteString("SELECT a.id FROM articles a")
-	if tag != nil {
-		b.WriteString(" JOIN article_tags at ON at.article_id = a.id JOIN tags t ON t.id = at.tag_id")
-	}
-	b.WriteString(" WHERE 1=1")
-	if status != nil {
-		b.WriteString(" AND a.status = ?")
-		args = append(args, string(*status))
-	}
-	if tag != nil {
-		b.WriteString(" AND t.name = ?")
-		args = append(args, strings.ToLower(strings.TrimSpace(*tag)))
-	}
-	b.WriteString(" ORDER BY a.updated_at_ms DESC LIMIT ? OFFSET ?")
-	args = append(args, limit, offset)
+type listedID struct {
+	id    string
+	value any
+}
 
-	rows, err := r.db.QueryContext(ctx, b.String(), args...)
+func (r *SQLiteRepository) listArticleIDs(ctx context.Context, query domain.ListArticlesQuery, cursor *listCursor, limit, offset int) ([]listedID, error) {
+	stmt, args, err := listSQL(query, cursor, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	out := make([]string, 0, limit)
+	out := make([]listedID, 0, limit)
 	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
+		var l listedID
+		if err := rows.Scan(&l.id, &l.value); err != nil {
 			return nil, err
 		}
-		out = append(out, id)
+		out = append(out, l)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -620,31 +1019,22 @@ func (r *SQLiteRepository) listArticleIDs(ctx context.Context, status *domain.Ar
 	return out, nil
 }
 
-func (r *SQLiteRepository) getArticlesByIDs(ctx context.Context, ids []string, status *domain.ArticleStatus, tag *string) ([]domain.Article, error) {
+// getArticlesByIDs loads articles in the order of ids. Filtering is done by
+// the query that produced ids.
+func (r *SQLiteRepository) getArticlesByIDs(ctx context.Context, ids []string) ([]domain.Article, error) {
 	if len(ids) == 0 {
 		return nil, nil
 	}
 
-	args := make([]any, 0, len(ids)*2+4)
+	args := make([]any, 0, len(ids)*2)
 	var b strings.Builder
-	b.WriteString("SELECT a.id, a.title, a.content, a.status, a.created_at_ms, a.updated_at_ms, a.current_version FROM articles a")
-	if tag != nil {
-		b.WriteString(" JOIN article_tags at ON at.article_id = a.id JOIN tags t ON t.id = at.tag_id")
-	}
+	b.WriteString("SELECT " + articleColumns + " FROM articles a")
 	b.WriteString(" WHERE a.id IN (")
 	b.WriteString(placeholders(len(ids)))
 	b.WriteString(")")
 	for _, id := range ids {
 		args = append(args, id)
 	}
-	if status != nil {
-		b.WriteString(" AND a.status = ?")
-		args = append(args, string(*status))
-	}
-	if tag != nil {
-		b.WriteString(" AND t.name = ?")
-		args = append(args, strings.ToLower(strings.TrimSpace(*tag)))
-	}
 	b.WriteString(" ORDER BY ")
 	b.WriteString(caseOrder("a.id", ids))
 	for _, id := range ids {
@@ -661,7 +1051,7 @@ func (r *SQLiteRepository) getArticlesByIDs(ctx context.Context, ids []string, s
 	returnedIDs := make([]string, 0, len(ids))
 	for rows.Next() {
 		var dto models.ArticleDTO
-		if err := rows.Scan(&dto.ID, &dto.Title, &dto.Content, &dto.Status, &dto.CreatedAtMs, &dto.UpdatedAtMs, &dto.CurrentVersion); err != nil {
+		if err := scanArticle(rows, &dto); err != nil {
 			return nil, err
 		}
 		dtos = append(dtos, dto)
@@ -781,6 +1171,9 @@ ORDER BY t.name ASC
 	return out, nil
 }
 
+// replaceTagsTx links the article to tagNames. Names left behind by a rename
+// or merge resolve to the tag they became, so the returned tags, not
+// tagNames, are what the article now carries.
 func (r *SQLiteRepository) replaceTagsTx(ctx context.Context, tx *sql.Tx, articleID string, tagNames []string, nowMs int64) ([]domain.Tag, error) {
 	if _, err := tx.ExecContext(ctx, `DELETE FROM article_tags WHERE article_id = ?`, articleID); err != nil {
 		return nil, err
@@ -790,11 +1183,16 @@ func (r *SQLiteRepository) replaceTagsTx(ctx context.Context, tx *sql.Tx, articl
 	}
 
 	out := make([]domain.Tag, 0, len(tagNames))
+	seen := make(map[string]bool, len(tagNames))
 	for _, name := range tagNames {
 		tag, err := upsertTagTx(ctx, tx, name, nowMs)
 		if err != nil {
 			return nil, err
 		}
+		if seen[tag.ID] {
+			continue
+		}
+		seen[tag.ID] = true
 		if _, err := tx.ExecContext(ctx, `INSERT INTO article_tags(article_id, tag_id) VALUES(?, ?)`, articleID, tag.ID); err != nil {
 			return nil, err
 		}
@@ -861,6 +1259,26 @@ func caseOrder(column string, ids []string) string {
 	return b.String()
 }
 
+// expectUpdated turns a compare-and-set UPDATE that matched no row into a
+// version conflict: another writer moved current_version first.
+func expectUpdated(res sql.Result, articleID string, expectedVersion int) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &domain.VersionConflictError{ArticleID: articleID, ExpectedVersion: expectedVersion}
+	}
+	return nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func isUniqueConstraintErr(err error) bool {
 	if err == nil {
 		return false
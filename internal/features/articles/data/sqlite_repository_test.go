@@ -5,11 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	_ "modernc.org/sqlite"
 
+	"github.com/Xiaoxinkeji/WX/internal/core/migrate"
 	"github.com/Xiaoxinkeji/WX/internal/features/articles/data"
 	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
 )
@@ -105,7 +109,7 @@ func TestSQLiteRepository_CRUD_Versions_Tags_Search(t *testing.T) {
 		t.Fatalf("unexpected search results after update: %+v", results)
 	}
 
-	restored, err := repo.RestoreVersion(ctx, "a1", 1, createdAt.Add(5*time.Minute))
+	restored, err := repo.RestoreVersion(ctx, "a1", domain.RestoreVersionParams{Version: 1, RestoredAt: createdAt.Add(5 * time.Minute)})
 	if err != nil {
 		t.Fatalf("restore: %v", err)
 	}
@@ -163,3 +167,1188 @@ func TestSQLiteRepository_ListArticles_ByTagAndStatus(t *testing.T) {
 		t.Fatalf("unexpected results: %+v", articles)
 	}
 }
+
+func TestSQLiteRepository_MetadataIsVersioned(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	created, err := repo.CreateArticle(ctx, domain.CreateArticleParams{
+		ID: "a1", Title: "t", Content: "c", Status: domain.ArticleStatusDraft,
+		Digest: "first", Author: "小新", CoverImage: "media-1", SourceURL: "https://example.com/post", OpenComments: true,
+		CreatedAt: now, UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Author != "小新" || created.CoverImage != "media-1" || created.SourceURL != "https://example.com/post" || !created.OpenComments {
+		t.Fatalf("unexpected created metadata: %+v", created)
+	}
+
+	digest, author, closed := "second", "编辑部", false
+	updated, err := repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{Digest: &digest, Author: &author, OpenComments: &closed, UpdatedAt: now.Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Digest != "second" || updated.Author != "编辑部" || updated.OpenComments || updated.CoverImage != "media-1" || updated.Content != "c" {
+		t.Fatalf("unexpected article after metadata update: %+v", updated)
+	}
+
+	v1, err := repo.GetVersion(ctx, "a1", 1)
+	if err != nil {
+		t.Fatalf("get version: %v", err)
+	}
+	if v1.Digest != "first" || v1.Author != "小新" || !v1.OpenComments || v1.SourceURL != "https://example.com/post" {
+		t.Fatalf("unexpected version 1 metadata: %+v", v1)
+	}
+	if _, err := repo.RestoreVersion(ctx, "a1", domain.RestoreVersionParams{Version: 1, RestoredAt: now.Add(2 * time.Minute)}); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	got, err := repo.GetArticle(ctx, "a1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Digest != "first" || got.Author != "小新" || !got.OpenComments {
+		t.Fatalf("expected restored metadata, got %+v", got)
+	}
+
+	badURL := "javascript:alert(1)"
+	if _, err := repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{SourceURL: &badURL}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for bad source url, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_AddsColumnsToExistingDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE articles (id TEXT PRIMARY KEY, title TEXT NOT NULL, content TEXT NOT NULL, status TEXT NOT NULL, created_at_ms INTEGER NOT NULL, updated_at_ms INTEGER NOT NULL, current_version INTEGER NOT NULL);
+CREATE TABLE article_versions (article_id TEXT NOT NULL, version INTEGER NOT NULL, title TEXT NOT NULL, content TEXT NOT NULL, status TEXT NOT NULL, tags_csv TEXT NOT NULL, created_at_ms INTEGER NOT NULL, is_autosave INTEGER NOT NULL, PRIMARY KEY(article_id, version));
+INSERT INTO articles VALUES('old', 'Old', 'body', 'draft', 1, 1, 1);
+INSERT INTO article_versions VALUES('old', 1, 'Old', 'body', 'draft', '', 1, 0);
+`); err != nil {
+		t.Fatalf("seed legacy schema: %v", err)
+	}
+
+	repo, err := data.NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	got, err := repo.GetArticle(ctx, "old")
+	if err != nil {
+		t.Fatalf("get legacy article: %v", err)
+	}
+	if got.Title != "Old" || got.Digest != "" {
+		t.Fatalf("unexpected legacy article: %+v", got)
+	}
+	if _, err := repo.GetVersion(ctx, "old", 1); err != nil {
+		t.Fatalf("get legacy version: %v", err)
+	}
+}
+
+func TestSQLiteRepository_MigratesVersionOneDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if err := data.Migrations()[0].Up(ctx, tx); err != nil {
+		t.Fatalf("apply v1 schema: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := migrate.Apply(ctx, db, data.MigrationFeature, data.Migrations()[:1]); err != nil {
+		t.Fatalf("record v1: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `
+INSERT INTO articles VALUES('old', 'Old', 'body', 'draft', 1, 1, 1);
+INSERT INTO article_versions VALUES('old', 1, 'Old', 'body', 'draft', '', 1, 0);
+`); err != nil {
+		t.Fatalf("seed v1 rows: %v", err)
+	}
+
+	repo, err := data.NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	if v, err := migrate.Version(ctx, db, data.MigrationFeature); err != nil || v != len(data.Migrations()) {
+		t.Fatalf("expected latest version, got %d (%v)", v, err)
+	}
+	author := "Ann"
+	updated, err := repo.UpdateArticle(ctx, "old", domain.UpdateArticleParams{Author: &author})
+	if err != nil {
+		t.Fatalf("update migrated article: %v", err)
+	}
+	if updated.Title != "Old" || updated.Author != "Ann" || updated.CurrentVersion != 2 {
+		t.Fatalf("unexpected migrated article: %+v", updated)
+	}
+
+	if _, err := db.ExecContext(ctx, `INSERT INTO schema_migrations(feature, version, name, applied_at_ms) VALUES(?, 99, 'future', 0)`, data.MigrationFeature); err != nil {
+		t.Fatalf("seed future version: %v", err)
+	}
+	if _, err := data.NewSQLiteRepository(db); !errors.Is(err, migrate.ErrDatabaseTooNew) {
+		t.Fatalf("expected ErrDatabaseTooNew, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_SearchSegmentsChinese(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	for _, p := range []domain.CreateArticleParams{
+		{ID: "a1", Title: "公众号运营笔记", Content: "内容增长的关键在于持续输出高质量文章。", Tags: []string{"运营"}},
+		{ID: "a2", Title: "Go语言入门", Content: "从零开始学习Go语言并发编程。"},
+		{ID: "a3", Title: "旅行日记", Content: "今天去了海边。"},
+	} {
+		p.Status = domain.ArticleStatusDraft
+		if _, err := repo.CreateArticle(ctx, p); err != nil {
+			t.Fatalf("create %s: %v", p.ID, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"增长", []string{"a1"}},
+		{"高质量文章", []string{"a1"}},
+		{"运营", []string{"a1"}},
+		{"go语言", []string{"a2"}},
+		{"语言 并发", []string{"a2"}},
+		{"海", []string{"a3"}},
+		{"边", []string{"a3"}},
+		{"章", []string{"a1"}},
+		{"增长 海边", nil},
+		{"长增", nil},
+	} {
+		got, err := repo.SearchArticles(ctx, domain.SearchArticlesQuery{Query: tc.query})
+		if err != nil {
+			t.Fatalf("search %q: %v", tc.query, err)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("search %q: expected %v, got %+v", tc.query, tc.want, got)
+		}
+		for i := range got {
+			if got[i].ID != tc.want[i] {
+				t.Fatalf("search %q: expected %v, got %+v", tc.query, tc.want, got)
+			}
+		}
+	}
+}
+
+func TestSQLiteRepository_SearchQuerySyntax(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, p := range []domain.CreateArticleParams{
+		{ID: "a1", Title: "用户增长实战", Content: "拉新和留存的精确短语方法。", Tags: []string{"运营"}},
+		{ID: "a2", Title: "流量复盘", Content: "本周增长来自广告投放。", Tags: []string{"运营"}},
+		{ID: "a3", Title: "技术周报", Content: "增长团队的流量分析工具。", Tags: []string{"技术"}, Status: domain.ArticleStatusPublished},
+		{ID: "a4", Title: "Traffic notes", Content: "Traffic grew after the newsletter.", Tags: []string{"english"}},
+	} {
+		if p.Status == "" {
+			p.Status = domain.ArticleStatusDraft
+		}
+		p.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		p.UpdatedAt = p.CreatedAt
+		if _, err := repo.CreateArticle(ctx, p); err != nil {
+			t.Fatalf("create %s: %v", p.ID, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{`title:增长`, []string{"a1"}},
+		{`增长 tag:运营 -广告`, []string{"a1"}},
+		{`增长 -tag:运营`, []string{"a3"}},
+		{`"精确短语"`, []string{"a1"}},
+		{`content:流量`, []string{"a3"}},
+		{`traf*`, []string{"a4"}},
+		{`复盘 OR 周报`, []string{"a2", "a3"}},
+		{`增长 NOT (广告 OR 工具)`, []string{"a1"}},
+		{`tag:运营`, []string{"a2", "a1"}},
+		{`增长 status:published`, []string{"a3"}},
+	} {
+		got, err := repo.SearchArticles(ctx, domain.SearchArticlesQuery{Query: tc.query})
+		if err != nil {
+			t.Fatalf("search %q: %v", tc.query, err)
+		}
+		ids := make([]string, 0, len(got))
+		for _, a := range got {
+			ids = append(ids, a.ID)
+		}
+		if !sameIDs(ids, tc.want, tc.query == `tag:运营`) {
+			t.Fatalf("search %q: expected %v, got %v", tc.query, tc.want, ids)
+		}
+	}
+
+	if _, err := repo.SearchArticles(ctx, domain.SearchArticlesQuery{Query: `title:(增长`}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_SearchArticleResults(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	long := strings.Repeat("无关的铺垫文字。", 40) + "这里讲用户增长的方法，增长需要耐心。" + strings.Repeat("结尾的补充说明。", 40)
+	for _, p := range []domain.CreateArticleParams{
+		{ID: "a1", Title: "增长笔记", Content: long},
+		{ID: "a2", Title: "随笔", Content: "偶尔提到增长。"},
+	} {
+		p.Status = domain.ArticleStatusDraft
+		if _, err := repo.CreateArticle(ctx, p); err != nil {
+			t.Fatalf("create %s: %v", p.ID, err)
+		}
+	}
+
+	got, err := repo.SearchArticleResults(ctx, domain.SearchResultsQuery{
+		SearchArticlesQuery: domain.SearchArticlesQuery{Query: "增长 -广告"},
+		SnippetRunes:        40,
+	})
+	if err != nil {
+		t.Fatalf("search results: %v", err)
+	}
+	if len(got) != 2 || got[0].Score < got[1].Score || got[1].Score <= 0 {
+		t.Fatalf("unexpected results: %+v", got)
+	}
+	byID := map[string]domain.SearchResult{got[0].Article.ID: got[0], got[1].Article.ID: got[1]}
+	first, second := byID["a1"], byID["a2"]
+	if first.Article.Content != "" {
+		t.Fatalf("expected content to be omitted")
+	}
+	if first.HighlightedTitle != "<mark>增长</mark>笔记" || !reflect.DeepEqual(first.TitleMatches, []domain.MatchRange{{Start: 0, End: 2}}) {
+		t.Fatalf("unexpected title highlight: %q %+v", first.HighlightedTitle, first.TitleMatches)
+	}
+	if !strings.HasPrefix(first.Snippet, "…") || !strings.HasSuffix(first.Snippet, "…") || utf8.RuneCountInString(first.Snippet) != 42 {
+		t.Fatalf("unexpected snippet: %q", first.Snippet)
+	}
+	if len(first.SnippetMatches) != 2 {
+		t.Fatalf("expected two snippet matches, got %+v in %q", first.SnippetMatches, first.Snippet)
+	}
+	runes := []rune(first.Snippet)
+	for _, m := range first.SnippetMatches {
+		if string(runes[m.Start:m.End]) != "增长" {
+			t.Fatalf("match %+v points at %q", m, string(runes[m.Start:m.End]))
+		}
+	}
+	if second.Snippet != "偶尔提到增长。" || !reflect.DeepEqual(second.SnippetMatches, []domain.MatchRange{{Start: 4, End: 6}}) {
+		t.Fatalf("unexpected short snippet: %q %+v", second.Snippet, second.SnippetMatches)
+	}
+
+	got, err = repo.SearchArticleResults(ctx, domain.SearchResultsQuery{
+		SearchArticlesQuery: domain.SearchArticlesQuery{Query: "content:增长"},
+		HighlightOpen:       "[",
+		HighlightClose:      "]",
+	})
+	if err != nil || len(got) != 2 {
+		t.Fatalf("search content field: %+v (%v)", got, err)
+	}
+	for _, res := range got {
+		if res.HighlightedTitle != res.Article.Title || res.TitleMatches != nil {
+			t.Fatalf("content-scoped terms must not highlight the title: %+v", res)
+		}
+		if res.Article.ID == "a2" && res.Snippet != "偶尔提到增长。" {
+			t.Fatalf("unexpected snippet: %q", res.Snippet)
+		}
+	}
+
+	// The title around the markers is always escaped.
+	if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{
+		ID: "a3", Title: `<b>"增长" & 留存</b>`, Content: strings.Repeat("铺", 30000) + "增长",
+		Status: domain.ArticleStatusDraft,
+	}); err != nil {
+		t.Fatalf("create a3: %v", err)
+	}
+	got, err = repo.SearchArticleResults(ctx, domain.SearchResultsQuery{
+		SearchArticlesQuery: domain.SearchArticlesQuery{Query: "留存"},
+		SnippetRunes:        10,
+	})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("search escaped title: %+v (%v)", got, err)
+	}
+	if want := "&lt;b&gt;&#34;增长&#34; &amp; <mark>留存</mark>&lt;/b&gt;"; got[0].HighlightedTitle != want {
+		t.Fatalf("unexpected escaped title: %q, want %q", got[0].HighlightedTitle, want)
+	}
+	// A match past the scanned prefix leaves the snippet at the start.
+	if got[0].Snippet != strings.Repeat("铺", 10)+"…" || got[0].SnippetMatches != nil {
+		t.Fatalf("unexpected snippet: %q %+v", got[0].Snippet, got[0].SnippetMatches)
+	}
+
+	got, err = repo.SearchArticleResults(ctx, domain.SearchResultsQuery{
+		SearchArticlesQuery: domain.SearchArticlesQuery{Query: "留存"},
+		HighlightOpen:       "<em>",
+		HighlightClose:      "</em>",
+	})
+	if want := "&lt;b&gt;&#34;增长&#34; &amp; <em>留存</em>&lt;/b&gt;"; err != nil || len(got) != 1 || got[0].HighlightedTitle != want {
+		t.Fatalf("custom markers must not skip escaping: %+v (%v)", got, err)
+	}
+	if _, err := repo.SearchArticleResults(ctx, domain.SearchResultsQuery{
+		SearchArticlesQuery: domain.SearchArticlesQuery{Query: "留存"},
+		HighlightOpen:       "<em>",
+	}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for an unbalanced marker, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_ListArticlesSortFilterAndCursor(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, p := range []domain.CreateArticleParams{
+		{ID: "a1", Title: "Banana", Content: "一二三", Tags: []string{"go", "sql"}},
+		{ID: "a2", Title: "apple", Content: "one two three four five", Tags: []string{"go"}},
+		{ID: "a3", Title: "Cherry", Content: "中文 and English", Tags: []string{"sql"}},
+		{ID: "a4", Title: "Date", Content: "", Status: domain.ArticleStatusPublished},
+		{ID: "a5", Title: "Elder", Content: "x"},
+	} {
+		if p.Status == "" {
+			p.Status = domain.ArticleStatusDraft
+		}
+		if p.Status == domain.ArticleStatusPublished {
+			p.Content = "published body"
+		}
+		p.CreatedAt = base.Add(time.Duration(i) * 24 * time.Hour)
+		p.UpdatedAt = p.CreatedAt
+		if _, err := repo.CreateArticle(ctx, p); err != nil {
+			t.Fatalf("create %s: %v", p.ID, err)
+		}
+	}
+
+	list := func(q domain.ListArticlesQuery) []string {
+		t.Helper()
+		got, err := repo.ListArticles(ctx, q)
+		if err != nil {
+			t.Fatalf("list %+v: %v", q, err)
+		}
+		ids := make([]string, 0, len(got))
+		for _, a := range got {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+	for _, tc := range []struct {
+		name  string
+		query domain.ListArticlesQuery
+		want  []string
+	}{
+		{"default", domain.ListArticlesQuery{}, []string{"a5", "a4", "a3", "a2", "a1"}},
+		{"title asc", domain.ListArticlesQuery{Sort: domain.ArticleSortTitle, Ascending: true}, []string{"a1", "a3", "a4", "a5", "a2"}},
+		{"word count desc", domain.ListArticlesQuery{Sort: domain.ArticleSortWordCount}, []string{"a2", "a3", "a1", "a4", "a5"}},
+		{"created range", domain.ListArticlesQuery{CreatedFrom: base.Add(24 * time.Hour), CreatedTo: base.Add(72 * time.Hour), Sort: domain.ArticleSortCreatedAt, Ascending: true}, []string{"a2", "a3"}},
+		{"tags all", domain.ListArticlesQuery{Tags: []string{"go", "sql"}}, []string{"a1"}},
+		{"tags any", domain.ListArticlesQuery{Tags: []string{"go", "sql"}, TagMatch: domain.TagMatchAny}, []string{"a3", "a2", "a1"}},
+		{"statuses", domain.ListArticlesQuery{Statuses: []domain.ArticleStatus{domain.ArticleStatusPublished}}, []string{"a4"}},
+	} {
+		if got := list(tc.query); !sameIDs(got, tc.want, true) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+
+	got, err := repo.GetArticle(ctx, "a3")
+	if err != nil || got.WordCount != 4 {
+		t.Fatalf("expected word count 4, got %d (%v)", got.WordCount, err)
+	}
+
+	t.Run("cursor is stable under autosave", func(t *testing.T) {
+		pageAll := func(query domain.ListArticlesQuery, between func()) []string {
+			t.Helper()
+			page, err := repo.ListArticlesPage(ctx, query)
+			if err != nil || len(page.Articles) != query.Limit || page.NextCursor == "" {
+				t.Fatalf("first page: %+v (%v)", page, err)
+			}
+			var seen []string
+			for _, a := range page.Articles {
+				seen = append(seen, a.ID)
+			}
+			between()
+			for page.NextCursor != "" {
+				query.Cursor = page.NextCursor
+				page, err = repo.ListArticlesPage(ctx, query)
+				if err != nil {
+					t.Fatalf("next page: %v", err)
+				}
+				for _, a := range page.Articles {
+					seen = append(seen, a.ID)
+				}
+			}
+			return seen
+		}
+		autosave := func(id string, days int) {
+			content := "一二三四"
+			if _, err := repo.UpdateArticle(ctx, id, domain.UpdateArticleParams{Content: &content, IsAutoSave: true, UpdatedAt: base.Add(time.Duration(days) * 24 * time.Hour)}); err != nil {
+				t.Fatalf("autosave %s: %v", id, err)
+			}
+		}
+
+		// An autosave moves a1, not yet listed, to the top; it must still be
+		// listed once where it stood.
+		seen := pageAll(domain.ListArticlesQuery{Limit: 2}, func() { autosave("a1", 100) })
+		if want := []string{"a5", "a4", "a3", "a2", "a1"}; !sameIDs(seen, want, true) {
+			t.Fatalf("expected %v across pages, got %v", want, seen)
+		}
+		// An autosave moves a2, already listed, to the end of an ascending
+		// listing; it must not be listed again. Articles created meanwhile
+		// wait for the next listing.
+		seen = pageAll(domain.ListArticlesQuery{Limit: 2, Ascending: true}, func() {
+			autosave("a2", 101)
+			if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{ID: "a6", Title: "Fig", Status: domain.ArticleStatusDraft, CreatedAt: base.Add(102 * 24 * time.Hour), UpdatedAt: base.Add(102 * 24 * time.Hour)}); err != nil {
+				t.Fatalf("create a6: %v", err)
+			}
+		})
+		if want := []string{"a2", "a3", "a4", "a5", "a1"}; !sameIDs(seen, want, true) {
+			t.Fatalf("expected %v across ascending pages, got %v", want, seen)
+		}
+		if err := repo.DeleteArticle(ctx, "a6"); err != nil {
+			t.Fatalf("delete a6: %v", err)
+		}
+	})
+
+	if _, err := repo.ListArticlesPage(ctx, domain.ListArticlesQuery{Cursor: "not a cursor"}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for bad cursor, got %v", err)
+	}
+	first, err := repo.ListArticlesPage(ctx, domain.ListArticlesQuery{Limit: 1})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if _, err := repo.ListArticlesPage(ctx, domain.ListArticlesQuery{Cursor: first.NextCursor, Sort: domain.ArticleSortTitle}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for cursor with another sort, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_TrashRestoreAndPurge(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	for _, id := range []string{"a1", "a2"} {
+		if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{ID: id, Title: "标题 " + id, Content: "回收站测试", Status: domain.ArticleStatusDraft, Tags: []string{"trash"}}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	if err := repo.DeleteArticle(ctx, "a1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := repo.DeleteArticle(ctx, "a1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+	if _, err := repo.GetArticle(ctx, "a1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected trashed article to be hidden, got %v", err)
+	}
+	title := "changed"
+	if _, err := repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{Title: &title}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound updating trashed article, got %v", err)
+	}
+	for _, q := range []string{"回收站", "tag:trash"} {
+		got, err := repo.SearchArticles(ctx, domain.SearchArticlesQuery{Query: q})
+		if err != nil || len(got) != 1 || got[0].ID != "a2" {
+			t.Fatalf("search %q: expected only a2, got %+v (%v)", q, got, err)
+		}
+	}
+	if got, err := repo.ListArticles(ctx, domain.ListArticlesQuery{}); err != nil || len(got) != 1 {
+		t.Fatalf("expected one listed article, got %+v (%v)", got, err)
+	}
+	trash, err := repo.ListTrash(ctx, domain.ListTrashQuery{})
+	if err != nil || len(trash) != 1 || trash[0].ID != "a1" || trash[0].DeletedAt.IsZero() {
+		t.Fatalf("unexpected trash: %+v (%v)", trash, err)
+	}
+
+	restored, err := repo.RestoreArticle(ctx, "a1")
+	if err != nil || !restored.DeletedAt.IsZero() || len(restored.Tags) != 1 {
+		t.Fatalf("restore: %+v (%v)", restored, err)
+	}
+	if _, err := repo.RestoreArticle(ctx, "a1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound restoring a live article, got %v", err)
+	}
+	if got, err := repo.SearchArticles(ctx, domain.SearchArticlesQuery{Query: "回收站"}); err != nil || len(got) != 2 {
+		t.Fatalf("expected restored article to be searchable, got %+v (%v)", got, err)
+	}
+
+	if err := repo.DeleteArticle(ctx, "a1"); err != nil {
+		t.Fatalf("delete again: %v", err)
+	}
+	if n, err := repo.PurgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("expected nothing old enough to purge, got %d (%v)", n, err)
+	}
+	if n, err := repo.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("expected one purged article, got %d (%v)", n, err)
+	}
+	if _, err := repo.GetVersion(ctx, "a1", 1); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected purged versions to be gone, got %v", err)
+	}
+	if _, err := repo.RestoreArticle(ctx, "a1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected purged article to be gone, got %v", err)
+	}
+	if got, err := repo.GetArticle(ctx, "a2"); err != nil || len(got.Tags) != 1 {
+		t.Fatalf("purge must not touch live articles: %+v (%v)", got, err)
+	}
+}
+
+func TestSQLiteRepository_CompactVersions(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	base := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{ID: "a1", Title: "t", Content: "v1", Status: domain.ArticleStatusDraft, CreatedAt: base, UpdatedAt: base}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	// Versions 2-31: an autosave every minute for half an hour, then a
+	// manual save as version 32.
+	for i := 1; i <= 30; i++ {
+		content := fmt.Sprintf("draft %d", i)
+		if _, err := repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{Content: &content, IsAutoSave: true, UpdatedAt: base.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatalf("autosave %d: %v", i, err)
+		}
+	}
+	final := "final"
+	if _, err := repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{Content: &final, UpdatedAt: base.Add(31 * time.Minute)}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	params := domain.CompactVersionsParams{
+		Retention: domain.VersionRetention{RecentWindow: time.Hour, RecentInterval: 10 * time.Minute},
+		Now:       base.Add(40 * time.Minute),
+	}
+	res, err := repo.CompactVersions(ctx, params)
+	if err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	// Autosaves at minutes 9, 19, 29 and 30 close their ten-minute buckets.
+	if res.Articles != 1 || res.Removed != 26 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	versions, err := repo.ListVersions(ctx, domain.ListVersionsQuery{ArticleID: "a1"})
+	if err != nil {
+		t.Fatalf("list versions: %v", err)
+	}
+	var got []int
+	for _, v := range versions {
+		got = append(got, v.Version)
+	}
+	if !reflect.DeepEqual(got, []int{32, 31, 30, 20, 10, 1}) {
+		t.Fatalf("unexpected surviving versions: %v", got)
+	}
+	if v, err := repo.GetVersion(ctx, "a1", 30); err != nil || v.Content != "draft 29" {
+		t.Fatalf("get surviving version: %+v (%v)", v, err)
+	}
+
+	// A day later the autosaves collapse to one, and compaction is idempotent.
+	params.Now = base.Add(25 * time.Hour)
+	if res, err := repo.CompactVersions(ctx, params); err != nil || res.Removed != 3 {
+		t.Fatalf("second compact: %+v (%v)", res, err)
+	}
+	if res, err := repo.CompactVersions(ctx, params); err != nil || res.Removed != 0 || res.Articles != 0 {
+		t.Fatalf("third compact: %+v (%v)", res, err)
+	}
+	if a, err := repo.GetArticle(ctx, "a1"); err != nil || a.CurrentVersion != 32 || a.Content != "final" {
+		t.Fatalf("compaction must not touch the article: %+v (%v)", a, err)
+	}
+
+	params.Retention.MaxVersions = -1
+	if _, err := repo.CompactVersions(ctx, params); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_WorkflowTransitionsAndHistory(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	w := domain.Workflow{RequireReview: true}
+	base := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{ID: "a1", Title: "标题", Content: "正文", Status: domain.ArticleStatusPublished, Workflow: w}); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition creating a published article, got %v", err)
+	}
+	if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{ID: "a1", Title: "标题", Content: "正文", Status: domain.ArticleStatusDraft, CreatedAt: base, Workflow: w}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	move := func(to domain.ArticleStatus, note string, minute int) (domain.Article, error) {
+		return repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{Status: &to, StatusNote: note, Workflow: w, UpdatedAt: base.Add(time.Duration(minute) * time.Minute)})
+	}
+	_, err = move(domain.ArticleStatusPublished, "", 1)
+	var te *domain.TransitionError
+	if !errors.As(err, &te) || te.From != domain.ArticleStatusDraft || !te.ReviewRequired {
+		t.Fatalf("expected *TransitionError, got %v", err)
+	}
+	for i, step := range []struct {
+		to   domain.ArticleStatus
+		note string
+	}{
+		{domain.ArticleStatusInReview, ""},
+		{domain.ArticleStatusDraft, "第二段需要改"},
+		{domain.ArticleStatusInReview, ""},
+		{domain.ArticleStatusApproved, "通过"},
+		{domain.ArticleStatusPublished, ""},
+	} {
+		if _, err := move(step.to, step.note, i+2); err != nil {
+			t.Fatalf("move to %s: %v", step.to, err)
+		}
+	}
+	// Edits that keep the status are not history entries.
+	title := "新标题"
+	if _, err := repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{Title: &title, Workflow: w}); err != nil {
+		t.Fatalf("update title: %v", err)
+	}
+	if _, err := move(domain.ArticleStatusInReview, "", 10); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	history, err := repo.ListStatusHistory(ctx, "a1")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	var got []string
+	for _, c := range history {
+		got = append(got, fmt.Sprintf("%d:%s>%s:%s", c.Version, c.From, c.To, c.Note))
+	}
+	want := []string{
+		"1:>draft:",
+		"2:draft>in_review:",
+		"3:in_review>draft:第二段需要改",
+		"4:draft>in_review:",
+		"5:in_review>approved:通过",
+		"6:approved>published:",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected history:\n%v\nwant:\n%v", got, want)
+	}
+	if !history[0].ChangedAt.Equal(base) || !history[5].ChangedAt.Equal(base.Add(6*time.Minute)) {
+		t.Fatalf("unexpected timestamps: %v, %v", history[0].ChangedAt, history[5].ChangedAt)
+	}
+
+	// Restoring a draft version of a published article takes it back to draft.
+	if _, err := repo.RestoreVersion(ctx, "a1", domain.RestoreVersionParams{Version: 1, Workflow: w}); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if history, err = repo.ListStatusHistory(ctx, "a1"); err != nil || len(history) != 7 || history[6].Note != "restored version 1" {
+		t.Fatalf("unexpected history after restore: %+v (%v)", history, err)
+	}
+	if _, err := repo.ListStatusHistory(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_ScheduledPublish(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo, err := data.NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	base := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a1", "a2", "a3"} {
+		if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{ID: id, Title: "标题", Content: "正文", Status: domain.ArticleStatusDraft, CreatedAt: base}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	schedule := func(id string, at time.Time) domain.ScheduledJob {
+		t.Helper()
+		job, err := repo.SchedulePublish(ctx, domain.SchedulePublishParams{ArticleID: id, At: at, Now: base})
+		if err != nil {
+			t.Fatalf("schedule %s: %v", id, err)
+		}
+		return job
+	}
+	status := func(id string) domain.ArticleStatus {
+		t.Helper()
+		a, err := repo.GetArticle(ctx, id)
+		if err != nil {
+			t.Fatalf("get %s: %v", id, err)
+		}
+		return a.Status
+	}
+
+	job := schedule("a1", base.Add(time.Hour))
+	if status("a1") != domain.ArticleStatusScheduled || job.State != domain.JobStatePending || job.FromStatus != domain.ArticleStatusDraft {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	if moved := schedule("a1", base.Add(2*time.Hour)); moved.ID != job.ID || !moved.RunAt.Equal(base.Add(2*time.Hour)) {
+		t.Fatalf("expected the job to be rescheduled, got %+v", moved)
+	}
+	if due, err := repo.DueJobs(ctx, base.Add(time.Hour), 10); err != nil || len(due) != 0 {
+		t.Fatalf("expected nothing due, got %+v (%v)", due, err)
+	}
+
+	// Jobs are rows, so a repository opened later still finds them.
+	reopened, err := data.NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	due, err := reopened.DueJobs(ctx, base.Add(3*time.Hour), 10)
+	if err != nil || len(due) != 1 || due[0].ID != job.ID {
+		t.Fatalf("expected the job to be due, got %+v (%v)", due, err)
+	}
+	if err := reopened.RunJob(ctx, job.ID, base.Add(3*time.Hour)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if status("a1") != domain.ArticleStatusPublished {
+		t.Fatalf("expected a1 published")
+	}
+	if err := reopened.RunJob(ctx, job.ID, base.Add(3*time.Hour)); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound running a done job, got %v", err)
+	}
+	history, err := repo.ListStatusHistory(ctx, "a1")
+	if err != nil || len(history) != 3 || history[2].Note != "published on schedule" || !history[2].ChangedAt.Equal(base.Add(3*time.Hour)) {
+		t.Fatalf("unexpected history: %+v (%v)", history, err)
+	}
+
+	schedule("a2", base.Add(time.Hour))
+	if err := repo.CancelScheduledPublish(ctx, "a2", base); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if status("a2") != domain.ArticleStatusDraft {
+		t.Fatalf("expected cancel to restore draft")
+	}
+	if err := repo.CancelScheduledPublish(ctx, "a2", base); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound cancelling twice, got %v", err)
+	}
+
+	// Moving a scheduled article out of scheduled by hand drops its job.
+	schedule("a3", base.Add(time.Hour))
+	content := "自动保存"
+	if _, err := repo.UpdateArticle(ctx, "a3", domain.UpdateArticleParams{Content: &content, Status: ptrStatus(domain.ArticleStatusDraft), IsAutoSave: true}); err != nil {
+		t.Fatalf("autosave: %v", err)
+	}
+	jobs, err := repo.ListJobs(ctx, domain.ListJobsQuery{ArticleID: "a3"})
+	if err != nil || len(jobs) != 1 || jobs[0].State != domain.JobStateCancelled {
+		t.Fatalf("expected cancelled job, got %+v (%v)", jobs, err)
+	}
+	if pending, err := repo.ListJobs(ctx, domain.ListJobsQuery{States: []domain.JobState{domain.JobStatePending}}); err != nil || len(pending) != 0 {
+		t.Fatalf("expected no pending jobs, got %+v (%v)", pending, err)
+	}
+
+	// A failed attempt moves the job; a final one fails it for good.
+	job = schedule("a3", base.Add(time.Hour))
+	if err := repo.RecordJobFailure(ctx, domain.JobFailureParams{JobID: job.ID, Err: "busy", At: base.Add(time.Hour), RetryAt: base.Add(2 * time.Hour)}); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	if err := repo.RecordJobFailure(ctx, domain.JobFailureParams{JobID: job.ID, Err: "still busy", At: base.Add(2 * time.Hour)}); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	jobs, err = repo.ListJobs(ctx, domain.ListJobsQuery{ArticleID: "a3", States: []domain.JobState{domain.JobStateFailed}})
+	if err != nil || len(jobs) != 1 || jobs[0].Attempts != 2 || jobs[0].LastError != "still busy" {
+		t.Fatalf("unexpected failed job: %+v (%v)", jobs, err)
+	}
+
+	// Trashing cancels the schedule, so nothing falls due for the trashed
+	// article and restoring it brings it back as it was before scheduling.
+	if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{ID: "a4", Title: "标题", Content: "正文", Status: domain.ArticleStatusDraft, CreatedAt: base}); err != nil {
+		t.Fatalf("create a4: %v", err)
+	}
+	schedule("a4", base.Add(time.Hour))
+	if err := repo.DeleteArticle(ctx, "a4"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if due, err := repo.DueJobs(ctx, base.Add(3*time.Hour), 10); err != nil || len(due) != 0 {
+		t.Fatalf("expected nothing due for the trashed article, got %+v (%v)", due, err)
+	}
+	jobs, err = repo.ListJobs(ctx, domain.ListJobsQuery{ArticleID: "a4"})
+	if err != nil || len(jobs) != 1 || jobs[0].State != domain.JobStateCancelled {
+		t.Fatalf("expected the job cancelled, got %+v (%v)", jobs, err)
+	}
+	if _, err := repo.RestoreArticle(ctx, "a4"); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if status("a4") != domain.ArticleStatusDraft {
+		t.Fatalf("expected the restored article back in draft, got %s", status("a4"))
+	}
+
+	if _, err := repo.SchedulePublish(ctx, domain.SchedulePublishParams{ArticleID: "missing", At: base}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func ptrStatus(s domain.ArticleStatus) *domain.ArticleStatus { return &s }
+
+func TestSQLiteRepository_TagManagement(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	base := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	for i, p := range []domain.CreateArticleParams{
+		{ID: "a1", Tags: []string{"增长", "go"}},
+		{ID: "a2", Tags: []string{"用户增长", "增长"}},
+		{ID: "a3", Tags: []string{"增长"}},
+		{ID: "a4", Tags: []string{"临时"}},
+	} {
+		p.Title, p.Content, p.Status = "标题 "+p.ID, "正文", domain.ArticleStatusDraft
+		p.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		p.UpdatedAt = p.CreatedAt
+		if _, err := repo.CreateArticle(ctx, p); err != nil {
+			t.Fatalf("create %s: %v", p.ID, err)
+		}
+	}
+	if err := repo.DeleteArticle(ctx, "a3"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	search := func(q string) []string {
+		t.Helper()
+		got, err := repo.SearchArticles(ctx, domain.SearchArticlesQuery{Query: q})
+		if err != nil {
+			t.Fatalf("search %q: %v", q, err)
+		}
+		ids := make([]string, 0, len(got))
+		for _, a := range got {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+
+	usage, err := repo.ListTagUsage(ctx, domain.TagUsageSortCount)
+	if err != nil || len(usage) != 4 {
+		t.Fatalf("usage: %+v (%v)", usage, err)
+	}
+	if u := usage[0]; u.Name != "增长" || u.ArticleCount != 2 || u.TrashedCount != 1 || !u.LastUsedAt.Equal(base.Add(time.Hour)) {
+		t.Fatalf("unexpected top tag: %+v", u)
+	}
+
+	if _, err := repo.RenameTag(ctx, domain.RenameTagParams{From: "go", To: "增长"}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict renaming onto an existing tag, got %v", err)
+	}
+	if tag, err := repo.RenameTag(ctx, domain.RenameTagParams{From: " Go ", To: " Golang "}); err != nil || tag.Name != "golang" {
+		t.Fatalf("rename: %+v (%v)", tag, err)
+	}
+	if got := search("golang"); !sameIDs(got, []string{"a1"}, false) {
+		t.Fatalf("expected the renamed tag to be searchable, got %v", got)
+	}
+
+	if _, err := repo.MergeTags(ctx, domain.MergeTagsParams{Sources: []string{"增长", "missing"}, Target: "用户增长"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound merging a missing tag, got %v", err)
+	}
+	into, err := repo.MergeTags(ctx, domain.MergeTagsParams{Sources: []string{"增长"}, Target: "用户增长"})
+	if err != nil || into.Name != "用户增长" {
+		t.Fatalf("merge: %+v (%v)", into, err)
+	}
+	if got := search("tag:用户增长"); !sameIDs(got, []string{"a1", "a2"}, false) {
+		t.Fatalf("unexpected articles for the merged tag: %v", got)
+	}
+	if got := search("tag:增长"); len(got) != 0 {
+		t.Fatalf("the merged-away tag must be gone, got %v", got)
+	}
+	a2, err := repo.GetArticle(ctx, "a2")
+	if err != nil || len(a2.Tags) != 1 {
+		t.Fatalf("expected a2 to carry the merged tag once: %+v (%v)", a2.Tags, err)
+	}
+
+	// New versions and restores use the surviving names.
+	title := "改标题"
+	if _, err := repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{Title: &title}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if v, err := repo.GetVersion(ctx, "a1", 2); err != nil || !reflect.DeepEqual(v.Tags, []string{"golang", "用户增长"}) {
+		t.Fatalf("unexpected version tags: %+v (%v)", v.Tags, err)
+	}
+	restored, err := repo.RestoreVersion(ctx, "a1", domain.RestoreVersionParams{Version: 1})
+	if err != nil {
+		t.Fatalf("restore version: %v", err)
+	}
+	// Renames and merges only remap current tags; an old version brings its
+	// own names back as ordinary tags.
+	if got := tagNames(restored.Tags); !reflect.DeepEqual(got, []string{"增长", "go"}) {
+		t.Fatalf("expected the version's own tag names, got %v", got)
+	}
+	if v, err := repo.GetVersion(ctx, "a1", 3); err != nil || !reflect.DeepEqual(v.Tags, []string{"增长", "go"}) {
+		t.Fatalf("unexpected restored version tags: %+v (%v)", v.Tags, err)
+	}
+	if got := search("tag:增长"); !sameIDs(got, []string{"a1"}, false) {
+		t.Fatalf("expected the old name to be a tag again, got %v", got)
+	}
+	if got, err := repo.ListArticles(ctx, domain.ListArticlesQuery{Tags: []string{"增长"}}); err != nil || len(got) != 1 || got[0].ID != "a1" {
+		t.Fatalf("expected the tag filter to agree with search: %+v (%v)", got, err)
+	}
+	if _, err := repo.RestoreArticle(ctx, "a3"); err != nil {
+		t.Fatalf("restore article: %v", err)
+	}
+	if got := search("tag:用户增长"); !sameIDs(got, []string{"a2", "a3"}, false) {
+		t.Fatalf("expected the restored article under the merged tag, got %v", got)
+	}
+
+	empty := []string{}
+	if _, err := repo.UpdateArticle(ctx, "a4", domain.UpdateArticleParams{Tags: &empty}); err != nil {
+		t.Fatalf("untag: %v", err)
+	}
+	if n, err := repo.DeleteUnusedTags(ctx); err != nil || n != 2 {
+		t.Fatalf("expected two unused tags deleted, got %d (%v)", n, err)
+	}
+	if usage, err := repo.ListTagUsage(ctx, domain.TagUsageSortName); err != nil || len(usage) != 3 {
+		t.Fatalf("unexpected tags left: %+v (%v)", usage, err)
+	}
+}
+
+func tagNames(tags []domain.Tag) []string {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, t.Name)
+	}
+	return out
+}
+
+func TestSQLiteRepository_Collections(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	base := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	for i, id := range []string{"a1", "a2", "a3", "a4"} {
+		at := base.Add(time.Duration(i) * time.Hour)
+		if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{
+			ID: id, Title: "标题 " + id, Content: "正文", Status: domain.ArticleStatusDraft, CreatedAt: at, UpdatedAt: at,
+		}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	listed := func(q domain.ListArticlesQuery) []string {
+		t.Helper()
+		got, err := repo.ListArticles(ctx, q)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		ids := make([]string, 0, len(got))
+		for _, a := range got {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+
+	if _, err := repo.CreateCollection(ctx, domain.CreateCollectionParams{ID: "c1", Title: "合集", ArticleIDs: []string{"a1", "missing"}}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing article, got %v", err)
+	}
+	c, err := repo.CreateCollection(ctx, domain.CreateCollectionParams{ID: "c1", Title: "合集", ArticleIDs: []string{"a3", "a1"}, CreatedAt: base})
+	if err != nil || !reflect.DeepEqual(c.ArticleIDs, []string{"a3", "a1"}) || !c.CreatedAt.Equal(base) {
+		t.Fatalf("create collection: %+v (%v)", c, err)
+	}
+	if _, err := repo.CreateCollection(ctx, domain.CreateCollectionParams{ID: "c1", Title: "重复"}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict for a duplicate id, got %v", err)
+	}
+	if _, err := repo.CreateCollection(ctx, domain.CreateCollectionParams{ID: "c2", Title: "另一个", ArticleIDs: []string{"a1"}, CreatedAt: base.Add(time.Minute)}); err != nil {
+		t.Fatalf("create second collection: %v", err)
+	}
+
+	c, err = repo.AddToCollection(ctx, domain.CollectionMembersParams{CollectionID: "c1", ArticleIDs: []string{"a1", "a2", "a4"}, At: base.Add(time.Hour)})
+	if err != nil || !reflect.DeepEqual(c.ArticleIDs, []string{"a3", "a1", "a2", "a4"}) {
+		t.Fatalf("add: %+v (%v)", c.ArticleIDs, err)
+	}
+	if got := listed(domain.ListArticlesQuery{CollectionID: "c1"}); !reflect.DeepEqual(got, []string{"a3", "a1", "a2", "a4"}) {
+		t.Fatalf("expected collection order, got %v", got)
+	}
+	if got := listed(domain.ListArticlesQuery{CollectionID: "c1", Sort: domain.ArticleSortCreatedAt}); !reflect.DeepEqual(got, []string{"a4", "a3", "a2", "a1"}) {
+		t.Fatalf("expected an explicit sort to win, got %v", got)
+	}
+	if _, err := repo.ListArticles(ctx, domain.ListArticlesQuery{Sort: domain.ArticleSortCollection}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for collection sort without a collection, got %v", err)
+	}
+	page, err := repo.ListArticlesPage(ctx, domain.ListArticlesQuery{CollectionID: "c1", Limit: 3, Ascending: true})
+	if err != nil || len(page.Articles) != 3 || page.Articles[0].ID != "a4" || page.NextCursor == "" {
+		t.Fatalf("first page: %+v (%v)", page, err)
+	}
+	page, err = repo.ListArticlesPage(ctx, domain.ListArticlesQuery{CollectionID: "c1", Limit: 3, Ascending: true, Cursor: page.NextCursor})
+	if err != nil || len(page.Articles) != 1 || page.Articles[0].ID != "a3" || page.NextCursor != "" {
+		t.Fatalf("second page: %+v (%v)", page, err)
+	}
+
+	if _, err := repo.ReorderCollection(ctx, domain.CollectionMembersParams{CollectionID: "c1", ArticleIDs: []string{"a1", "a1", "a2", "a4"}}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for a duplicate in the new order, got %v", err)
+	}
+	if _, err := repo.ReorderCollection(ctx, domain.CollectionMembersParams{CollectionID: "c1", ArticleIDs: []string{"a1", "a2"}}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for a partial order, got %v", err)
+	}
+
+	// Trashed members drop out of the collection and come back in place.
+	if err := repo.DeleteArticle(ctx, "a1"); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	c, err = repo.ReorderCollection(ctx, domain.CollectionMembersParams{CollectionID: "c1", ArticleIDs: []string{"a4", "a2", "a3"}})
+	if err != nil || !reflect.DeepEqual(c.ArticleIDs, []string{"a4", "a2", "a3"}) {
+		t.Fatalf("reorder: %+v (%v)", c.ArticleIDs, err)
+	}
+	if got := listed(domain.ListArticlesQuery{CollectionID: "c1"}); !reflect.DeepEqual(got, []string{"a4", "a2", "a3"}) {
+		t.Fatalf("expected trashed members to be hidden, got %v", got)
+	}
+	if _, err := repo.AddToCollection(ctx, domain.CollectionMembersParams{CollectionID: "c2", ArticleIDs: []string{"a1"}}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound adding a trashed article, got %v", err)
+	}
+	if _, err := repo.RestoreArticle(ctx, "a1"); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if c, err := repo.GetCollection(ctx, "c1"); err != nil || !reflect.DeepEqual(c.ArticleIDs, []string{"a4", "a2", "a3", "a1"}) {
+		t.Fatalf("expected the restored article at the end: %+v (%v)", c.ArticleIDs, err)
+	}
+
+	in, err := repo.CollectionsOfArticle(ctx, "a1")
+	if err != nil || len(in) != 2 || in[0].ID != "c2" || in[1].ID != "c1" {
+		t.Fatalf("collections of a1: %+v (%v)", in, err)
+	}
+	title := "  "
+	if _, err := repo.UpdateCollection(ctx, "c1", domain.UpdateCollectionParams{Title: &title}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for a blank title, got %v", err)
+	}
+	title, description := "新合集", "简介"
+	c, err = repo.UpdateCollection(ctx, "c1", domain.UpdateCollectionParams{Title: &title, Description: &description, UpdatedAt: base.Add(2 * time.Hour)})
+	if err != nil || c.Title != title || c.Description != description || len(c.ArticleIDs) != 4 {
+		t.Fatalf("update: %+v (%v)", c, err)
+	}
+	all, err := repo.ListCollections(ctx, domain.ListCollectionsQuery{})
+	if err != nil || len(all) != 2 || all[0].ID != "c1" {
+		t.Fatalf("list collections: %+v (%v)", all, err)
+	}
+
+	c, err = repo.RemoveFromCollection(ctx, domain.CollectionMembersParams{CollectionID: "c1", ArticleIDs: []string{"a2"}})
+	if err != nil || !reflect.DeepEqual(c.ArticleIDs, []string{"a4", "a3", "a1"}) {
+		t.Fatalf("remove: %+v (%v)", c.ArticleIDs, err)
+	}
+
+	// Purging a trashed member removes it from every collection.
+	if err := repo.DeleteArticle(ctx, "a1"); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	if _, err := repo.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if _, err := repo.CollectionsOfArticle(ctx, "a1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a purged article, got %v", err)
+	}
+	if c, err := repo.GetCollection(ctx, "c2"); err != nil || len(c.ArticleIDs) != 0 {
+		t.Fatalf("expected c2 to be empty: %+v (%v)", c, err)
+	}
+
+	if err := repo.DeleteCollection(ctx, "c1"); err != nil {
+		t.Fatalf("delete collection: %v", err)
+	}
+	if _, err := repo.GetCollection(ctx, "c1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if _, err := repo.GetArticle(ctx, "a3"); err != nil {
+		t.Fatalf("deleting a collection must keep its articles: %v", err)
+	}
+	if err := repo.DeleteCollection(ctx, "c1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+// sameIDs compares ID lists, ignoring order unless ordered is set.
+func sameIDs(got, want []string, ordered bool) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	if ordered {
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+	seen := make(map[string]int, len(got))
+	for _, id := range got {
+		seen[id]++
+	}
+	for _, id := range want {
+		if seen[id] == 0 {
+			return false
+		}
+		seen[id]--
+	}
+	return true
+}
+
+func TestSQLiteRepository_MigrationReindexesExistingSearchRows(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	if err := migrate.Apply(ctx, db, data.MigrationFeature, data.Migrations()[:2]); err != nil {
+		t.Fatalf("apply v2: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `
+INSERT INTO articles(id, title, content, status, created_at_ms, updated_at_ms, current_version) VALUES('old', '旧文章', '关于内容增长的思考', 'draft', 1, 1, 1);
+INSERT INTO article_fts(title, content, tags, article_id) VALUES('旧文章', '关于内容增长的思考', '', 'old');
+`); err != nil {
+		t.Fatalf("seed v2 rows: %v", err)
+	}
+
+	repo, err := data.NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	got, err := repo.SearchArticles(ctx, domain.SearchArticlesQuery{Query: "增长"})
+	if err != nil || len(got) != 1 || got[0].ID != "old" {
+		t.Fatalf("expected reindexed match, got %+v (%v)", got, err)
+	}
+	if err := repo.Reindex(ctx); err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM article_fts`).Scan(&n); err != nil || n != 1 {
+		t.Fatalf("expected one index row after reindex, got %d (%v)", n, err)
+	}
+}
+
+func TestSQLiteRepository_ExpectedVersionConflicts(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.CreateArticle(ctx, domain.CreateArticleParams{ID: "a1", Title: "t", Content: "v1", Status: domain.ArticleStatusDraft, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	manual := "manual save"
+	if _, err := repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{Content: &manual, ExpectedVersion: 1}); err != nil {
+		t.Fatalf("update at expected version: %v", err)
+	}
+
+	stale := "stale autosave"
+	_, err = repo.UpdateArticle(ctx, "a1", domain.UpdateArticleParams{Content: &stale, IsAutoSave: true, ExpectedVersion: 1})
+	var conflict *domain.VersionConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected VersionConflictError, got %v", err)
+	}
+	if conflict.ExpectedVersion != 1 || conflict.CurrentVersion != 2 {
+		t.Fatalf("unexpected conflict: %+v", conflict)
+	}
+
+	if _, err := repo.RestoreVersion(ctx, "a1", domain.RestoreVersionParams{Version: 1, ExpectedVersion: 1}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict on stale restore, got %v", err)
+	}
+
+	got, err := repo.GetArticle(ctx, "a1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Content != "manual save" || got.CurrentVersion != 2 {
+		t.Fatalf("stale writes must not apply, got %+v", got)
+	}
+	versions, err := repo.ListVersions(ctx, domain.ListVersionsQuery{ArticleID: "a1"})
+	if err != nil {
+		t.Fatalf("list versions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected no version rows from rejected writes, got %d", len(versions))
+	}
+}
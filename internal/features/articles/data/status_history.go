@@ -0,0 +1,64 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+func recordStatusChangeTx(ctx context.Context, tx *sql.Tx, articleID string, version int, from, to domain.ArticleStatus, note string, atMs int64) error {
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO article_status_history(article_id, version, from_status, to_status, note, changed_at_ms)
+VALUES(?, ?, ?, ?, ?, ?)
+`, articleID, version, string(from), string(to), note, atMs)
+	return err
+}
+
+// ListStatusHistory returns the status changes of an article, including one
+// in the trash, oldest first.
+func (r *SQLiteRepository) ListStatusHistory(ctx context.Context, articleID string) ([]domain.StatusChange, error) {
+	if articleID == "" {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	var exists int
+	if err := r.db.QueryRowContext(ctx, `SELECT 1 FROM articles WHERE id = ?`, articleID).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT version, from_status, to_status, note, changed_at_ms
+FROM article_status_history
+WHERE article_id = ?
+ORDER BY id
+`, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.StatusChange, 0)
+	for rows.Next() {
+		var (
+			c        domain.StatusChange
+			from, to string
+			atMs     int64
+		)
+		if err := rows.Scan(&c.Version, &from, &to, &c.Note, &atMs); err != nil {
+			return nil, err
+		}
+		c.ArticleID = articleID
+		c.From, c.To = domain.ArticleStatus(from), domain.ArticleStatus(to)
+		c.ChangedAt = time.UnixMilli(atMs).UTC()
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,270 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/data/models"
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+func (r *SQLiteRepository) ListTagUsage(ctx context.Context, sort domain.TagUsageSort) ([]domain.TagUsage, error) {
+	if sort == "" {
+		sort = domain.TagUsageSortName
+	}
+	if !sort.Valid() {
+		return nil, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("invalid sort %q", sort))
+	}
+	order := `t.name ASC`
+	switch sort {
+	case domain.TagUsageSortCount:
+		order = `article_count DESC, t.name ASC`
+	case domain.TagUsageSortLastUsed:
+		order = `last_used_ms DESC, t.name ASC`
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT t.id, t.name, t.created_at_ms,
+	COUNT(CASE WHEN a.deleted_at_ms = 0 THEN 1 END) AS article_count,
+	COUNT(CASE WHEN a.deleted_at_ms != 0 THEN 1 END),
+	COALESCE(MAX(CASE WHEN a.deleted_at_ms = 0 THEN a.updated_at_ms END), 0) AS last_used_ms
+FROM tags t
+LEFT JOIN article_tags at ON at.tag_id = t.id
+LEFT JOIN articles a ON a.id = at.article_id
+GROUP BY t.id
+ORDER BY `+order)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.TagUsage, 0)
+	for rows.Next() {
+		var (
+			dto        models.TagDTO
+			u          domain.TagUsage
+			lastUsedMs int64
+		)
+		if err := rows.Scan(&dto.ID, &dto.Name, &dto.CreatedAtMs, &u.ArticleCount, &u.TrashedCount, &lastUsedMs); err != nil {
+			return nil, err
+		}
+		u.Tag = dto.ToDomain()
+		if lastUsedMs != 0 {
+			u.LastUsedAt = time.UnixMilli(lastUsedMs).UTC()
+		}
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *SQLiteRepository) RenameTag(ctx context.Context, params domain.RenameTagParams) (domain.Tag, error) {
+	from, err := domain.NormalizeTagName(params.From)
+	if err != nil {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	to, err := domain.NormalizeTagName(params.To)
+	if err != nil {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	defer tx.Rollback()
+
+	tag, err := findTagTx(ctx, tx, from)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	if from == to {
+		return tag, nil
+	}
+	if _, err := findTagTx(ctx, tx, to); err == nil {
+		return domain.Tag{}, errors.Join(domain.ErrConflict, fmt.Errorf("tag %q already exists; merge the tags instead", to))
+	} else if !errors.Is(err, domain.ErrNotFound) {
+		return domain.Tag{}, err
+	}
+
+	ids, err := taggedArticleIDsTx(ctx, tx, tag.ID)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tags SET name = ? WHERE id = ?`, to, tag.ID); err != nil {
+		return domain.Tag{}, err
+	}
+	if err := r.refreshSearchTx(ctx, tx, ids); err != nil {
+		return domain.Tag{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Tag{}, err
+	}
+	tag.Name = to
+	return tag, nil
+}
+
+func (r *SQLiteRepository) MergeTags(ctx context.Context, params domain.MergeTagsParams) (domain.Tag, error) {
+	target, err := domain.NormalizeTagName(params.Target)
+	if err != nil {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	names, err := domain.NormalizeTagNames(params.Sources)
+	if err != nil {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	sources := make([]string, 0, len(names))
+	for _, n := range names {
+		if n != target {
+			sources = append(sources, n)
+		}
+	}
+	if len(sources) == 0 {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, errors.New("at least one tag other than the target is required"))
+	}
+	nowMs := params.Now.UTC().UnixMilli()
+	if params.Now.IsZero() {
+		nowMs = time.Now().UTC().UnixMilli()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	defer tx.Rollback()
+
+	merged := make([]domain.Tag, 0, len(sources))
+	var ids []string
+	for _, name := range sources {
+		tag, err := findTagTx(ctx, tx, name)
+		if err != nil {
+			return domain.Tag{}, errors.Join(err, fmt.Errorf("tag %q", name))
+		}
+		tagged, err := taggedArticleIDsTx(ctx, tx, tag.ID)
+		if err != nil {
+			return domain.Tag{}, err
+		}
+		merged = append(merged, tag)
+		ids = append(ids, tagged...)
+	}
+
+	into, err := upsertTagTx(ctx, tx, target, nowMs)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	for _, tag := range merged {
+		for _, stmt := range []string{
+			`INSERT OR IGNORE INTO article_tags(article_id, tag_id) SELECT article_id, ?2 FROM article_tags WHERE tag_id = ?1`,
+			`DELETE FROM article_tags WHERE tag_id = ?1`,
+		} {
+			if _, err := tx.ExecContext(ctx, stmt, tag.ID, into.ID); err != nil {
+				return domain.Tag{}, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = ?`, tag.ID); err != nil {
+			return domain.Tag{}, err
+		}
+	}
+	if err := r.refreshSearchTx(ctx, tx, ids); err != nil {
+		return domain.Tag{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Tag{}, err
+	}
+	return into, nil
+}
+
+func (r *SQLiteRepository) DeleteUnusedTags(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM article_tags)`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+func findTagTx(ctx context.Context, tx *sql.Tx, name string) (domain.Tag, error) {
+	var dto models.TagDTO
+	if err := tx.QueryRowContext(ctx, `SELECT id, name, created_at_ms FROM tags WHERE name = ?`, name).Scan(&dto.ID, &dto.Name, &dto.CreatedAtMs); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Tag{}, domain.ErrNotFound
+		}
+		return domain.Tag{}, err
+	}
+	return dto.ToDomain(), nil
+}
+
+func taggedArticleIDsTx(ctx context.Context, tx *sql.Tx, tagID string) ([]string, error) {
+	return queryIDsTx(ctx, tx, `SELECT article_id FROM article_tags WHERE tag_id = ?`, tagID)
+}
+
+// queryIDsTx runs a query selecting a single ID column.
+func queryIDsTx(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// refreshSearchTx rewrites the search rows of the live articles among ids
+// from their current tags.
+func (r *SQLiteRepository) refreshSearchTx(ctx context.Context, tx *sql.Tx, ids []string) error {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		var title, content string
+		if err := tx.QueryRowContext(ctx, `SELECT title, content FROM articles WHERE id = ? AND deleted_at_ms = 0`, id).Scan(&title, &content); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue // trashed articles are not indexed
+			}
+			return err
+		}
+		tagNames, err := r.fetchTagNames(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := r.index.UpsertTx(ctx, tx, id, title, content, tagNames); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tagNamesOf(tags []domain.Tag) []string {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, t.Name)
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+// CompactVersions applies params.Retention to every article that has
+// autosaves. Each article is compacted in its own short transaction that
+// re-reads the current version, so saves running alongside only wait for one
+// article and never lose the version they just wrote.
+func (r *SQLiteRepository) CompactVersions(ctx context.Context, params domain.CompactVersionsParams) (domain.CompactVersionsResult, error) {
+	if err := params.Retention.Validate(); err != nil {
+		return domain.CompactVersionsResult{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	now := params.Now
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+
+	ids, err := r.autosavedArticleIDs(ctx)
+	if err != nil {
+		return domain.CompactVersionsResult{}, err
+	}
+	var res domain.CompactVersionsResult
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		n, err := r.compactArticleVersions(ctx, id, params.Retention, now)
+		if err != nil {
+			return res, err
+		}
+		if n > 0 {
+			res.Articles++
+			res.Removed += n
+		}
+	}
+	return res, nil
+}
+
+func (r *SQLiteRepository) autosavedArticleIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT article_id FROM article_versions WHERE is_autosave = 1 ORDER BY article_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *SQLiteRepository) compactArticleVersions(ctx context.Context, articleID string, retention domain.VersionRetention, now time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var current int
+	if err := tx.QueryRowContext(ctx, `SELECT current_version FROM articles WHERE id = ?`, articleID).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil // purged since the ids were listed
+		}
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT version, created_at_ms, is_autosave FROM article_versions WHERE article_id = ?`, articleID)
+	if err != nil {
+		return 0, err
+	}
+	var stamps []domain.VersionStamp
+	for rows.Next() {
+		var (
+			s         domain.VersionStamp
+			createdMs int64
+			autosave  int
+		)
+		if err := rows.Scan(&s.Version, &createdMs, &autosave); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		s.CreatedAt = time.UnixMilli(createdMs).UTC()
+		s.IsAutoSave = autosave != 0
+		stamps = append(stamps, s)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	expired := retention.ExpiredVersions(stamps, current, now)
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	args := make([]any, 0, len(expired)+1)
+	args = append(args, articleID)
+	for _, v := range expired {
+		args = append(args, v)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM article_versions WHERE article_id = ? AND is_autosave = 1 AND version IN (`+placeholders(len(expired))+`)`, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
@@ -3,8 +3,11 @@
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -12,40 +15,74 @@ const (
 	MaxContentLength = 1000000 // 1MB
 	MaxTagCount      = 50
 	MaxTagLength     = 100
+	MaxDigestLength  = 120 // runes, WeChat's limit for 摘要
+	MaxAuthorLength  = 64
+	MaxURLLength     = 2048
+	MaxNoteLength    = 1000 // runes, for status change notes
 )
 
+// DefaultTrashRetention is how long deleted articles stay restorable.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
 type ArticleStatus string
 
 const (
 	ArticleStatusDraft     ArticleStatus = "draft"
+	ArticleStatusInReview  ArticleStatus = "in_review"
+	ArticleStatusApproved  ArticleStatus = "approved"
+	ArticleStatusScheduled ArticleStatus = "scheduled"
 	ArticleStatusPublished ArticleStatus = "published"
+	ArticleStatusArchived  ArticleStatus = "archived"
 )
 
 type Article struct {
-	ID            string
-	Title         string
-	Content       string
-	Status        ArticleStatus
-	Tags          []Tag
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID             string
+	Title          string
+	Content        string
+	Status         ArticleStatus
+	Digest         string
+	Author         string
+	CoverImage     string // WeChat media ID or image URL
+	SourceURL      string // 原文链接
+	OpenComments   bool
+	Tags           []Tag
+	WordCount      int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      time.Time // zero unless the article is in the trash
 	CurrentVersion int
 }
 
 type ArticleVersion struct {
-	ArticleID  string
-	Version    int
-	Title      string
-	Content    string
-	Status     ArticleStatus
-	Tags       []string
-	CreatedAt  time.Time
-	IsAutoSave bool
+	ArticleID    string
+	Version      int
+	Title        string
+	Content      string
+	Status       ArticleStatus
+	Digest       string
+	Author       string
+	CoverImage   string
+	SourceURL    string
+	OpenComments bool
+	Tags         []string
+	CreatedAt    time.Time
+	IsAutoSave   bool
 }
 
 func (s ArticleStatus) Valid() bool {
 	switch s {
-	case ArticleStatusDraft, ArticleStatusPublished:
+	case ArticleStatusDraft, ArticleStatusInReview, ArticleStatusApproved, ArticleStatusScheduled, ArticleStatusPublished, ArticleStatusArchived:
+		return true
+	default:
+		return false
+	}
+}
+
+// Ready reports whether s is past drafting, so the article needs a title and
+// content.
+func (s ArticleStatus) Ready() bool {
+	switch s {
+	case ArticleStatusInReview, ArticleStatusApproved, ArticleStatusScheduled, ArticleStatusPublished:
 		return true
 	default:
 		return false
@@ -66,14 +103,69 @@ func ValidateArticleFields(status ArticleStatus, title, content string) error {
 		return fmt.Errorf("content too long: max %d characters, got %d", MaxContentLength, len(content))
 	}
 
-	if status == ArticleStatusPublished {
+	if status.Ready() {
 		if strings.TrimSpace(title) == "" {
-			return errors.New("title required for published article")
+			return fmt.Errorf("title required for %s article", status)
 		}
 		if strings.TrimSpace(content) == "" {
-			return errors.New("content required for published article")
+			return fmt.Errorf("content required for %s article", status)
 		}
 	}
 
 	return nil
 }
+
+// ValidateArticleTransition is ValidateArticleFields for an article moving
+// from status from. An illegal move is reported as a *TransitionError.
+func ValidateArticleTransition(w Workflow, from, to ArticleStatus, title, content string) error {
+	if err := w.CheckTransition(from, to); err != nil {
+		return err
+	}
+	return ValidateArticleFields(to, title, content)
+}
+
+// ValidateArticleMetadata checks the WeChat post metadata. Lengths of digest
+// and author are counted in characters, not bytes.
+func ValidateArticleMetadata(digest, author, coverImage, sourceURL string) error {
+	if n := utf8.RuneCountInString(digest); n > MaxDigestLength {
+		return fmt.Errorf("digest too long: max %d characters, got %d", MaxDigestLength, n)
+	}
+	if n := utf8.RuneCountInString(author); n > MaxAuthorLength {
+		return fmt.Errorf("author too long: max %d characters, got %d", MaxAuthorLength, n)
+	}
+	if len(coverImage) > MaxURLLength {
+		return fmt.Errorf("cover image reference too long: max %d characters, got %d", MaxURLLength, len(coverImage))
+	}
+	if sourceURL != "" {
+		if len(sourceURL) > MaxURLLength {
+			return fmt.Errorf("source url too long: max %d characters, got %d", MaxURLLength, len(sourceURL))
+		}
+		u, err := url.Parse(sourceURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("source url must be an absolute http(s) url")
+		}
+	}
+	return nil
+}
+
+// CountWords counts words the way Chinese editors do: every CJK character is
+// a word, and so is every run of other letters or digits.
+func CountWords(s string) int {
+	n := 0
+	inWord := false
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			n++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				n++
+			}
+			inWord = true
+		default:
+			inWord = false
+		}
+	}
+	return n
+}
@@ -1,6 +1,7 @@
 ﻿package domain_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
@@ -23,3 +24,35 @@ func TestValidateArticleFields(t *testing.T) {
 		t.Fatalf("invalid status should fail")
 	}
 }
+
+func TestValidateArticleMetadata(t *testing.T) {
+	if err := domain.ValidateArticleMetadata(strings.Repeat("摘", domain.MaxDigestLength), "作者", "media-id", "https://mp.weixin.qq.com/s/x"); err != nil {
+		t.Fatalf("expected valid metadata: %v", err)
+	}
+	if err := domain.ValidateArticleMetadata(strings.Repeat("摘", domain.MaxDigestLength+1), "", "", ""); err == nil {
+		t.Fatalf("digest over %d characters should fail", domain.MaxDigestLength)
+	}
+	if err := domain.ValidateArticleMetadata("", strings.Repeat("a", domain.MaxAuthorLength+1), "", ""); err == nil {
+		t.Fatalf("long author should fail")
+	}
+	for _, u := range []string{"example.com/post", "ftp://example.com/x", "https://"} {
+		if err := domain.ValidateArticleMetadata("", "", "", u); err == nil {
+			t.Fatalf("source url %q should fail", u)
+		}
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	for in, want := range map[string]int{
+		"":                  0,
+		"增长黑客":              4,
+		"Hello, world 2025": 3,
+		"用 Go 写 SQLite 扩展。": 6,
+		"  \n\t":            0,
+		"かなカナ and 한글 text":  8,
+	} {
+		if got := domain.CountWords(in); got != want {
+			t.Fatalf("CountWords(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+﻿package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxCollectionTitleLength       = 64  // runes
+	MaxCollectionDescriptionLength = 500 // runes
+	MaxCollectionArticles          = 500
+)
+
+// Collection is an ordered series of articles, a 合集 on WeChat.
+// ArticleIDs lists its live articles in order; members in the trash are
+// kept, so restoring an article puts it back in its series.
+type Collection struct {
+	ID          string
+	Title       string
+	Description string
+	ArticleIDs  []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func ValidateCollectionFields(title, description string) error {
+	if strings.TrimSpace(title) == "" {
+		return errors.New("collection title is required")
+	}
+	if n := utf8.RuneCountInString(title); n > MaxCollectionTitleLength {
+		return fmt.Errorf("collection title too long: max %d characters, got %d", MaxCollectionTitleLength, n)
+	}
+	if n := utf8.RuneCountInString(description); n > MaxCollectionDescriptionLength {
+		return fmt.Errorf("collection description too long: max %d characters, got %d", MaxCollectionDescriptionLength, n)
+	}
+	return nil
+}
+
+type CreateCollectionParams struct {
+	ID          string
+	Title       string
+	Description string
+	ArticleIDs  []string
+	CreatedAt   time.Time
+}
+
+type UpdateCollectionParams struct {
+	Title       *string
+	Description *string
+	UpdatedAt   time.Time
+}
+
+// CollectionMembersParams names articles of a collection. Adding appends them
+// in order and skips ones already in it; reordering takes every live member
+// in the new order, and members in the trash move to the end.
+type CollectionMembersParams struct {
+	CollectionID string
+	ArticleIDs   []string
+	At           time.Time
+}
+
+type ListCollectionsQuery struct {
+	Limit  int
+	Offset int
+}
+
+type CollectionRepository interface {
+	CreateCollection(ctx context.Context, params CreateCollectionParams) (Collection, error)
+	GetCollection(ctx context.Context, collectionID string) (Collection, error)
+	UpdateCollection(ctx context.Context, collectionID string, params UpdateCollectionParams) (Collection, error)
+	// DeleteCollection removes the collection, never its articles.
+	DeleteCollection(ctx context.Context, collectionID string) error
+	// ListCollections lists the most recently updated first.
+	ListCollections(ctx context.Context, query ListCollectionsQuery) ([]Collection, error)
+	AddToCollection(ctx context.Context, params CollectionMembersParams) (Collection, error)
+	RemoveFromCollection(ctx context.Context, params CollectionMembersParams) (Collection, error)
+	ReorderCollection(ctx context.Context, params CollectionMembersParams) (Collection, error)
+	// CollectionsOfArticle returns the collections an article belongs to.
+	CollectionsOfArticle(ctx context.Context, articleID string) ([]Collection, error)
+}
@@ -0,0 +1,93 @@
+﻿package domain
+
+// Bounds on the Myers search. Past either one, the part that still differs
+// after the common prefix and suffix is left unmatched, as if it had been
+// replaced wholesale. That alignment is correct, only not minimal, so
+// callers need no special case.
+const (
+	maxDiffCost = 1000       // edit distance D
+	maxDiffWork = 20_000_000 // diagonal steps, snakes included
+)
+
+// matchSequences aligns a and b with Myers' O(ND) diff and returns, for each
+// index of a, the index of its matching element in b, or -1. Only the
+// v[-d-1..d+1] window of each step is kept for the backtrack, so memory is
+// O(D²) rather than O(D·(N+M)).
+func matchSequences[T comparable](a, b []T) []int {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+
+	// Common prefix and suffix need no search.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		match[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		match[len(a)-1-suf] = len(b) - 1 - suf
+		suf++
+	}
+	a, b = a[pre:len(a)-suf], b[pre:len(b)-suf]
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return match
+	}
+
+	max := n + m
+	off := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	work := 0
+search:
+	for d := 0; d <= max; d++ {
+		if d > maxDiffCost {
+			return match
+		}
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+				work++
+			}
+			if work++; work > maxDiffWork {
+				return match
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		w := trace[d] // w[d+1+k] is v[k] before step d
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && w[d+k] < w[d+k+2]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := w[d+1+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			match[pre+x] = pre + y
+		}
+		x, y = prevX, prevY
+	}
+	return match
+}
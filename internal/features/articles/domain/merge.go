@@ -0,0 +1,115 @@
+﻿package domain
+
+import "strings"
+
+const (
+	MergeMarkerOurs   = "<<<<<<< ours"
+	MergeMarkerBase   = "======="
+	MergeMarkerTheirs = ">>>>>>> theirs"
+)
+
+// MergeConflict is a region both sides changed differently. Line is the
+// 0-based line of the conflict's opening marker in MergeResult.Text.
+type MergeConflict struct {
+	Line   int
+	Base   string
+	Ours   string
+	Theirs string
+}
+
+type MergeResult struct {
+	Text      string
+	Conflicts []MergeConflict
+}
+
+func (r MergeResult) Clean() bool { return len(r.Conflicts) == 0 }
+
+// MergeText performs a line-based three-way merge of ours and theirs, both
+// derived from base. Conflicting regions are written with git-style markers.
+func MergeText(base, ours, theirs string) MergeResult {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	mo, mt := matchSequences(b, o), matchSequences(b, t)
+
+	var out strings.Builder
+	var conflicts []MergeConflict
+	line := 0
+	write := func(lines []string) {
+		for _, l := range lines {
+			out.WriteString(l)
+			line++
+		}
+	}
+
+	i, j, k := 0, 0, 0
+	for i < len(b) || j < len(o) || k < len(t) {
+		// Next base line kept unchanged by both sides.
+		next := i
+		for next < len(b) && (mo[next] < 0 || mt[next] < 0) {
+			next++
+		}
+		if next == i && i < len(b) && mo[i] == j && mt[i] == k {
+			write(b[i : i+1])
+			i, j, k = i+1, j+1, k+1
+			continue
+		}
+		oEnd, tEnd := len(o), len(t)
+		if next < len(b) {
+			oEnd, tEnd = mo[next], mt[next]
+		}
+		bc, oc, tc := b[i:next], o[j:oEnd], t[k:tEnd]
+		switch {
+		case equalLines(oc, bc):
+			write(tc)
+		case equalLines(tc, bc), equalLines(oc, tc):
+			write(oc)
+		default:
+			conflicts = append(conflicts, MergeConflict{
+				Line:   line,
+				Base:   strings.Join(bc, ""),
+				Ours:   strings.Join(oc, ""),
+				Theirs: strings.Join(tc, ""),
+			})
+			write([]string{MergeMarkerOurs + "\n"})
+			write(terminate(oc))
+			write([]string{MergeMarkerBase + "\n"})
+			write(terminate(tc))
+			write([]string{MergeMarkerTheirs + "\n"})
+		}
+		i, j, k = next, oEnd, tEnd
+	}
+	return MergeResult{Text: out.String(), Conflicts: conflicts}
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// terminate makes sure a conflict side ends with a newline so the closing
+// marker starts on its own line.
+func terminate(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	out := append([]string(nil), lines...)
+	out[len(out)-1] += "\n"
+	return out
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
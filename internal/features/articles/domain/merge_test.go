@@ -0,0 +1,66 @@
+﻿package domain_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+func TestMergeText_CombinesNonOverlappingEdits(t *testing.T) {
+	base := "标题\n第一段\n第二段\n第三段\n"
+	ours := "标题\n第一段（改）\n第二段\n第三段\n"
+	theirs := "标题\n第一段\n第二段\n第三段\n新增第四段\n"
+
+	got := domain.MergeText(base, ours, theirs)
+	if !got.Clean() {
+		t.Fatalf("expected clean merge, got conflicts %+v", got.Conflicts)
+	}
+	if want := "标题\n第一段（改）\n第二段\n第三段\n新增第四段\n"; got.Text != want {
+		t.Fatalf("unexpected merge:\n%s", got.Text)
+	}
+
+	if got := domain.MergeText(base, base, theirs); got.Text != theirs || !got.Clean() {
+		t.Fatalf("unchanged ours should take theirs, got %q", got.Text)
+	}
+	if got := domain.MergeText(base, ours, ours); got.Text != ours || !got.Clean() {
+		t.Fatalf("identical edits should merge cleanly, got %q", got.Text)
+	}
+}
+
+func TestMergeText_ReportsConflicts(t *testing.T) {
+	base := "a\nb\nc"
+	ours := "a\nB1\nc"
+	theirs := "a\nB2\nc"
+
+	got := domain.MergeText(base, ours, theirs)
+	if len(got.Conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", got.Conflicts)
+	}
+	c := got.Conflicts[0]
+	if c.Line != 1 || c.Base != "b\n" || c.Ours != "B1\n" || c.Theirs != "B2\n" {
+		t.Fatalf("unexpected conflict: %+v", c)
+	}
+	want := strings.Join([]string{"a", domain.MergeMarkerOurs, "B1", domain.MergeMarkerBase, "B2", domain.MergeMarkerTheirs, "c"}, "\n")
+	if got.Text != want {
+		t.Fatalf("unexpected merged text:\n%s", got.Text)
+	}
+}
+
+func TestMergeText_LargeUnrelatedRewrite(t *testing.T) {
+	var base, ours strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&base, "旧的第%d行\n", i)
+		fmt.Fprintf(&ours, "新的第%d行\n", i)
+	}
+	start := time.Now()
+	got := domain.MergeText(base.String(), ours.String(), base.String())
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("merge took %v", elapsed)
+	}
+	if !got.Clean() || got.Text != ours.String() {
+		t.Fatalf("expected the rewrite to win cleanly, got %d conflicts", len(got.Conflicts))
+	}
+}
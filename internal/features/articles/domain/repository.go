@@ -3,6 +3,7 @@
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -12,6 +13,20 @@ var (
 	ErrInvalidArgument = errors.New("articles: invalid argument")
 )
 
+// VersionConflictError is returned when a write expected a version of the
+// article other than its current one. It matches ErrConflict.
+type VersionConflictError struct {
+	ArticleID       string
+	ExpectedVersion int
+	CurrentVersion  int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("articles: conflict: article %s is at version %d, expected %d", e.ArticleID, e.CurrentVersion, e.ExpectedVersion)
+}
+
+func (e *VersionConflictError) Is(target error) bool { return target == ErrConflict }
+
 type Clock interface {
 	Now() time.Time
 }
@@ -21,29 +36,116 @@ type IDGenerator interface {
 }
 
 type CreateArticleParams struct {
-	ID        string
-	Title     string
-	Content   string
-	Status    ArticleStatus
-	Tags      []string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID           string
+	Title        string
+	Content      string
+	Status       ArticleStatus
+	Digest       string
+	Author       string
+	CoverImage   string
+	SourceURL    string
+	OpenComments bool
+	Tags         []string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	// Workflow decides which statuses a new article may start in; it is
+	// checked as a move out of draft.
+	Workflow Workflow
 }
 
 type UpdateArticleParams struct {
-	Title      *string
-	Content    *string
-	Status     *ArticleStatus
-	Tags       *[]string
-	UpdatedAt  time.Time
-	IsAutoSave bool
+	Title        *string
+	Content      *string
+	Status       *ArticleStatus
+	Digest       *string
+	Author       *string
+	CoverImage   *string
+	SourceURL    *string
+	OpenComments *bool
+	Tags         *[]string
+	UpdatedAt    time.Time
+	IsAutoSave   bool
+	// Workflow is enforced when Status changes, and StatusNote is recorded
+	// with the change in the status history.
+	Workflow   Workflow
+	StatusNote string
+	// ExpectedVersion, when positive, makes the update fail with a
+	// *VersionConflictError unless it equals the current version.
+	ExpectedVersion int
+}
+
+type RestoreVersionParams struct {
+	Version         int
+	RestoredAt      time.Time
+	ExpectedVersion int
+	Workflow        Workflow
+}
+
+// ArticleSort names the column articles are listed by.
+type ArticleSort string
+
+const (
+	ArticleSortUpdatedAt ArticleSort = "updated_at"
+	ArticleSortCreatedAt ArticleSort = "created_at"
+	ArticleSortTitle     ArticleSort = "title"
+	ArticleSortWordCount ArticleSort = "word_count"
+	// ArticleSortCollection lists in the order of ListArticlesQuery's
+	// collection, first article first; Ascending reverses it.
+	ArticleSortCollection ArticleSort = "collection"
+)
+
+func (s ArticleSort) Valid() bool {
+	switch s {
+	case ArticleSortUpdatedAt, ArticleSortCreatedAt, ArticleSortTitle, ArticleSortWordCount, ArticleSortCollection:
+		return true
+	default:
+		return false
+	}
 }
 
+// TagMatch says whether an article needs any or all of the listed tags.
+type TagMatch string
+
+const (
+	TagMatchAll TagMatch = "all"
+	TagMatchAny TagMatch = "any"
+)
+
+func (m TagMatch) Valid() bool {
+	return m == TagMatchAll || m == TagMatchAny
+}
+
+// ListArticlesQuery filters and orders an article listing. Zero values leave
+// a filter off; the default order is most recently updated first. Date
+// ranges are half-open: From is inclusive, To exclusive.
 type ListArticlesQuery struct {
-	Status *ArticleStatus
-	Tag    *string
-	Limit  int
-	Offset int
+	Status      *ArticleStatus
+	Tag         *string
+	Statuses    []ArticleStatus // any of
+	Tags        []string
+	TagMatch    TagMatch // defaults to TagMatchAll
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+	// CollectionID keeps only the collection's articles.
+	CollectionID string
+	// Sort defaults to ArticleSortCollection with a CollectionID and to
+	// ArticleSortUpdatedAt otherwise.
+	Sort      ArticleSort
+	Ascending bool
+	Limit     int
+	Offset    int
+	// Cursor continues a ListArticlesPage listing and replaces Offset. It is
+	// only valid with the sort order it was issued for.
+	Cursor string
+}
+
+// ArticlePage is one page of a cursor listing. NextCursor is empty on the
+// last page.
+type ArticlePage struct {
+	Articles   []Article
+	NextCursor string
 }
 
 type SearchArticlesQuery struct {
@@ -54,6 +156,11 @@ type SearchArticlesQuery struct {
 	Offset int
 }
 
+type ListTrashQuery struct {
+	Limit  int
+	Offset int
+}
+
 type ListVersionsQuery struct {
 	ArticleID string
 	Limit     int
@@ -68,10 +175,29 @@ type ArticleUpdater interface {
 	UpdateArticle(ctx context.Context, articleID string, params UpdateArticleParams) (Article, error)
 }
 
+// ArticleDeleter moves an article to the trash. It disappears from gets,
+// listings and search but keeps its versions and tags until purged. A
+// scheduled publish is cancelled as by CancelScheduledPublish.
 type ArticleDeleter interface {
 	DeleteArticle(ctx context.Context, articleID string) error
 }
 
+// ArticleRestorer takes an article back out of the trash.
+type ArticleRestorer interface {
+	RestoreArticle(ctx context.Context, articleID string) (Article, error)
+}
+
+// TrashLister lists trashed articles, most recently deleted first.
+type TrashLister interface {
+	ListTrash(ctx context.Context, query ListTrashQuery) ([]Article, error)
+}
+
+// TrashPurger permanently deletes articles trashed before deletedBefore,
+// with their versions and tag links, and reports how many were removed.
+type TrashPurger interface {
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
 type ArticleGetter interface {
 	GetArticle(ctx context.Context, articleID string) (Article, error)
 }
@@ -80,6 +206,16 @@ type ArticleLister interface {
 	ListArticles(ctx context.Context, query ListArticlesQuery) ([]Article, error)
 }
 
+// ArticlePageLister lists with keyset cursors over a snapshot taken when the
+// first page is listed: each article keeps the sort position it had then, so
+// edits made while paging (an autosave bumping updated_at, say) neither skip
+// nor repeat rows. Articles created after the first page are left out.
+// Collection order and filters other than the updated range use current
+// values.
+type ArticlePageLister interface {
+	ListArticlesPage(ctx context.Context, query ListArticlesQuery) (ArticlePage, error)
+}
+
 type ArticleSearcher interface {
 	SearchArticles(ctx context.Context, query SearchArticlesQuery) ([]Article, error)
 }
@@ -88,10 +224,21 @@ type TagLister interface {
 	ListTags(ctx context.Context) ([]Tag, error)
 }
 
+type VersionGetter interface {
+	GetVersion(ctx context.Context, articleID string, version int) (ArticleVersion, error)
+}
+
 type VersionLister interface {
 	ListVersions(ctx context.Context, query ListVersionsQuery) ([]ArticleVersion, error)
 	GetVersion(ctx context.Context, articleID string, version int) (ArticleVersion, error)
-	RestoreVersion(ctx context.Context, articleID string, version int, restoredAt time.Time) (Article, error)
+	RestoreVersion(ctx context.Context, articleID string, params RestoreVersionParams) (Article, error)
+}
+
+// VersionCompactor deletes the autosave versions that a retention policy
+// drops. It works one article per transaction, so it can run while the app
+// is saving.
+type VersionCompactor interface {
+	CompactVersions(ctx context.Context, params CompactVersionsParams) (CompactVersionsResult, error)
 }
 
 type Repository interface {
@@ -0,0 +1,88 @@
+﻿package domain
+
+import (
+	"context"
+	"time"
+)
+
+type JobAction string
+
+const JobActionPublish JobAction = "publish"
+
+type JobState string
+
+const (
+	JobStatePending   JobState = "pending"
+	JobStateDone      JobState = "done"
+	JobStateFailed    JobState = "failed" // retries exhausted or the job cannot succeed
+	JobStateCancelled JobState = "cancelled"
+)
+
+func (s JobState) Valid() bool {
+	switch s {
+	case JobStatePending, JobStateDone, JobStateFailed, JobStateCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// ScheduledJob is a persisted time-based action on an article. RunAt is when
+// the job is next due, so it moves forward when a failed attempt is retried.
+type ScheduledJob struct {
+	ID         string
+	ArticleID  string
+	Action     JobAction
+	State      JobState
+	RunAt      time.Time
+	Attempts   int
+	LastError  string
+	FromStatus ArticleStatus // restored when a scheduled publish is cancelled
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type SchedulePublishParams struct {
+	ArticleID string
+	At        time.Time
+	Now       time.Time
+	Workflow  Workflow
+}
+
+type ListJobsQuery struct {
+	ArticleID string     // optional
+	States    []JobState // optional
+	Limit     int
+	Offset    int
+}
+
+type JobFailureParams struct {
+	JobID string
+	Err   string
+	At    time.Time
+	// RetryAt is when to try again; zero marks the job failed for good.
+	RetryAt time.Time
+}
+
+// PublishScheduler moves an article to scheduled and keeps one pending publish
+// job for it; scheduling it again moves the job. Cancelling returns the
+// article to the status it was scheduled from. Any other status change of a
+// scheduled article, e.g. an autosave forcing draft, cancels the job too, and
+// so does moving the article to the trash.
+type PublishScheduler interface {
+	SchedulePublish(ctx context.Context, params SchedulePublishParams) (ScheduledJob, error)
+	CancelScheduledPublish(ctx context.Context, articleID string, now time.Time) error
+}
+
+type JobLister interface {
+	ListJobs(ctx context.Context, query ListJobsQuery) ([]ScheduledJob, error)
+}
+
+// JobRunner is what the scheduler drives. RunJob performs a pending job and
+// marks it done in one transaction; a job that can never succeed fails with
+// ErrNotFound or ErrInvalidArgument.
+type JobRunner interface {
+	DueJobs(ctx context.Context, now time.Time, limit int) ([]ScheduledJob, error)
+	RunJob(ctx context.Context, jobID string, now time.Time) error
+	RecordJobFailure(ctx context.Context, params JobFailureParams) error
+}
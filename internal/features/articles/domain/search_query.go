@@ -0,0 +1,482 @@
+﻿package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxSearchQueryLength bounds the raw query accepted by ParseSearchQuery.
+const MaxSearchQueryLength = 512
+
+// SearchNodeKind identifies a node of a parsed search expression.
+type SearchNodeKind int
+
+const (
+	SearchTermNode SearchNodeKind = iota
+	SearchAndNode
+	SearchOrNode
+	SearchNotNode
+)
+
+// Search fields that scope a term to one part of the article.
+const (
+	SearchFieldTitle   = "title"
+	SearchFieldContent = "content"
+)
+
+// SearchNode is one node of the full-text part of a query. Terms carry Text
+// (a word or quoted phrase); And/Or have two or more Children and Not exactly
+// one.
+type SearchNode struct {
+	Kind     SearchNodeKind
+	Field    string
+	Text     string
+	Prefix   bool
+	Children []SearchNode
+}
+
+// ParsedSearchQuery splits a query into its full-text expression and the
+// filters that apply to article columns. Text is nil when the query only
+// filters.
+type ParsedSearchQuery struct {
+	Text        *SearchNode
+	Tags        []string
+	ExcludeTags []string
+	Status      *ArticleStatus
+}
+
+// ParseSearchQuery parses the article search syntax:
+//
+//	增长 黑客            both words (AND is implicit)
+//	"精确短语"           an exact phrase
+//	流量*                prefix match
+//	title:增长           match in the title only (also content:)
+//	-广告, NOT 广告       exclude a term
+//	a OR b, (a OR b) c   alternatives and grouping
+//	tag:运营, -tag:广告   require or exclude a tag
+//	status:published     filter by status
+//
+// AND, OR and NOT are operators only in upper case, and OR binds tighter
+// than AND, so "a b OR c" means a AND (b OR c). Filters must apply to the
+// whole query, so they cannot appear inside OR or parentheses. Errors wrap
+// ErrInvalidArgument and describe the problem.
+func ParseSearchQuery(query string) (ParsedSearchQuery, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return ParsedSearchQuery{}, invalidSearch("query is empty")
+	}
+	if n := utf8.RuneCountInString(query); n > MaxSearchQueryLength {
+		return ParsedSearchQuery{}, invalidSearch(fmt.Sprintf("query too long: max %d characters, got %d", MaxSearchQueryLength, n))
+	}
+	toks, err := lexSearch(query)
+	if err != nil {
+		return ParsedSearchQuery{}, err
+	}
+	p := &searchParser{toks: toks}
+	var out ParsedSearchQuery
+	node, err := p.parseTop(&out)
+	if err != nil {
+		return ParsedSearchQuery{}, err
+	}
+	if node != nil {
+		if err := checkPositive(*node); err != nil {
+			return ParsedSearchQuery{}, err
+		}
+		out.Text = node
+	}
+	return out, nil
+}
+
+func invalidSearch(msg string) error {
+	return errors.Join(ErrInvalidArgument, errors.New("search query: "+msg))
+}
+
+type searchTokenKind int
+
+const (
+	tokWord searchTokenKind = iota
+	tokPhrase
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokMinus
+)
+
+type searchToken struct {
+	kind   searchTokenKind
+	field  string
+	text   string
+	prefix bool
+	pos    int
+}
+
+func lexSearch(s string) ([]searchToken, error) {
+	var toks []searchToken
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			toks = append(toks, searchToken{kind: tokLParen, pos: i})
+			i += size
+		case r == ')':
+			toks = append(toks, searchToken{kind: tokRParen, pos: i})
+			i += size
+		case r == '-':
+			toks = append(toks, searchToken{kind: tokMinus, pos: i})
+			i += size
+		default:
+			tok, n, err := lexTerm(s, i)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, tok)
+			i = n
+		}
+	}
+	return toks, nil
+}
+
+// lexTerm reads an optional field prefix followed by a word or quoted phrase,
+// with an optional trailing '*'.
+func lexTerm(s string, start int) (searchToken, int, error) {
+	tok := searchToken{kind: tokWord, pos: start}
+	i := start
+	if j := strings.IndexByte(s[i:], ':'); j > 0 {
+		name := s[i : i+j]
+		if isFieldName(name) {
+			tok.field = strings.ToLower(name)
+			i += j + 1
+			if i >= len(s) || isTermEnd(s[i]) {
+				return tok, 0, invalidSearch(fmt.Sprintf("%s: needs a value at position %d", tok.field, start))
+			}
+		}
+	}
+
+	if s[i] == '"' {
+		end := strings.IndexByte(s[i+1:], '"')
+		if end < 0 {
+			return tok, 0, invalidSearch(fmt.Sprintf("unterminated quote at position %d", i))
+		}
+		tok.kind = tokPhrase
+		tok.text = s[i+1 : i+1+end]
+		i += end + 2
+		if strings.TrimSpace(tok.text) == "" {
+			return tok, 0, invalidSearch(fmt.Sprintf("empty phrase at position %d", start))
+		}
+	} else {
+		j := i
+		for j < len(s) && !isTermEnd(s[j]) && s[j] != '"' {
+			r, size := utf8.DecodeRuneInString(s[j:])
+			if unicode.IsSpace(r) {
+				break
+			}
+			j += size
+		}
+		tok.text = s[i:j]
+		i = j
+		if tok.field == "" && tok.text != "" {
+			switch tok.text {
+			case "AND":
+				tok.kind = tokAnd
+			case "OR":
+				tok.kind = tokOr
+			case "NOT":
+				tok.kind = tokNot
+			}
+		}
+	}
+
+	if i < len(s) && s[i] == '*' {
+		tok.prefix = true
+		i++
+	}
+	if i < len(s) {
+		if r, _ := utf8.DecodeRuneInString(s[i:]); !unicode.IsSpace(r) && r != '(' && r != ')' {
+			return tok, 0, invalidSearch(fmt.Sprintf("unexpected %q at position %d", r, i))
+		}
+	}
+	if tok.text == "" && tok.kind == tokWord {
+		return tok, 0, invalidSearch(fmt.Sprintf("unexpected '*' at position %d", start))
+	}
+	if tok.prefix && tok.kind != tokWord && tok.kind != tokPhrase {
+		return tok, 0, invalidSearch(fmt.Sprintf("operator %s cannot take '*' at position %d", tok.text, start))
+	}
+	return tok, i, nil
+}
+
+// isFieldName reports whether name is shaped like a field. Unknown fields
+// are rejected by the parser rather than searched as literal text.
+func isFieldName(name string) bool {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func isTermEnd(b byte) bool {
+	return b == '(' || b == ')' || b == '*' || b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+type searchParser struct {
+	toks []searchToken
+	pos  int
+}
+
+func (p *searchParser) peek() (searchToken, bool) {
+	if p.pos >= len(p.toks) {
+		return searchToken{}, false
+	}
+	return p.toks[p.pos], true
+}
+
+// afterTerm reports whether the previous token ends a term or group.
+func (p *searchParser) afterTerm() bool {
+	if p.pos == 0 {
+		return false
+	}
+	switch p.toks[p.pos-1].kind {
+	case tokWord, tokPhrase, tokRParen:
+		return true
+	}
+	return false
+}
+
+// parseTop parses the top-level conjunction, where filters are allowed.
+func (p *searchParser) parseTop(out *ParsedSearchQuery) (*SearchNode, error) {
+	var parts []SearchNode
+	for {
+		tok, ok := p.peek()
+		if !ok {
+			break
+		}
+		if tok.kind == tokRParen {
+			return nil, invalidSearch(fmt.Sprintf("unmatched ')' at position %d", tok.pos))
+		}
+		if tok.kind == tokAnd {
+			if !p.afterTerm() || p.pos == len(p.toks)-1 {
+				return nil, invalidSearch(fmt.Sprintf("AND at position %d needs a term on both sides", tok.pos))
+			}
+			p.pos++
+			continue
+		}
+		if handled, err := p.parseFilter(out); err != nil {
+			return nil, err
+		} else if handled {
+			continue
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, node)
+	}
+	return joinNodes(SearchAndNode, parts), nil
+}
+
+// parseFilter consumes a tag: or status: filter, optionally negated.
+func (p *searchParser) parseFilter(out *ParsedSearchQuery) (bool, error) {
+	tok, _ := p.peek()
+	negated := false
+	if tok.kind == tokMinus || tok.kind == tokNot {
+		if p.pos+1 >= len(p.toks) {
+			return false, nil
+		}
+		negated = true
+		tok = p.toks[p.pos+1]
+	}
+	if tok.field != "tag" && tok.field != "status" {
+		return false, nil
+	}
+	next := p.pos + 1
+	if negated {
+		next++
+	}
+	if next < len(p.toks) && p.toks[next].kind == tokOr {
+		return false, invalidSearch(tok.field + ": filters cannot be combined with OR")
+	}
+	if tok.prefix {
+		return false, invalidSearch(tok.field + ": does not support prefix matching")
+	}
+	switch tok.field {
+	case "tag":
+		name, err := NormalizeTagName(tok.text)
+		if err != nil {
+			return false, invalidSearch("tag: " + err.Error())
+		}
+		if negated {
+			out.ExcludeTags = append(out.ExcludeTags, name)
+		} else {
+			out.Tags = append(out.Tags, name)
+		}
+	case "status":
+		if negated {
+			return false, invalidSearch("status: cannot be negated")
+		}
+		st := ArticleStatus(strings.ToLower(strings.TrimSpace(tok.text)))
+		if !st.Valid() {
+			return false, invalidSearch(fmt.Sprintf("unknown status %q", tok.text))
+		}
+		if out.Status != nil && *out.Status != st {
+			return false, invalidSearch("status: given more than once")
+		}
+		out.Status = &st
+	}
+	p.pos = next
+	return true, nil
+}
+
+func (p *searchParser) parseOr() (SearchNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return SearchNode{}, err
+	}
+	parts := []SearchNode{left}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokOr {
+			break
+		}
+		p.pos++
+		if _, ok := p.peek(); !ok {
+			return SearchNode{}, invalidSearch(fmt.Sprintf("OR at position %d needs a right-hand term", tok.pos))
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return SearchNode{}, err
+		}
+		parts = append(parts, right)
+	}
+	return *joinNodes(SearchOrNode, parts), nil
+}
+
+// parseUnary parses an optionally negated primary. An explicit AND between
+// two terms is accepted and means the same as juxtaposition.
+func (p *searchParser) parseUnary() (SearchNode, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return SearchNode{}, invalidSearch("unexpected end of query")
+	}
+	switch tok.kind {
+	case tokMinus, tokNot:
+		p.pos++
+		if _, ok := p.peek(); !ok {
+			return SearchNode{}, invalidSearch(fmt.Sprintf("nothing to exclude after position %d", tok.pos))
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return SearchNode{}, err
+		}
+		return SearchNode{Kind: SearchNotNode, Children: []SearchNode{child}}, nil
+	case tokAnd:
+		if !p.afterTerm() {
+			return SearchNode{}, invalidSearch(fmt.Sprintf("AND at position %d needs a left-hand term", tok.pos))
+		}
+		p.pos++
+		if _, ok := p.peek(); !ok {
+			return SearchNode{}, invalidSearch(fmt.Sprintf("AND at position %d needs a right-hand term", tok.pos))
+		}
+		return p.parseUnary()
+	case tokOr:
+		return SearchNode{}, invalidSearch(fmt.Sprintf("OR at position %d needs a left-hand term", tok.pos))
+	case tokRParen:
+		return SearchNode{}, invalidSearch(fmt.Sprintf("unexpected ')' at position %d", tok.pos))
+	case tokLParen:
+		p.pos++
+		var parts []SearchNode
+		for {
+			next, ok := p.peek()
+			if !ok {
+				return SearchNode{}, invalidSearch(fmt.Sprintf("unclosed '(' at position %d", tok.pos))
+			}
+			if next.kind == tokRParen {
+				p.pos++
+				break
+			}
+			if next.field == "tag" || next.field == "status" {
+				return SearchNode{}, invalidSearch(next.field + ": filters cannot be used inside parentheses")
+			}
+			node, err := p.parseOr()
+			if err != nil {
+				return SearchNode{}, err
+			}
+			parts = append(parts, node)
+		}
+		if len(parts) == 0 {
+			return SearchNode{}, invalidSearch(fmt.Sprintf("empty parentheses at position %d", tok.pos))
+		}
+		return *joinNodes(SearchAndNode, parts), nil
+	}
+
+	if tok.field == "tag" || tok.field == "status" {
+		return SearchNode{}, invalidSearch(tok.field + ": filters must apply to the whole query")
+	}
+	if tok.field != "" && tok.field != SearchFieldTitle && tok.field != SearchFieldContent {
+		return SearchNode{}, invalidSearch(fmt.Sprintf("unknown field %q", tok.field))
+	}
+	if !hasSearchableRune(tok.text) {
+		return SearchNode{}, invalidSearch(fmt.Sprintf("%q has nothing to search for", tok.text))
+	}
+	p.pos++
+	return SearchNode{Kind: SearchTermNode, Field: tok.field, Text: tok.text, Prefix: tok.prefix}, nil
+}
+
+func joinNodes(kind SearchNodeKind, parts []SearchNode) *SearchNode {
+	switch len(parts) {
+	case 0:
+		return nil
+	case 1:
+		return &parts[0]
+	}
+	return &SearchNode{Kind: kind, Children: parts}
+}
+
+func hasSearchableRune(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPositive rejects expressions that could only exclude, since full-text
+// search needs something to match before it can subtract.
+func checkPositive(n SearchNode) error {
+	switch n.Kind {
+	case SearchNotNode:
+		return invalidSearch("a query cannot consist only of excluded terms")
+	case SearchOrNode:
+		for _, c := range n.Children {
+			if err := checkPositive(c); err != nil {
+				return invalidSearch("each side of OR needs a term to match")
+			}
+		}
+	case SearchAndNode:
+		positive := false
+		for _, c := range n.Children {
+			if c.Kind == SearchNotNode {
+				if err := checkPositive(c.Children[0]); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := checkPositive(c); err != nil {
+				return err
+			}
+			positive = true
+		}
+		if !positive {
+			return invalidSearch("a query cannot consist only of excluded terms")
+		}
+	}
+	return nil
+}
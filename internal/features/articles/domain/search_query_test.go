@@ -0,0 +1,86 @@
+﻿package domain_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	got, err := domain.ParseSearchQuery(`title:增长 tag:运营 -广告 "精确 短语" 流量* status:draft -tag:旧闻`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !reflect.DeepEqual(got.Tags, []string{"运营"}) || !reflect.DeepEqual(got.ExcludeTags, []string{"旧闻"}) {
+		t.Fatalf("unexpected tag filters: %+v", got)
+	}
+	if got.Status == nil || *got.Status != domain.ArticleStatusDraft {
+		t.Fatalf("unexpected status filter: %+v", got.Status)
+	}
+	want := &domain.SearchNode{Kind: domain.SearchAndNode, Children: []domain.SearchNode{
+		{Kind: domain.SearchTermNode, Field: domain.SearchFieldTitle, Text: "增长"},
+		{Kind: domain.SearchNotNode, Children: []domain.SearchNode{{Kind: domain.SearchTermNode, Text: "广告"}}},
+		{Kind: domain.SearchTermNode, Text: "精确 短语"},
+		{Kind: domain.SearchTermNode, Text: "流量", Prefix: true},
+	}}
+	if !reflect.DeepEqual(got.Text, want) {
+		t.Fatalf("unexpected expression:\n got %+v\nwant %+v", got.Text, want)
+	}
+
+	got, err = domain.ParseSearchQuery(`go AND (sqlite OR postgres) NOT orm`)
+	if err != nil {
+		t.Fatalf("parse operators: %v", err)
+	}
+	want = &domain.SearchNode{Kind: domain.SearchAndNode, Children: []domain.SearchNode{
+		{Kind: domain.SearchTermNode, Text: "go"},
+		{Kind: domain.SearchOrNode, Children: []domain.SearchNode{
+			{Kind: domain.SearchTermNode, Text: "sqlite"},
+			{Kind: domain.SearchTermNode, Text: "postgres"},
+		}},
+		{Kind: domain.SearchNotNode, Children: []domain.SearchNode{{Kind: domain.SearchTermNode, Text: "orm"}}},
+	}}
+	if !reflect.DeepEqual(got.Text, want) {
+		t.Fatalf("unexpected expression:\n got %+v\nwant %+v", got.Text, want)
+	}
+
+	got, err = domain.ParseSearchQuery(`tag:go`)
+	if err != nil || got.Text != nil || len(got.Tags) != 1 {
+		t.Fatalf("expected filter-only query, got %+v (%v)", got, err)
+	}
+}
+
+func TestParseSearchQuery_RejectsMalformedInput(t *testing.T) {
+	for _, q := range []string{
+		``,
+		`"unterminated`,
+		`""`,
+		`(增长`,
+		`增长)`,
+		`()`,
+		`a OR`,
+		`OR a`,
+		`AND a`,
+		`a AND`,
+		`-广告`,
+		`NOT a`,
+		`a OR -b`,
+		`author:someone`,
+		`title:`,
+		`*`,
+		`a*b`,
+		`!!!`,
+		`status:archivedd`,
+		`tag:go OR a`,
+		`a OR tag:go`,
+		`(a tag:go)`,
+		`tag:go*`,
+		`-status:draft`,
+		`status:draft status:published`,
+	} {
+		if _, err := domain.ParseSearchQuery(q); !errors.Is(err, domain.ErrInvalidArgument) {
+			t.Fatalf("query %q: expected ErrInvalidArgument, got %v", q, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+﻿package domain
+
+import "context"
+
+const (
+	DefaultSnippetRunes   = 120
+	MaxSnippetRunes       = 1000
+	DefaultHighlightOpen  = "<mark>"
+	DefaultHighlightClose = "</mark>"
+)
+
+// MatchRange is a half-open range of rune offsets.
+type MatchRange struct {
+	Start int
+	End   int
+}
+
+// SearchResultsQuery extends a search with how matches are presented.
+// HighlightOpen and HighlightClose wrap matches in HighlightedTitle and are
+// set together; both empty means <mark>. The title text around them is
+// always HTML-escaped, so HighlightedTitle can be inserted as HTML.
+type SearchResultsQuery struct {
+	SearchArticlesQuery
+	SnippetRunes   int
+	HighlightOpen  string
+	HighlightClose string
+}
+
+// SearchResult is one search hit with enough context to show in a result
+// list. Article.Content is left empty so large bodies are not passed around;
+// load the article to read it.
+type SearchResult struct {
+	Article Article
+	// Score is the negated BM25 rank, so higher is better. Queries made only
+	// of filters score 0.
+	Score            float64
+	HighlightedTitle string
+	// TitleMatches are offsets into Article.Title.
+	TitleMatches []MatchRange
+	// Snippet is a plain-text excerpt of the content around the first match,
+	// with "…" marking cut ends. SnippetMatches are offsets into Snippet.
+	// Only the start of long content is searched for the excerpt.
+	Snippet        string
+	SnippetMatches []MatchRange
+}
+
+type ArticleResultSearcher interface {
+	SearchArticleResults(ctx context.Context, query SearchResultsQuery) ([]SearchResult, error)
+}
@@ -1,6 +1,7 @@
 ﻿package domain
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -44,3 +45,54 @@ func NormalizeTagNames(names []string) ([]string, error) {
 	}
 	return out, nil
 }
+
+// TagUsage is a tag with how much it is used. ArticleCount and LastUsedAt
+// (the latest update of a tagged article) cover live articles; TrashedCount
+// counts tagged articles in the trash, which keep a tag from being unused.
+type TagUsage struct {
+	Tag
+	ArticleCount int
+	TrashedCount int
+	LastUsedAt   time.Time // zero when no live article has the tag
+}
+
+type TagUsageSort string
+
+const (
+	TagUsageSortName     TagUsageSort = "name"
+	TagUsageSortCount    TagUsageSort = "count"     // most used first
+	TagUsageSortLastUsed TagUsageSort = "last_used" // most recent first
+)
+
+func (s TagUsageSort) Valid() bool {
+	switch s {
+	case TagUsageSortName, TagUsageSortCount, TagUsageSortLastUsed:
+		return true
+	default:
+		return false
+	}
+}
+
+type RenameTagParams struct {
+	From string
+	To   string
+}
+
+type MergeTagsParams struct {
+	Sources []string
+	Target  string // created when it does not exist yet
+	Now     time.Time
+}
+
+// TagManager edits tags across all articles and keeps the search index in
+// step. Renames and merges remap the articles' current tags only; versions
+// keep the names they were saved with, so restoring one brings those names
+// back as ordinary tags.
+type TagManager interface {
+	ListTagUsage(ctx context.Context, sort TagUsageSort) ([]TagUsage, error)
+	RenameTag(ctx context.Context, params RenameTagParams) (Tag, error)
+	MergeTags(ctx context.Context, params MergeTagsParams) (Tag, error)
+	// DeleteUnusedTags removes tags no article, live or trashed, carries and
+	// reports how many were removed.
+	DeleteUnusedTags(ctx context.Context) (int, error)
+}
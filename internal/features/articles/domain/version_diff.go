@@ -0,0 +1,430 @@
+﻿package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffHunk is a run of text that is unchanged, inserted or deleted. Within a
+// change the deletion always comes before the insertion.
+type DiffHunk struct {
+	Op   DiffOp
+	Text string
+}
+
+// FieldChange records a scalar field that differs between two versions.
+type FieldChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// VersionDiff describes how the To side of an article differs from From.
+// ToVersion is 0 when To is the current article.
+type VersionDiff struct {
+	ArticleID   string
+	FromVersion int
+	ToVersion   int
+	Title       []DiffHunk
+	Content     []DiffHunk
+	Fields      []FieldChange // status, digest, author, cover_image, source_url, open_comments
+	TagsAdded   []string
+	TagsRemoved []string
+}
+
+// Changed reports whether the two sides differ at all.
+func (d VersionDiff) Changed() bool {
+	return hunksChanged(d.Title) || hunksChanged(d.Content) || len(d.Fields) > 0 || len(d.TagsAdded) > 0 || len(d.TagsRemoved) > 0
+}
+
+// VersionOfArticle returns the current state of a as an ArticleVersion, so it
+// can be diffed against a stored version.
+func VersionOfArticle(a Article) ArticleVersion {
+	tags := make([]string, 0, len(a.Tags))
+	for _, t := range a.Tags {
+		tags = append(tags, t.Name)
+	}
+	return ArticleVersion{
+		ArticleID:    a.ID,
+		Version:      a.CurrentVersion,
+		Title:        a.Title,
+		Content:      a.Content,
+		Status:       a.Status,
+		Digest:       a.Digest,
+		Author:       a.Author,
+		CoverImage:   a.CoverImage,
+		SourceURL:    a.SourceURL,
+		OpenComments: a.OpenComments,
+		Tags:         tags,
+		CreatedAt:    a.UpdatedAt,
+	}
+}
+
+// DiffVersions compares two versions of an article.
+func DiffVersions(from, to ArticleVersion) VersionDiff {
+	d := VersionDiff{
+		ArticleID:   from.ArticleID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Title:       DiffText(from.Title, to.Title),
+		Content:     DiffText(from.Content, to.Content),
+	}
+	for _, f := range []FieldChange{
+		{"status", string(from.Status), string(to.Status)},
+		{"digest", from.Digest, to.Digest},
+		{"author", from.Author, to.Author},
+		{"cover_image", from.CoverImage, to.CoverImage},
+		{"source_url", from.SourceURL, to.SourceURL},
+		{"open_comments", fmt.Sprint(from.OpenComments), fmt.Sprint(to.OpenComments)},
+	} {
+		if f.From != f.To {
+			d.Fields = append(d.Fields, f)
+		}
+	}
+	d.TagsAdded = missingFrom(to.Tags, from.Tags)
+	d.TagsRemoved = missingFrom(from.Tags, to.Tags)
+	return d
+}
+
+// missingFrom returns the sorted names in a that are not in b.
+func missingFrom(a, b []string) []string {
+	have := make(map[string]bool, len(b))
+	for _, s := range b {
+		have[s] = true
+	}
+	var out []string
+	for _, s := range a {
+		if !have[s] {
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// DiffText diffs a and b at word granularity, treating each CJK character as
+// a word. Lines are aligned first and only changed regions are diffed by
+// token, which keeps long articles with small edits cheap.
+func DiffText(a, b string) []DiffHunk {
+	if a == b {
+		if a == "" {
+			return nil
+		}
+		return []DiffHunk{{Op: DiffEqual, Text: a}}
+	}
+	la, lb := splitLines(a), splitLines(b)
+	match := matchSequences(la, lb)
+
+	var raw []DiffHunk
+	i, j := 0, 0
+	for i < len(la) || j < len(lb) {
+		if i < len(la) && match[i] >= 0 && match[i] == j {
+			raw = append(raw, DiffHunk{Op: DiffEqual, Text: la[i]})
+			i++
+			j++
+			continue
+		}
+		// Collect the changed region up to the next aligned line pair.
+		i0, j0 := i, j
+		for i < len(la) && match[i] < 0 {
+			i++
+		}
+		if i < len(la) {
+			j = match[i]
+		} else {
+			j = len(lb)
+		}
+		raw = append(raw, diffTokens(strings.Join(la[i0:i], ""), strings.Join(lb[j0:j], ""))...)
+	}
+	return normalizeHunks(absorbSmallEquals(raw))
+}
+
+// maxTokenDiffTokens caps the tokens on both sides of a changed region that
+// is diffed token by token. Larger regions, such as a rewritten paragraph of
+// thousands of CJK characters on one line, are reported as one deletion and
+// one insertion.
+const maxTokenDiffTokens = 20000
+
+func diffTokens(a, b string) []DiffHunk {
+	ta, tb := tokenize(a), tokenize(b)
+	if len(ta)+len(tb) > maxTokenDiffTokens {
+		var out []DiffHunk
+		if a != "" {
+			out = append(out, DiffHunk{Op: DiffDelete, Text: a})
+		}
+		if b != "" {
+			out = append(out, DiffHunk{Op: DiffInsert, Text: b})
+		}
+		return out
+	}
+	match := matchSequences(ta, tb)
+	out := make([]DiffHunk, 0, len(ta)+len(tb))
+	j := 0
+	for i, tok := range ta {
+		if match[i] < 0 {
+			out = append(out, DiffHunk{Op: DiffDelete, Text: tok})
+			continue
+		}
+		for ; j < match[i]; j++ {
+			out = append(out, DiffHunk{Op: DiffInsert, Text: tb[j]})
+		}
+		out = append(out, DiffHunk{Op: DiffEqual, Text: tok})
+		j++
+	}
+	for ; j < len(tb); j++ {
+		out = append(out, DiffHunk{Op: DiffInsert, Text: tb[j]})
+	}
+	return out
+}
+
+// tokenize splits s into CJK characters, runs of letters and digits, runs of
+// spaces, newlines and single other characters. Concatenating the tokens
+// gives back s.
+func tokenize(s string) []string {
+	var out []string
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		j := i + size
+		switch {
+		case isCJKRune(r) || r == '\n':
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			for j < len(s) {
+				r2, n := utf8.DecodeRuneInString(s[j:])
+				if isCJKRune(r2) || !(unicode.IsLetter(r2) || unicode.IsDigit(r2) || unicode.Is(unicode.Mn, r2)) {
+					break
+				}
+				j += n
+			}
+		case unicode.IsSpace(r):
+			for j < len(s) {
+				r2, n := utf8.DecodeRuneInString(s[j:])
+				if r2 == '\n' || !unicode.IsSpace(r2) {
+					break
+				}
+				j += n
+			}
+		}
+		out = append(out, s[i:j])
+		i = j
+	}
+	return out
+}
+
+func isCJKRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// absorbSmallEquals turns a single equal character caught between two
+// changes into part of the change. Character-level matching of Chinese
+// otherwise splits a rewritten phrase around every shared 的 or 了.
+func absorbSmallEquals(hunks []DiffHunk) []DiffHunk {
+	out := make([]DiffHunk, 0, len(hunks)+2)
+	for i, h := range hunks {
+		if h.Op == DiffEqual && i > 0 && i < len(hunks)-1 &&
+			hunks[i-1].Op != DiffEqual && hunks[i+1].Op != DiffEqual &&
+			utf8.RuneCountInString(h.Text) == 1 && h.Text != "\n" {
+			out = append(out, DiffHunk{Op: DiffDelete, Text: h.Text}, DiffHunk{Op: DiffInsert, Text: h.Text})
+			continue
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// normalizeHunks merges neighbours with the same op and orders each change
+// as one deletion followed by one insertion.
+func normalizeHunks(hunks []DiffHunk) []DiffHunk {
+	var (
+		out      []DiffHunk
+		del, ins strings.Builder
+		eq       strings.Builder
+	)
+	flushChange := func() {
+		if del.Len() > 0 {
+			out = append(out, DiffHunk{Op: DiffDelete, Text: del.String()})
+			del.Reset()
+		}
+		if ins.Len() > 0 {
+			out = append(out, DiffHunk{Op: DiffInsert, Text: ins.String()})
+			ins.Reset()
+		}
+	}
+	flushEqual := func() {
+		if eq.Len() > 0 {
+			out = append(out, DiffHunk{Op: DiffEqual, Text: eq.String()})
+			eq.Reset()
+		}
+	}
+	for _, h := range hunks {
+		switch h.Op {
+		case DiffEqual:
+			flushChange()
+			eq.WriteString(h.Text)
+		case DiffDelete:
+			flushEqual()
+			del.WriteString(h.Text)
+		case DiffInsert:
+			flushEqual()
+			ins.WriteString(h.Text)
+		}
+	}
+	flushChange()
+	flushEqual()
+	return out
+}
+
+func hunksChanged(hunks []DiffHunk) bool {
+	for _, h := range hunks {
+		if h.Op != DiffEqual {
+			return true
+		}
+	}
+	return false
+}
+
+// hunkSides rebuilds the two texts a hunk list was computed from.
+func hunkSides(hunks []DiffHunk) (string, string) {
+	var a, b strings.Builder
+	for _, h := range hunks {
+		if h.Op != DiffInsert {
+			a.WriteString(h.Text)
+		}
+		if h.Op != DiffDelete {
+			b.WriteString(h.Text)
+		}
+	}
+	return a.String(), b.String()
+}
+
+// Unified renders d as unified-diff text for terminals: title, fields and
+// tags as small sections, then the content as line hunks with contextLines of
+// context.
+func (d VersionDiff) Unified(contextLines int) string {
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	label := func(v int) string {
+		if v == 0 {
+			return d.ArticleID + "@current"
+		}
+		return fmt.Sprintf("%s@v%d", d.ArticleID, v)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", label(d.FromVersion), label(d.ToVersion))
+	if hunksChanged(d.Title) {
+		from, to := hunkSides(d.Title)
+		fmt.Fprintf(&b, "@@ title @@\n-%s\n+%s\n", from, to)
+	}
+	for _, f := range d.Fields {
+		fmt.Fprintf(&b, "@@ %s @@\n-%s\n+%s\n", f.Field, f.From, f.To)
+	}
+	if len(d.TagsAdded) > 0 || len(d.TagsRemoved) > 0 {
+		b.WriteString("@@ tags @@\n")
+		for _, t := range d.TagsRemoved {
+			fmt.Fprintf(&b, "-%s\n", t)
+		}
+		for _, t := range d.TagsAdded {
+			fmt.Fprintf(&b, "+%s\n", t)
+		}
+	}
+	if hunksChanged(d.Content) {
+		from, to := hunkSides(d.Content)
+		writeUnifiedLines(&b, splitLines(from), splitLines(to), contextLines)
+	}
+	return b.String()
+}
+
+type lineEdit struct {
+	op   byte // ' ', '-' or '+'
+	text string
+	a, b int // 0-based line numbers on each side before this edit
+}
+
+func writeUnifiedLines(b *strings.Builder, la, lb []string, contextLines int) {
+	match := matchSequences(la, lb)
+	var edits []lineEdit
+	i, j := 0, 0
+	for i < len(la) || j < len(lb) {
+		switch {
+		case i < len(la) && match[i] == j:
+			edits = append(edits, lineEdit{' ', la[i], i, j})
+			i++
+			j++
+		case i < len(la) && match[i] < 0:
+			edits = append(edits, lineEdit{'-', la[i], i, j})
+			i++
+		default:
+			edits = append(edits, lineEdit{'+', lb[j], i, j})
+			j++
+		}
+	}
+
+	for start := 0; start < len(edits); {
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			return
+		}
+		// Extend the hunk while changes are within 2*contextLines of each other.
+		end := start
+		for k := start; k < len(edits); k++ {
+			if edits[k].op != ' ' {
+				end = k + 1
+				continue
+			}
+			if k-end >= 2*contextLines {
+				break
+			}
+		}
+		lo := start - contextLines
+		if lo < 0 {
+			lo = 0
+		}
+		hi := end + contextLines
+		if hi > len(edits) {
+			hi = len(edits)
+		}
+		var na, nb int
+		for _, e := range edits[lo:hi] {
+			if e.op != '+' {
+				na++
+			}
+			if e.op != '-' {
+				nb++
+			}
+		}
+		fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(edits[lo].a, na), hunkRange(edits[lo].b, nb))
+		for _, e := range edits[lo:hi] {
+			b.WriteByte(e.op)
+			b.WriteString(e.text)
+			if !strings.HasSuffix(e.text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = hi
+	}
+}
+
+// hunkRange formats a unified-diff range; empty ranges point at the line
+// before them, as diff(1) does.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
@@ -0,0 +1,164 @@
+﻿package domain_test
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+func sides(hunks []domain.DiffHunk) (string, string) {
+	var a, b strings.Builder
+	for _, h := range hunks {
+		if h.Op != domain.DiffInsert {
+			a.WriteString(h.Text)
+		}
+		if h.Op != domain.DiffDelete {
+			b.WriteString(h.Text)
+		}
+	}
+	return a.String(), b.String()
+}
+
+func TestDiffText_ChineseAndWords(t *testing.T) {
+	got := domain.DiffText("我们今天讨论用户增长。", "我们明天讨论内容增长。")
+	want := []domain.DiffHunk{
+		{Op: domain.DiffEqual, Text: "我们"},
+		{Op: domain.DiffDelete, Text: "今"},
+		{Op: domain.DiffInsert, Text: "明"},
+		{Op: domain.DiffEqual, Text: "天讨论"},
+		{Op: domain.DiffDelete, Text: "用户"},
+		{Op: domain.DiffInsert, Text: "内容"},
+		{Op: domain.DiffEqual, Text: "增长。"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+
+	got = domain.DiffText("hello brave world", "hello new world")
+	want = []domain.DiffHunk{
+		{Op: domain.DiffEqual, Text: "hello "},
+		{Op: domain.DiffDelete, Text: "brave"},
+		{Op: domain.DiffInsert, Text: "new"},
+		{Op: domain.DiffEqual, Text: " world"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+
+	// A lone shared character inside a rewrite is folded into the change.
+	got = domain.DiffText("增长的飞轮", "留存的秘诀")
+	want = []domain.DiffHunk{
+		{Op: domain.DiffDelete, Text: "增长的飞轮"},
+		{Op: domain.DiffInsert, Text: "留存的秘诀"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestDiffText_RebuildsBothSides(t *testing.T) {
+	for _, tc := range [][2]string{
+		{"", "新内容"},
+		{"旧内容\n", ""},
+		{"第一段\n第二段\n第三段", "第一段\n插入段\n第二段改\n第三段"},
+		{"a\nb\nc\n", "a\nc\nd\n"},
+		{"same", "same"},
+	} {
+		hunks := domain.DiffText(tc[0], tc[1])
+		if a, b := sides(hunks); a != tc[0] || b != tc[1] {
+			t.Fatalf("diff of %q -> %q rebuilds %q -> %q", tc[0], tc[1], a, b)
+		}
+		for i := 1; i < len(hunks); i++ {
+			if hunks[i].Op == hunks[i-1].Op {
+				t.Fatalf("adjacent hunks share op %s: %+v", hunks[i].Op, hunks)
+			}
+		}
+	}
+}
+
+// TestDiffText_LargeCJKRewrite diffs unrelated single-line CJK texts, one
+// token per character, within a time and memory budget.
+func TestDiffText_LargeCJKRewrite(t *testing.T) {
+	for _, n := range []int{9000, 10000, 200000} {
+		a := strings.Repeat("增长飞轮留存", n/6)
+		b := strings.Repeat("内容运营秘诀", n/6)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		hunks := domain.DiffText(a, b)
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		if elapsed > 5*time.Second {
+			t.Fatalf("%d characters: diff took %v", n, elapsed)
+		}
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 256<<20 {
+			t.Fatalf("%d characters: diff allocated %d MB", n, alloc>>20)
+		}
+		if x, y := sides(hunks); x != a || y != b {
+			t.Fatalf("%d characters: diff does not rebuild both sides", n)
+		}
+	}
+}
+
+func TestDiffVersions_FieldsTagsAndUnified(t *testing.T) {
+	from := domain.ArticleVersion{
+		ArticleID: "a1", Version: 2, Title: "旧标题", Status: domain.ArticleStatusDraft,
+		Content: "第一行\n第二行\n第三行\n第四行\n第五行\n",
+		Tags:    []string{"go", "old"},
+	}
+	to := from
+	to.Version = 3
+	to.Title = "新标题"
+	to.Status = domain.ArticleStatusPublished
+	to.Content = "第一行\n第二行\n第三行改\n第四行\n第五行\n"
+	to.Tags = []string{"go", "new"}
+	to.OpenComments = true
+
+	d := domain.DiffVersions(from, to)
+	if !d.Changed() {
+		t.Fatalf("expected a change")
+	}
+	wantFields := []domain.FieldChange{
+		{Field: "status", From: "draft", To: "published"},
+		{Field: "open_comments", From: "false", To: "true"},
+	}
+	if !reflect.DeepEqual(d.Fields, wantFields) {
+		t.Fatalf("unexpected fields: %+v", d.Fields)
+	}
+	if !reflect.DeepEqual(d.TagsAdded, []string{"new"}) || !reflect.DeepEqual(d.TagsRemoved, []string{"old"}) {
+		t.Fatalf("unexpected tags: +%v -%v", d.TagsAdded, d.TagsRemoved)
+	}
+
+	want := `--- a1@v2
++++ a1@v3
+@@ title @@
+-旧标题
++新标题
+@@ status @@
+-draft
++published
+@@ open_comments @@
+-false
++true
+@@ tags @@
+-old
++new
+@@ -2,3 +2,3 @@
+ 第二行
+-第三行
++第三行改
+ 第四行
+`
+	if got := d.Unified(1); got != want {
+		t.Fatalf("unexpected unified diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	if domain.DiffVersions(from, from).Changed() {
+		t.Fatalf("identical versions must not report changes")
+	}
+}
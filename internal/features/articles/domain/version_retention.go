@@ -0,0 +1,114 @@
+﻿package domain
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultVersionRecentWindow   = 7 * 24 * time.Hour
+	DefaultVersionRecentInterval = 10 * time.Minute
+	DefaultMaxVersions           = 200
+)
+
+// VersionRetention decides which autosave versions survive compaction.
+// Manual versions and an article's current version are always kept; zero
+// fields take the Default* values.
+type VersionRetention struct {
+	// Autosaves younger than RecentWindow are thinned to the newest one per
+	// RecentInterval; older ones to the newest one per calendar day.
+	RecentWindow   time.Duration
+	RecentInterval time.Duration
+	// MaxVersions caps the versions kept per article by dropping the oldest
+	// remaining autosaves. Manual versions alone may still exceed it.
+	MaxVersions int
+}
+
+// VersionStamp is the part of a version that retention looks at.
+type VersionStamp struct {
+	Version    int
+	CreatedAt  time.Time
+	IsAutoSave bool
+}
+
+type CompactVersionsParams struct {
+	Retention VersionRetention
+	Now       time.Time
+}
+
+type CompactVersionsResult struct {
+	Articles int // articles that lost at least one version
+	Removed  int
+}
+
+func (p VersionRetention) WithDefaults() VersionRetention {
+	if p.RecentWindow == 0 {
+		p.RecentWindow = DefaultVersionRecentWindow
+	}
+	if p.RecentInterval == 0 {
+		p.RecentInterval = DefaultVersionRecentInterval
+	}
+	if p.MaxVersions == 0 {
+		p.MaxVersions = DefaultMaxVersions
+	}
+	return p
+}
+
+func (p VersionRetention) Validate() error {
+	if p.RecentWindow < 0 || p.RecentInterval < 0 {
+		return errors.New("retention durations must not be negative")
+	}
+	if p.MaxVersions < 0 {
+		return errors.New("max versions must not be negative")
+	}
+	return nil
+}
+
+// ExpiredVersions returns, in ascending order, the versions of one article
+// that p drops. Days are counted in now's location.
+func (p VersionRetention) ExpiredVersions(stamps []VersionStamp, current int, now time.Time) []int {
+	p = p.WithDefaults()
+	sorted := make([]VersionStamp, len(stamps))
+	copy(sorted, stamps)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version > sorted[j].Version })
+
+	type bucket struct {
+		recent bool
+		n      int64
+	}
+	seen := make(map[bucket]bool)
+	kept := make([]VersionStamp, 0, len(sorted))
+	var expired []int
+	recentFrom := now.Add(-p.RecentWindow)
+	for _, s := range sorted {
+		if !s.IsAutoSave || s.Version >= current {
+			kept = append(kept, s)
+			continue
+		}
+		var b bucket
+		if s.CreatedAt.After(recentFrom) {
+			b = bucket{recent: true, n: s.CreatedAt.UnixNano() / int64(p.RecentInterval)}
+		} else {
+			y, m, d := s.CreatedAt.In(now.Location()).Date()
+			b = bucket{n: int64(y)*10000 + int64(m)*100 + int64(d)}
+		}
+		if seen[b] {
+			expired = append(expired, s.Version)
+			continue
+		}
+		seen[b] = true
+		kept = append(kept, s)
+	}
+
+	// kept is newest first, so trimming from the end drops the oldest.
+	over := len(kept) - p.MaxVersions
+	for i := len(kept) - 1; i >= 0 && over > 0; i-- {
+		if s := kept[i]; s.IsAutoSave && s.Version < current {
+			expired = append(expired, s.Version)
+			over--
+		}
+	}
+	sort.Ints(expired)
+	return expired
+}
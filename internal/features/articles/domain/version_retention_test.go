@@ -0,0 +1,44 @@
+﻿package domain_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+func TestVersionRetention_ExpiredVersions(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	at := func(day, hour, minute int) time.Time { return time.Date(2025, 6, day, hour, minute, 0, 0, time.UTC) }
+	stamps := []domain.VersionStamp{
+		{Version: 1, CreatedAt: at(5, 9, 0)},
+		{Version: 2, CreatedAt: at(5, 10, 0), IsAutoSave: true},
+		{Version: 3, CreatedAt: at(5, 11, 0), IsAutoSave: true},
+		{Version: 4, CreatedAt: at(6, 8, 0), IsAutoSave: true},
+		{Version: 5, CreatedAt: at(9, 20, 0)},
+		{Version: 6, CreatedAt: at(10, 11, 1), IsAutoSave: true},
+		{Version: 7, CreatedAt: at(10, 11, 5), IsAutoSave: true},
+		{Version: 8, CreatedAt: at(10, 11, 25), IsAutoSave: true},
+	}
+	policy := domain.VersionRetention{RecentWindow: 24 * time.Hour, RecentInterval: 10 * time.Minute}
+
+	// One autosave per day before the window, one per ten minutes inside it.
+	if got := policy.ExpiredVersions(stamps, 8, now); !reflect.DeepEqual(got, []int{2, 6}) {
+		t.Fatalf("unexpected expired versions: %v", got)
+	}
+
+	// The cap removes the oldest autosaves but never manual or current ones.
+	policy.MaxVersions = 3
+	if got := policy.ExpiredVersions(stamps, 8, now); !reflect.DeepEqual(got, []int{2, 3, 4, 6, 7}) {
+		t.Fatalf("unexpected capped versions: %v", got)
+	}
+	policy.MaxVersions = 1
+	if got := policy.ExpiredVersions(stamps, 8, now); !reflect.DeepEqual(got, []int{2, 3, 4, 6, 7}) {
+		t.Fatalf("manual and current versions must survive the cap: %v", got)
+	}
+
+	if err := (domain.VersionRetention{MaxVersions: -1}).Validate(); err == nil {
+		t.Fatalf("expected negative cap to be rejected")
+	}
+}
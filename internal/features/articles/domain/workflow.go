@@ -0,0 +1,105 @@
+﻿package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidTransition = errors.New("articles: invalid status transition")
+
+// TransitionError is returned when a write would move an article between
+// statuses the workflow does not connect. It matches ErrInvalidTransition and
+// ErrInvalidArgument.
+type TransitionError struct {
+	From           ArticleStatus
+	To             ArticleStatus
+	ReviewRequired bool // the move is only allowed when review is optional
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf("articles: invalid status transition from %s to %s", e.From, e.To)
+	if e.ReviewRequired {
+		msg += ": review required"
+	}
+	return msg
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition || target == ErrInvalidArgument
+}
+
+// statusTransitions lists where each status may move when review is
+// optional. Staying in the same status is always allowed.
+var statusTransitions = map[ArticleStatus][]ArticleStatus{
+	ArticleStatusDraft:     {ArticleStatusInReview, ArticleStatusScheduled, ArticleStatusPublished, ArticleStatusArchived},
+	ArticleStatusInReview:  {ArticleStatusDraft, ArticleStatusApproved},
+	ArticleStatusApproved:  {ArticleStatusDraft, ArticleStatusScheduled, ArticleStatusPublished},
+	ArticleStatusScheduled: {ArticleStatusDraft, ArticleStatusApproved, ArticleStatusPublished},
+	ArticleStatusPublished: {ArticleStatusDraft, ArticleStatusArchived},
+	ArticleStatusArchived:  {ArticleStatusDraft},
+}
+
+// Workflow is the editorial transition table. The zero value lets drafts be
+// published or scheduled directly.
+type Workflow struct {
+	// RequireReview sends drafts through in_review and approved before they
+	// can be scheduled or published.
+	RequireReview bool
+}
+
+// Allows reports whether an article may move from one status to another.
+func (w Workflow) Allows(from, to ArticleStatus) bool {
+	if !from.Valid() || !to.Valid() {
+		return false
+	}
+	if from == to {
+		return true
+	}
+	if w.RequireReview && from == ArticleStatusDraft && (to == ArticleStatusScheduled || to == ArticleStatusPublished) {
+		return false
+	}
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the statuses an article in from may move to.
+func (w Workflow) Next(from ArticleStatus) []ArticleStatus {
+	out := make([]ArticleStatus, 0, len(statusTransitions[from]))
+	for _, s := range statusTransitions[from] {
+		if w.Allows(from, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// CheckTransition returns a *TransitionError unless w allows the move. New
+// articles are checked as a move out of draft.
+func (w Workflow) CheckTransition(from, to ArticleStatus) error {
+	if w.Allows(from, to) {
+		return nil
+	}
+	return &TransitionError{From: from, To: to, ReviewRequired: Workflow{}.Allows(from, to)}
+}
+
+// StatusChange is one row of an article's status history. From is empty for
+// the status an article was created in.
+type StatusChange struct {
+	ArticleID string
+	Version   int
+	From      ArticleStatus
+	To        ArticleStatus
+	Note      string
+	ChangedAt time.Time
+}
+
+// StatusHistoryLister returns an article's status changes, oldest first.
+type StatusHistoryLister interface {
+	ListStatusHistory(ctx context.Context, articleID string) ([]StatusChange, error)
+}
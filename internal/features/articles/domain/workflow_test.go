@@ -0,0 +1,60 @@
+﻿package domain_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+func TestWorkflow_Transitions(t *testing.T) {
+	direct := domain.Workflow{}
+	review := domain.Workflow{RequireReview: true}
+
+	if !direct.Allows(domain.ArticleStatusDraft, domain.ArticleStatusPublished) {
+		t.Fatalf("drafts publish directly without review")
+	}
+	if review.Allows(domain.ArticleStatusDraft, domain.ArticleStatusPublished) || review.Allows(domain.ArticleStatusDraft, domain.ArticleStatusScheduled) {
+		t.Fatalf("review must be required")
+	}
+	if !review.Allows(domain.ArticleStatusApproved, domain.ArticleStatusPublished) || !review.Allows(domain.ArticleStatusInReview, domain.ArticleStatusDraft) {
+		t.Fatalf("expected the review path to be direct")
+	}
+	if direct.Allows(domain.ArticleStatusArchived, domain.ArticleStatusPublished) || direct.Allows(domain.ArticleStatusDraft, "bogus") {
+		t.Fatalf("unexpected transition allowed")
+	}
+	if !direct.Allows(domain.ArticleStatusScheduled, domain.ArticleStatusScheduled) {
+		t.Fatalf("staying in a status is not a transition")
+	}
+	if got := review.Next(domain.ArticleStatusDraft); !reflect.DeepEqual(got, []domain.ArticleStatus{domain.ArticleStatusInReview, domain.ArticleStatusArchived}) {
+		t.Fatalf("unexpected next statuses: %v", got)
+	}
+
+	err := review.CheckTransition(domain.ArticleStatusDraft, domain.ArticleStatusPublished)
+	var te *domain.TransitionError
+	if !errors.As(err, &te) || !te.ReviewRequired || !errors.Is(err, domain.ErrInvalidTransition) || !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = direct.CheckTransition(domain.ArticleStatusArchived, domain.ArticleStatusPublished)
+	if !errors.As(err, &te) || te.ReviewRequired {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateArticleTransition(t *testing.T) {
+	w := domain.Workflow{RequireReview: true}
+	if err := domain.ValidateArticleTransition(w, domain.ArticleStatusDraft, domain.ArticleStatusInReview, "", "正文"); err == nil {
+		t.Fatalf("expected articles in review to need a title")
+	}
+	if err := domain.ValidateArticleTransition(w, domain.ArticleStatusDraft, domain.ArticleStatusInReview, "标题", "正文"); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	var te *domain.TransitionError
+	if err := domain.ValidateArticleTransition(w, domain.ArticleStatusDraft, domain.ArticleStatusPublished, "标题", "正文"); !errors.As(err, &te) {
+		t.Fatalf("expected *TransitionError, got %v", err)
+	}
+	if err := domain.ValidateArticleFields(domain.ArticleStatusArchived, "", ""); err != nil {
+		t.Fatalf("archived articles may be empty: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+type CreateCollectionInput struct {
+	Title       string
+	Description string
+	ArticleIDs  []string
+}
+
+type CreateCollectionUseCase struct {
+	Repo  domain.CollectionRepository
+	Clock domain.Clock
+	IDs   domain.IDGenerator
+}
+
+func NewCreateCollectionUseCase(repo domain.CollectionRepository) CreateCollectionUseCase {
+	return CreateCollectionUseCase{Repo: repo, Clock: systemClock{}, IDs: randomIDGenerator{}}
+}
+
+func (uc CreateCollectionUseCase) Execute(ctx context.Context, in CreateCollectionInput) (domain.Collection, error) {
+	if uc.Repo == nil {
+		return domain.Collection{}, errors.New("create collection: repo is nil")
+	}
+	if uc.Clock == nil {
+		uc.Clock = systemClock{}
+	}
+	if uc.IDs == nil {
+		uc.IDs = randomIDGenerator{}
+	}
+	title := strings.TrimSpace(in.Title)
+	if err := domain.ValidateCollectionFields(title, in.Description); err != nil {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	articleIDs, err := collectionArticleIDs(in.ArticleIDs)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+
+	id, err := uc.IDs.NewID()
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	return uc.Repo.CreateCollection(ctx, domain.CreateCollectionParams{
+		ID:          id,
+		Title:       title,
+		Description: in.Description,
+		ArticleIDs:  articleIDs,
+		CreatedAt:   uc.Clock.Now(),
+	})
+}
+
+type GetCollectionUseCase struct {
+	Repo domain.CollectionRepository
+}
+
+func NewGetCollectionUseCase(repo domain.CollectionRepository) GetCollectionUseCase {
+	return GetCollectionUseCase{Repo: repo}
+}
+
+func (uc GetCollectionUseCase) Execute(ctx context.Context, collectionID string) (domain.Collection, error) {
+	if uc.Repo == nil {
+		return domain.Collection{}, errors.New("get collection: repo is nil")
+	}
+	if collectionID == "" {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	return uc.Repo.GetCollection(ctx, collectionID)
+}
+
+type UpdateCollectionInput struct {
+	ID          string
+	Title       *string
+	Description *string
+}
+
+type UpdateCollectionUseCase struct {
+	Repo  domain.CollectionRepository
+	Clock domain.Clock
+}
+
+func NewUpdateCollectionUseCase(repo domain.CollectionRepository) UpdateCollectionUseCase {
+	return UpdateCollectionUseCase{Repo: repo, Clock: systemClock{}}
+}
+
+func (uc UpdateCollectionUseCase) Execute(ctx context.Context, in UpdateCollectionInput) (domain.Collection, error) {
+	if uc.Repo == nil {
+		return domain.Collection{}, errors.New("update collection: repo is nil")
+	}
+	if uc.Clock == nil {
+		uc.Clock = systemClock{}
+	}
+	if in.ID == "" {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	if in.Title == nil && in.Description == nil {
+		return domain.Collection{}, errors.Join(domain.ErrInvalidArgument, errors.New("no fields to update"))
+	}
+	if in.Title != nil {
+		title := strings.TrimSpace(*in.Title)
+		in.Title = &title
+	}
+	return uc.Repo.UpdateCollection(ctx, in.ID, domain.UpdateCollectionParams{
+		Title:       in.Title,
+		Description: in.Description,
+		UpdatedAt:   uc.Clock.Now(),
+	})
+}
+
+type DeleteCollectionUseCase struct {
+	Repo domain.CollectionRepository
+}
+
+func NewDeleteCollectionUseCase(repo domain.CollectionRepository) DeleteCollectionUseCase {
+	return DeleteCollectionUseCase{Repo: repo}
+}
+
+func (uc DeleteCollectionUseCase) Execute(ctx context.Context, collectionID string) error {
+	if uc.Repo == nil {
+		return errors.New("delete collection: repo is nil")
+	}
+	if collectionID == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	return uc.Repo.DeleteCollection(ctx, collectionID)
+}
+
+type ListCollectionsInput struct {
+	Limit  int
+	Offset int
+}
+
+type ListCollectionsUseCase struct {
+	Repo domain.CollectionRepository
+}
+
+func NewListCollectionsUseCase(repo domain.CollectionRepository) ListCollectionsUseCase {
+	return ListCollectionsUseCase{Repo: repo}
+}
+
+func (uc ListCollectionsUseCase) Execute(ctx context.Context, in ListCollectionsInput) ([]domain.Collection, error) {
+	if uc.Repo == nil {
+		return nil, errors.New("list collections: repo is nil")
+	}
+	limit := in.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := in.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	return uc.Repo.ListCollections(ctx, domain.ListCollectionsQuery{Limit: limit, Offset: offset})
+}
+
+type CollectionMembersInput struct {
+	CollectionID string
+	ArticleIDs   []string
+}
+
+// AddToCollectionUseCase appends articles to the end of a collection.
+type AddToCollectionUseCase struct {
+	Repo  domain.CollectionRepository
+	Clock domain.Clock
+}
+
+func NewAddToCollectionUseCase(repo domain.CollectionRepository) AddToCollectionUseCase {
+	return AddToCollectionUseCase{Repo: repo, Clock: systemClock{}}
+}
+
+func (uc AddToCollectionUseCase) Execute(ctx context.Context, in CollectionMembersInput) (domain.Collection, error) {
+	if uc.Repo == nil {
+		return domain.Collection{}, errors.New("add to collection: repo is nil")
+	}
+	params, err := collectionMembersParams(in, uc.Clock, false)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	return uc.Repo.AddToCollection(ctx, params)
+}
+
+type RemoveFromCollectionUseCase struct {
+	Repo  domain.CollectionRepository
+	Clock domain.Clock
+}
+
+func NewRemoveFromCollectionUseCase(repo domain.CollectionRepository) RemoveFromCollectionUseCase {
+	return RemoveFromCollectionUseCase{Repo: repo, Clock: systemClock{}}
+}
+
+func (uc RemoveFromCollectionUseCase) Execute(ctx context.Context, in CollectionMembersInput) (domain.Collection, error) {
+	if uc.Repo == nil {
+		return domain.Collection{}, errors.New("remove from collection: repo is nil")
+	}
+	params, err := collectionMembersParams(in, uc.Clock, false)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	return uc.Repo.RemoveFromCollection(ctx, params)
+}
+
+// ReorderCollectionUseCase sets the order of a collection; ArticleIDs must
+// list every live member exactly once.
+type ReorderCollectionUseCase struct {
+	Repo  domain.CollectionRepository
+	Clock domain.Clock
+}
+
+func NewReorderCollectionUseCase(repo domain.CollectionRepository) ReorderCollectionUseCase {
+	return ReorderCollectionUseCase{Repo: repo, Clock: systemClock{}}
+}
+
+func (uc ReorderCollectionUseCase) Execute(ctx context.Context, in CollectionMembersInput) (domain.Collection, error) {
+	if uc.Repo == nil {
+		return domain.Collection{}, errors.New("reorder collection: repo is nil")
+	}
+	params, err := collectionMembersParams(in, uc.Clock, true)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	return uc.Repo.ReorderCollection(ctx, params)
+}
+
+type ArticleCollectionsUseCase struct {
+	Repo domain.CollectionRepository
+}
+
+func NewArticleCollectionsUseCase(repo domain.CollectionRepository) ArticleCollectionsUseCase {
+	return ArticleCollectionsUseCase{Repo: repo}
+}
+
+func (uc ArticleCollectionsUseCase) Execute(ctx context.Context, articleID string) ([]domain.Collection, error) {
+	if uc.Repo == nil {
+		return nil, errors.New("article collections: repo is nil")
+	}
+	if articleID == "" {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	return uc.Repo.CollectionsOfArticle(ctx, articleID)
+}
+
+func collectionMembersParams(in CollectionMembersInput, clock domain.Clock, keepDuplicates bool) (domain.CollectionMembersParams, error) {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	if in.CollectionID == "" {
+		return domain.CollectionMembersParams{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	ids := in.ArticleIDs
+	if !keepDuplicates {
+		var err error
+		if ids, err = collectionArticleIDs(ids); err != nil {
+			return domain.CollectionMembersParams{}, err
+		}
+		if len(ids) == 0 {
+			return domain.CollectionMembersParams{}, errors.Join(domain.ErrInvalidArgument, errors.New("no articles given"))
+		}
+	}
+	return domain.CollectionMembersParams{CollectionID: in.CollectionID, ArticleIDs: ids, At: clock.Now()}, nil
+}
+
+// collectionArticleIDs drops duplicates, keeping the first occurrence.
+func collectionArticleIDs(ids []string) ([]string, error) {
+	out := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id == "" {
+			return nil, errors.Join(domain.ErrInvalidArgument, errors.New("article id is required"))
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	if len(out) > domain.MaxCollectionArticles {
+		return nil, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("too many articles: max %d, got %d", domain.MaxCollectionArticles, len(out)))
+	}
+	return out, nil
+}
@@ -0,0 +1,34 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+// CompactVersionsUseCase thins out autosave versions according to Retention.
+// Like PurgeTrashUseCase it is meant to run periodically, and it is safe to
+// run while articles are being edited.
+type CompactVersionsUseCase struct {
+	Repo      domain.VersionCompactor
+	Clock     domain.Clock
+	Retention domain.VersionRetention
+}
+
+func NewCompactVersionsUseCase(repo domain.VersionCompactor, retention domain.VersionRetention) CompactVersionsUseCase {
+	return CompactVersionsUseCase{Repo: repo, Clock: systemClock{}, Retention: retention}
+}
+
+func (uc CompactVersionsUseCase) Execute(ctx context.Context) (domain.CompactVersionsResult, error) {
+	if uc.Repo == nil {
+		return domain.CompactVersionsResult{}, errors.New("compact versions: repo is nil")
+	}
+	if uc.Clock == nil {
+		uc.Clock = systemClock{}
+	}
+	if err := uc.Retention.Validate(); err != nil {
+		return domain.CompactVersionsResult{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	return uc.Repo.CompactVersions(ctx, domain.CompactVersionsParams{Retention: uc.Retention.WithDefaults(), Now: uc.Clock.Now()})
+}
@@ -25,16 +25,22 @@ func (randomIDGenerator) NewID() (string, error) {
 }
 
 type CreateArticleInput struct {
-	Title   string
-	Content string
-	Status  domain.ArticleStatus
-	Tags    []string
+	Title        string
+	Content      string
+	Status       domain.ArticleStatus
+	Tags         []string
+	Digest       string
+	Author       string
+	CoverImage   string
+	SourceURL    string
+	OpenComments bool
 }
 
 type CreateArticleUseCase struct {
-	Repo  domain.ArticleCreator
-	Clock domain.Clock
-	IDs   domain.IDGenerator
+	Repo     domain.ArticleCreator
+	Clock    domain.Clock
+	IDs      domain.IDGenerator
+	Workflow domain.Workflow
 }
 
 func NewCreateArticleUseCase(repo domain.ArticleCreator) CreateArticleUseCase {
@@ -65,7 +71,10 @@ func (uc CreateArticleUseCase) Execute(ctx context.Context, in CreateArticleInpu
 		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, err)
 	}
 
-	if err := domain.ValidateArticleFields(status, in.Title, in.Content); err != nil {
+	if err := domain.ValidateArticleTransition(uc.Workflow, domain.ArticleStatusDraft, status, in.Title, in.Content); err != nil {
+		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	if err := domain.ValidateArticleMetadata(in.Digest, in.Author, in.CoverImage, in.SourceURL); err != nil {
 		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, err)
 	}
 
@@ -76,12 +85,18 @@ func (uc CreateArticleUseCase) Execute(ctx context.Context, in CreateArticleInpu
 
 	now := uc.Clock.Now()
 	return uc.Repo.CreateArticle(ctx, domain.CreateArticleParams{
-		ID:        id,
-		Title:     in.Title,
-		Content:   in.Content,
-		Status:    status,
-		Tags:      normalizedTags,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:           id,
+		Title:        in.Title,
+		Content:      in.Content,
+		Status:       status,
+		Digest:       in.Digest,
+		Author:       in.Author,
+		CoverImage:   in.CoverImage,
+		SourceURL:    in.SourceURL,
+		OpenComments: in.OpenComments,
+		Tags:         normalizedTags,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Workflow:     uc.Workflow,
 	})
 }
@@ -0,0 +1,60 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+type DiffVersionsInput struct {
+	ArticleID   string
+	FromVersion int
+	ToVersion   int // 0 compares against the current article
+}
+
+type DiffVersionsUseCase struct {
+	Versions domain.VersionGetter
+	Articles domain.ArticleGetter
+}
+
+func NewDiffVersionsUseCase(versions domain.VersionGetter, articles domain.ArticleGetter) DiffVersionsUseCase {
+	return DiffVersionsUseCase{Versions: versions, Articles: articles}
+}
+
+func (uc DiffVersionsUseCase) Execute(ctx context.Context, in DiffVersionsInput) (domain.VersionDiff, error) {
+	if uc.Versions == nil {
+		return domain.VersionDiff{}, errors.New("diff versions: versions repo is nil")
+	}
+	if in.ArticleID == "" {
+		return domain.VersionDiff{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	if in.FromVersion <= 0 || in.ToVersion < 0 {
+		return domain.VersionDiff{}, errors.Join(domain.ErrInvalidArgument, errors.New("version must be positive"))
+	}
+
+	from, err := uc.Versions.GetVersion(ctx, in.ArticleID, in.FromVersion)
+	if err != nil {
+		return domain.VersionDiff{}, err
+	}
+	var to domain.ArticleVersion
+	if in.ToVersion == 0 {
+		if uc.Articles == nil {
+			return domain.VersionDiff{}, errors.New("diff versions: articles repo is nil")
+		}
+		article, err := uc.Articles.GetArticle(ctx, in.ArticleID)
+		if err != nil {
+			return domain.VersionDiff{}, err
+		}
+		to = domain.VersionOfArticle(article)
+	} else {
+		to, err = uc.Versions.GetVersion(ctx, in.ArticleID, in.ToVersion)
+		if err != nil {
+			return domain.VersionDiff{}, err
+		}
+	}
+
+	d := domain.DiffVersions(from, to)
+	d.ToVersion = in.ToVersion
+	return d, nil
+}
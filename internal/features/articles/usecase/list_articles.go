@@ -3,15 +3,28 @@
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
 )
 
 type ListArticlesInput struct {
-	Status *domain.ArticleStatus
-	Tag    *string
-	Limit  int
-	Offset int
+	Status       *domain.ArticleStatus
+	Tag          *string
+	Statuses     []domain.ArticleStatus
+	Tags         []string
+	TagMatch     domain.TagMatch
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+	UpdatedFrom  time.Time
+	UpdatedTo    time.Time
+	CollectionID string
+	Sort         domain.ArticleSort
+	Ascending    bool
+	Limit        int
+	Offset       int
 }
 
 type ListArticlesUseCase struct {
@@ -26,8 +39,68 @@ func (uc ListArticlesUseCase) Execute(ctx context.Context, in ListArticlesInput)
 	if uc.Repo == nil {
 		return nil, errors.New("list articles: repo is nil")
 	}
+	query, err := listQuery(in)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Repo.ListArticles(ctx, query)
+}
+
+type ListArticlesPageInput struct {
+	ListArticlesInput
+	Cursor string
+}
+
+type ListArticlesPageUseCase struct {
+	Repo domain.ArticlePageLister
+}
+
+func NewListArticlesPageUseCase(repo domain.ArticlePageLister) ListArticlesPageUseCase {
+	return ListArticlesPageUseCase{Repo: repo}
+}
+
+func (uc ListArticlesPageUseCase) Execute(ctx context.Context, in ListArticlesPageInput) (domain.ArticlePage, error) {
+	if uc.Repo == nil {
+		return domain.ArticlePage{}, errors.New("list articles page: repo is nil")
+	}
+	if in.Offset != 0 {
+		return domain.ArticlePage{}, errors.Join(domain.ErrInvalidArgument, errors.New("offset cannot be used with cursor paging"))
+	}
+	query, err := listQuery(in.ListArticlesInput)
+	if err != nil {
+		return domain.ArticlePage{}, err
+	}
+	query.Cursor = strings.TrimSpace(in.Cursor)
+	return uc.Repo.ListArticlesPage(ctx, query)
+}
+
+func listQuery(in ListArticlesInput) (domain.ListArticlesQuery, error) {
 	if in.Status != nil && !in.Status.Valid() {
-		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("invalid status"))
+		return domain.ListArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, errors.New("invalid status"))
+	}
+	for _, st := range in.Statuses {
+		if !st.Valid() {
+			return domain.ListArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("invalid status %q", st))
+		}
+	}
+	tags, err := domain.NormalizeTagNames(in.Tags)
+	if err != nil {
+		return domain.ListArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	if in.TagMatch != "" && !in.TagMatch.Valid() {
+		return domain.ListArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, errors.New("invalid tag match"))
+	}
+	if in.Sort != "" && !in.Sort.Valid() {
+		return domain.ListArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, errors.New("invalid sort"))
+	}
+	if in.Sort == domain.ArticleSortCollection && in.CollectionID == "" {
+		return domain.ListArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, errors.New("collection order needs a collection"))
+	}
+	if !in.CreatedFrom.IsZero() && !in.CreatedTo.IsZero() && !in.CreatedFrom.Before(in.CreatedTo) {
+		return domain.ListArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, errors.New("created range is empty"))
+	}
+	if !in.UpdatedFrom.IsZero() && !in.UpdatedTo.IsZero() && !in.UpdatedFrom.Before(in.UpdatedTo) {
+		return domain.ListArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, errors.New("updated range is empty"))
 	}
 
 	limit := in.Limit
@@ -42,10 +115,20 @@ func (uc ListArticlesUseCase) Execute(ctx context.Context, in ListArticlesInput)
 		offset = 0
 	}
 
-	return uc.Repo.ListArticles(ctx, domain.ListArticlesQuery{
-		Status: in.Status,
-		Tag:    in.Tag,
-		Limit:  limit,
-		Offset: offset,
-	})
+	return domain.ListArticlesQuery{
+		Status:       in.Status,
+		Tag:          in.Tag,
+		Statuses:     in.Statuses,
+		Tags:         tags,
+		TagMatch:     in.TagMatch,
+		CreatedFrom:  in.CreatedFrom,
+		CreatedTo:    in.CreatedTo,
+		UpdatedFrom:  in.UpdatedFrom,
+		UpdatedTo:    in.UpdatedTo,
+		CollectionID: in.CollectionID,
+		Sort:         in.Sort,
+		Ascending:    in.Ascending,
+		Limit:        limit,
+		Offset:       offset,
+	}, nil
 }
@@ -0,0 +1,107 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+type SchedulePublishInput struct {
+	ID string
+	At time.Time
+}
+
+type SchedulePublishUseCase struct {
+	Repo     domain.PublishScheduler
+	Clock    domain.Clock
+	Workflow domain.Workflow
+}
+
+func NewSchedulePublishUseCase(repo domain.PublishScheduler) SchedulePublishUseCase {
+	return SchedulePublishUseCase{Repo: repo, Clock: systemClock{}}
+}
+
+// Execute schedules the article, or moves its existing schedule, to be
+// published at in.At.
+func (uc SchedulePublishUseCase) Execute(ctx context.Context, in SchedulePublishInput) (domain.ScheduledJob, error) {
+	if uc.Repo == nil {
+		return domain.ScheduledJob{}, errors.New("schedule publish: repo is nil")
+	}
+	if uc.Clock == nil {
+		uc.Clock = systemClock{}
+	}
+	if in.ID == "" {
+		return domain.ScheduledJob{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	now := uc.Clock.Now()
+	if in.At.IsZero() || in.At.Before(now) {
+		return domain.ScheduledJob{}, errors.Join(domain.ErrInvalidArgument, errors.New("publish time must be in the future"))
+	}
+	return uc.Repo.SchedulePublish(ctx, domain.SchedulePublishParams{ArticleID: in.ID, At: in.At, Now: now, Workflow: uc.Workflow})
+}
+
+type CancelScheduledPublishInput struct {
+	ID string
+}
+
+type CancelScheduledPublishUseCase struct {
+	Repo  domain.PublishScheduler
+	Clock domain.Clock
+}
+
+func NewCancelScheduledPublishUseCase(repo domain.PublishScheduler) CancelScheduledPublishUseCase {
+	return CancelScheduledPublishUseCase{Repo: repo, Clock: systemClock{}}
+}
+
+func (uc CancelScheduledPublishUseCase) Execute(ctx context.Context, in CancelScheduledPublishInput) error {
+	if uc.Repo == nil {
+		return errors.New("cancel scheduled publish: repo is nil")
+	}
+	if uc.Clock == nil {
+		uc.Clock = systemClock{}
+	}
+	if in.ID == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	return uc.Repo.CancelScheduledPublish(ctx, in.ID, uc.Clock.Now())
+}
+
+type ListJobsInput struct {
+	ArticleID string
+	States    []domain.JobState
+	Limit     int
+	Offset    int
+}
+
+type ListJobsUseCase struct {
+	Repo domain.JobLister
+}
+
+func NewListJobsUseCase(repo domain.JobLister) ListJobsUseCase {
+	return ListJobsUseCase{Repo: repo}
+}
+
+func (uc ListJobsUseCase) Execute(ctx context.Context, in ListJobsInput) ([]domain.ScheduledJob, error) {
+	if uc.Repo == nil {
+		return nil, errors.New("list jobs: repo is nil")
+	}
+	for _, s := range in.States {
+		if !s.Valid() {
+			return nil, errors.Join(domain.ErrInvalidArgument, errors.New("invalid job state"))
+		}
+	}
+	limit := in.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := in.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	return uc.Repo.ListJobs(ctx, domain.ListJobsQuery{ArticleID: in.ArticleID, States: in.States, Limit: limit, Offset: offset})
+}
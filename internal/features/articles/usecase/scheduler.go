@@ -0,0 +1,136 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+const (
+	DefaultJobMaxAttempts  = 5
+	DefaultJobRetryBackoff = time.Minute
+	DefaultJobMaxBackoff   = time.Hour
+	DefaultSchedulerPoll   = 30 * time.Second
+	dueJobsBatch           = 20
+)
+
+// RetryPolicy spaces out attempts of a failing job: retry n waits
+// Backoff * 2^(n-1), at most MaxBackoff. Zero fields take the defaults.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultJobMaxAttempts
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = DefaultJobRetryBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultJobMaxBackoff
+	}
+	return p
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Scheduler runs due jobs in process. Jobs live in the database, so nothing
+// is lost when the app stops: whatever fell due meanwhile runs on the next
+// RunDue, which Run calls at startup.
+type Scheduler struct {
+	Jobs  domain.JobRunner
+	Clock domain.Clock
+	Retry RetryPolicy
+	// Interval is how often Run polls for due jobs.
+	Interval time.Duration
+	// OnError, if set, receives failed attempts, and store errors with a zero
+	// job.
+	OnError func(job domain.ScheduledJob, err error)
+}
+
+func NewScheduler(jobs domain.JobRunner) Scheduler {
+	return Scheduler{Jobs: jobs, Clock: systemClock{}, Interval: DefaultSchedulerPoll}
+}
+
+// RunDue runs every job due at the clock's current time and reports how many
+// succeeded. A failed job is retried later according to Retry, unless the
+// error says it can never succeed.
+func (s Scheduler) RunDue(ctx context.Context) (int, error) {
+	if s.Jobs == nil {
+		return 0, errors.New("scheduler: jobs store is nil")
+	}
+	if s.Clock == nil {
+		s.Clock = systemClock{}
+	}
+	retry := s.Retry.withDefaults()
+	now := s.Clock.Now()
+
+	ran := 0
+	for {
+		jobs, err := s.Jobs.DueJobs(ctx, now, dueJobsBatch)
+		if err != nil {
+			return ran, err
+		}
+		for _, job := range jobs {
+			err := s.Jobs.RunJob(ctx, job.ID, now)
+			if err == nil {
+				ran++
+				continue
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ran, ctxErr
+			}
+			if s.OnError != nil {
+				s.OnError(job, err)
+			}
+			failure := domain.JobFailureParams{JobID: job.ID, Err: err.Error(), At: now}
+			permanent := errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrInvalidArgument)
+			if attempt := job.Attempts + 1; !permanent && attempt < retry.MaxAttempts {
+				failure.RetryAt = now.Add(retry.delay(attempt))
+			}
+			// Every due job either succeeds or moves out of the due set here,
+			// so the next batch makes progress.
+			if err := s.Jobs.RecordJobFailure(ctx, failure); err != nil {
+				return ran, err
+			}
+		}
+		if len(jobs) < dueJobsBatch {
+			return ran, nil
+		}
+	}
+}
+
+// Run calls RunDue at startup and then every Interval until ctx is done.
+func (s Scheduler) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSchedulerPoll
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(ctx); err != nil && ctx.Err() == nil && s.OnError != nil {
+			s.OnError(domain.ScheduledJob{}, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -3,6 +3,7 @@
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
@@ -28,12 +29,23 @@ func (uc SearchArticlesUseCase) Execute(ctx context.Context, in SearchArticlesIn
 	if uc.Repo == nil {
 		return nil, errors.New("search articles: repo is nil")
 	}
+	query, err := searchQuery(in)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Repo.SearchArticles(ctx, query)
+}
+
+func searchQuery(in SearchArticlesInput) (domain.SearchArticlesQuery, error) {
 	q := strings.TrimSpace(in.Query)
 	if q == "" {
-		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("query is required"))
+		return domain.SearchArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, errors.New("query is required"))
+	}
+	if _, err := domain.ParseSearchQuery(q); err != nil {
+		return domain.SearchArticlesQuery{}, err
 	}
 	if in.Status != nil && !in.Status.Valid() {
-		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("invalid status"))
+		return domain.SearchArticlesQuery{}, errors.Join(domain.ErrInvalidArgument, errors.New("invalid status"))
 	}
 
 	limit := in.Limit
@@ -48,11 +60,48 @@ func (uc SearchArticlesUseCase) Execute(ctx context.Context, in SearchArticlesIn
 		offset = 0
 	}
 
-	return uc.Repo.SearchArticles(ctx, domain.SearchArticlesQuery{
+	return domain.SearchArticlesQuery{
 		Query:  q,
 		Status: in.Status,
 		Tag:    in.Tag,
 		Limit:  limit,
 		Offset: offset,
+	}, nil
+}
+
+type SearchArticleResultsInput struct {
+	SearchArticlesInput
+	SnippetRunes   int
+	HighlightOpen  string
+	HighlightClose string
+}
+
+type SearchArticleResultsUseCase struct {
+	Repo domain.ArticleResultSearcher
+}
+
+func NewSearchArticleResultsUseCase(repo domain.ArticleResultSearcher) SearchArticleResultsUseCase {
+	return SearchArticleResultsUseCase{Repo: repo}
+}
+
+func (uc SearchArticleResultsUseCase) Execute(ctx context.Context, in SearchArticleResultsInput) ([]domain.SearchResult, error) {
+	if uc.Repo == nil {
+		return nil, errors.New("search article results: repo is nil")
+	}
+	query, err := searchQuery(in.SearchArticlesInput)
+	if err != nil {
+		return nil, err
+	}
+	if in.SnippetRunes < 0 || in.SnippetRunes > domain.MaxSnippetRunes {
+		return nil, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("snippet length must be between 0 and %d", domain.MaxSnippetRunes))
+	}
+	if (in.HighlightOpen == "") != (in.HighlightClose == "") {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("highlight markers must be set together"))
+	}
+	return uc.Repo.SearchArticleResults(ctx, domain.SearchResultsQuery{
+		SearchArticlesQuery: query,
+		SnippetRunes:        in.SnippetRunes,
+		HighlightOpen:       in.HighlightOpen,
+		HighlightClose:      in.HighlightClose,
 	})
 }
@@ -0,0 +1,30 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+type ListStatusHistoryInput struct {
+	ID string
+}
+
+type ListStatusHistoryUseCase struct {
+	Repo domain.StatusHistoryLister
+}
+
+func NewListStatusHistoryUseCase(repo domain.StatusHistoryLister) ListStatusHistoryUseCase {
+	return ListStatusHistoryUseCase{Repo: repo}
+}
+
+func (uc ListStatusHistoryUseCase) Execute(ctx context.Context, in ListStatusHistoryInput) ([]domain.StatusChange, error) {
+	if uc.Repo == nil {
+		return nil, errors.New("list status history: repo is nil")
+	}
+	if in.ID == "" {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	return uc.Repo.ListStatusHistory(ctx, in.ID)
+}
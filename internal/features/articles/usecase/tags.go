@@ -0,0 +1,112 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+type ListTagUsageInput struct {
+	Sort domain.TagUsageSort // defaults to name
+}
+
+type ListTagUsageUseCase struct {
+	Repo domain.TagManager
+}
+
+func NewListTagUsageUseCase(repo domain.TagManager) ListTagUsageUseCase {
+	return ListTagUsageUseCase{Repo: repo}
+}
+
+func (uc ListTagUsageUseCase) Execute(ctx context.Context, in ListTagUsageInput) ([]domain.TagUsage, error) {
+	if uc.Repo == nil {
+		return nil, errors.New("list tag usage: repo is nil")
+	}
+	sort := in.Sort
+	if sort == "" {
+		sort = domain.TagUsageSortName
+	}
+	if !sort.Valid() {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("invalid sort"))
+	}
+	return uc.Repo.ListTagUsage(ctx, sort)
+}
+
+type RenameTagInput struct {
+	From string
+	To   string
+}
+
+type RenameTagUseCase struct {
+	Repo domain.TagManager
+}
+
+func NewRenameTagUseCase(repo domain.TagManager) RenameTagUseCase {
+	return RenameTagUseCase{Repo: repo}
+}
+
+func (uc RenameTagUseCase) Execute(ctx context.Context, in RenameTagInput) (domain.Tag, error) {
+	if uc.Repo == nil {
+		return domain.Tag{}, errors.New("rename tag: repo is nil")
+	}
+	from, err := domain.NormalizeTagName(in.From)
+	if err != nil {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	to, err := domain.NormalizeTagName(in.To)
+	if err != nil {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	return uc.Repo.RenameTag(ctx, domain.RenameTagParams{From: from, To: to})
+}
+
+type MergeTagsInput struct {
+	Sources []string
+	Target  string
+}
+
+type MergeTagsUseCase struct {
+	Repo  domain.TagManager
+	Clock domain.Clock
+}
+
+func NewMergeTagsUseCase(repo domain.TagManager) MergeTagsUseCase {
+	return MergeTagsUseCase{Repo: repo, Clock: systemClock{}}
+}
+
+func (uc MergeTagsUseCase) Execute(ctx context.Context, in MergeTagsInput) (domain.Tag, error) {
+	if uc.Repo == nil {
+		return domain.Tag{}, errors.New("merge tags: repo is nil")
+	}
+	if uc.Clock == nil {
+		uc.Clock = systemClock{}
+	}
+	target, err := domain.NormalizeTagName(in.Target)
+	if err != nil {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	sources, err := domain.NormalizeTagNames(in.Sources)
+	if err != nil {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	if len(sources) == 0 {
+		return domain.Tag{}, errors.Join(domain.ErrInvalidArgument, errors.New("at least one tag to merge is required"))
+	}
+	return uc.Repo.MergeTags(ctx, domain.MergeTagsParams{Sources: sources, Target: target, Now: uc.Clock.Now()})
+}
+
+type DeleteUnusedTagsUseCase struct {
+	Repo domain.TagManager
+}
+
+func NewDeleteUnusedTagsUseCase(repo domain.TagManager) DeleteUnusedTagsUseCase {
+	return DeleteUnusedTagsUseCase{Repo: repo}
+}
+
+func (uc DeleteUnusedTagsUseCase) Execute(ctx context.Context) (int, error) {
+	if uc.Repo == nil {
+		return 0, errors.New("delete unused tags: repo is nil")
+	}
+	return uc.Repo.DeleteUnusedTags(ctx)
+}
@@ -0,0 +1,92 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+)
+
+type RestoreArticleInput struct {
+	ID string
+}
+
+type RestoreArticleUseCase struct {
+	Repo domain.ArticleRestorer
+}
+
+func NewRestoreArticleUseCase(repo domain.ArticleRestorer) RestoreArticleUseCase {
+	return RestoreArticleUseCase{Repo: repo}
+}
+
+func (uc RestoreArticleUseCase) Execute(ctx context.Context, in RestoreArticleInput) (domain.Article, error) {
+	if uc.Repo == nil {
+		return domain.Article{}, errors.New("restore article: repo is nil")
+	}
+	if in.ID == "" {
+		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, errors.New("id is required"))
+	}
+	return uc.Repo.RestoreArticle(ctx, in.ID)
+}
+
+type ListTrashInput struct {
+	Limit  int
+	Offset int
+}
+
+type ListTrashUseCase struct {
+	Repo domain.TrashLister
+}
+
+func NewListTrashUseCase(repo domain.TrashLister) ListTrashUseCase {
+	return ListTrashUseCase{Repo: repo}
+}
+
+func (uc ListTrashUseCase) Execute(ctx context.Context, in ListTrashInput) ([]domain.Article, error) {
+	if uc.Repo == nil {
+		return nil, errors.New("list trash: repo is nil")
+	}
+	limit := in.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := in.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	return uc.Repo.ListTrash(ctx, domain.ListTrashQuery{Limit: limit, Offset: offset})
+}
+
+// PurgeTrashUseCase permanently deletes articles that have been in the trash
+// longer than Retention (domain.DefaultTrashRetention when zero). Run it
+// periodically.
+type PurgeTrashUseCase struct {
+	Repo      domain.TrashPurger
+	Clock     domain.Clock
+	Retention time.Duration
+}
+
+func NewPurgeTrashUseCase(repo domain.TrashPurger, retention time.Duration) PurgeTrashUseCase {
+	return PurgeTrashUseCase{Repo: repo, Clock: systemClock{}, Retention: retention}
+}
+
+func (uc PurgeTrashUseCase) Execute(ctx context.Context) (int, error) {
+	if uc.Repo == nil {
+		return 0, errors.New("purge trash: repo is nil")
+	}
+	if uc.Clock == nil {
+		uc.Clock = systemClock{}
+	}
+	retention := uc.Retention
+	if retention < 0 {
+		return 0, errors.Join(domain.ErrInvalidArgument, errors.New("retention must not be negative"))
+	}
+	if retention == 0 {
+		retention = domain.DefaultTrashRetention
+	}
+	return uc.Repo.PurgeTrash(ctx, uc.Clock.Now().Add(-retention))
+}
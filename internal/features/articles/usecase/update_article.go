@@ -3,23 +3,36 @@
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
 )
 
 type UpdateArticleInput struct {
-	ID       string
-	Title    *string
-	Content  *string
-	Status   *domain.ArticleStatus
-	Tags     *[]string
-	AutoSave bool
+	ID           string
+	Title        *string
+	Content      *string
+	Status       *domain.ArticleStatus
+	Tags         *[]string
+	Digest       *string
+	Author       *string
+	CoverImage   *string
+	SourceURL    *string
+	OpenComments *bool
+	AutoSave     bool
+	// StatusNote is kept in the status history when Status changes, e.g. a
+	// reviewer's reason for sending an article back to draft.
+	StatusNote string
+	// ExpectedVersion is the version the edit was based on; 0 skips the check.
+	ExpectedVersion int
 }
 
 type UpdateArticleUseCase struct {
-	Repo  domain.ArticleUpdater
-	Clock domain.Clock
+	Repo     domain.ArticleUpdater
+	Clock    domain.Clock
+	Workflow domain.Workflow
 }
 
 func NewUpdateArticleUseCase(repo domain.ArticleUpdater) UpdateArticleUseCase {
@@ -45,6 +58,9 @@ func (uc UpdateArticleUseCase) Execute(ctx context.Context, in UpdateArticleInpu
 	if status != nil && !status.Valid() {
 		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, errors.New("invalid status"))
 	}
+	if n := utf8.RuneCountInString(in.StatusNote); n > domain.MaxNoteLength {
+		return domain.Article{}, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("note too long: max %d characters, got %d", domain.MaxNoteLength, n))
+	}
 
 	var normalizedTagsPtr *[]string
 	if in.Tags != nil {
@@ -61,11 +77,19 @@ func (uc UpdateArticleUseCase) Execute(ctx context.Context, in UpdateArticleInpu
 	}
 
 	return uc.Repo.UpdateArticle(ctx, in.ID, domain.UpdateArticleParams{
-		Title:      in.Title,
-		Content:    in.Content,
-		Status:     status,
-		Tags:       normalizedTagsPtr,
-		UpdatedAt:  now,
-		IsAutoSave: in.AutoSave,
+		Title:           in.Title,
+		Content:         in.Content,
+		Status:          status,
+		Digest:          in.Digest,
+		Author:          in.Author,
+		CoverImage:      in.CoverImage,
+		SourceURL:       in.SourceURL,
+		OpenComments:    in.OpenComments,
+		Tags:            normalizedTagsPtr,
+		UpdatedAt:       now,
+		IsAutoSave:      in.AutoSave,
+		Workflow:        uc.Workflow,
+		StatusNote:      in.StatusNote,
+		ExpectedVersion: in.ExpectedVersion,
 	})
 }
@@ -119,6 +119,37 @@ func TestUpdateArticleUseCase_AutoSaveForcesDraftWhenUnset(t *testing.T) {
 	}
 }
 
+func TestArticleUseCases_EnforceWorkflow(t *testing.T) {
+	w := domain.Workflow{RequireReview: true}
+	create := &createRepoFake{}
+	cuc := usecase.CreateArticleUseCase{Repo: create, Clock: fixedClock{}, IDs: fixedIDs{id: "a"}, Workflow: w}
+	_, err := cuc.Execute(context.Background(), usecase.CreateArticleInput{Title: "t", Content: "c", Status: domain.ArticleStatusPublished})
+	var te *domain.TransitionError
+	if !errors.As(err, &te) || !te.ReviewRequired {
+		t.Fatalf("expected *TransitionError, got %v", err)
+	}
+	if create.called != 0 {
+		t.Fatalf("repo must not be called")
+	}
+
+	update := &updateRepoFake{}
+	uuc := usecase.UpdateArticleUseCase{Repo: update, Clock: fixedClock{}, Workflow: w}
+	status := domain.ArticleStatusDraft
+	if _, err := uuc.Execute(context.Background(), usecase.UpdateArticleInput{ID: "a", Status: &status, StatusNote: "请补充数据来源"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if update.params.Workflow != w || update.params.StatusNote != "请补充数据来源" {
+		t.Fatalf("unexpected params: %+v", update.params)
+	}
+	long := make([]rune, domain.MaxNoteLength+1)
+	for i := range long {
+		long[i] = '长'
+	}
+	if _, err := uuc.Execute(context.Background(), usecase.UpdateArticleInput{ID: "a", Status: &status, StatusNote: string(long)}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
 type listRepoFake struct{
 	query domain.ListArticlesQuery
 	ret   []domain.Article
@@ -142,6 +173,46 @@ func TestListArticlesUseCase_DefaultPagination(t *testing.T) {
 	}
 }
 
+type pageRepoFake struct {
+	query domain.ListArticlesQuery
+	calls int
+}
+
+func (f *pageRepoFake) ListArticlesPage(ctx context.Context, q domain.ListArticlesQuery) (domain.ArticlePage, error) {
+	f.calls++
+	f.query = q
+	return domain.ArticlePage{}, nil
+}
+
+func TestListArticlesPageUseCase_ValidatesInput(t *testing.T) {
+	repo := &pageRepoFake{}
+	uc := usecase.NewListArticlesPageUseCase(repo)
+	now := time.Now()
+	for _, in := range []usecase.ListArticlesPageInput{
+		{ListArticlesInput: usecase.ListArticlesInput{Sort: "views"}},
+		{ListArticlesInput: usecase.ListArticlesInput{TagMatch: "some"}},
+		{ListArticlesInput: usecase.ListArticlesInput{Statuses: []domain.ArticleStatus{"retracted"}}},
+		{ListArticlesInput: usecase.ListArticlesInput{CreatedFrom: now, CreatedTo: now}},
+		{ListArticlesInput: usecase.ListArticlesInput{Tags: []string{" "}}},
+		{ListArticlesInput: usecase.ListArticlesInput{Offset: 10}},
+	} {
+		if _, err := uc.Execute(context.Background(), in); !errors.Is(err, domain.ErrInvalidArgument) {
+			t.Fatalf("input %+v: expected ErrInvalidArgument, got %v", in, err)
+		}
+	}
+	if repo.calls != 0 {
+		t.Fatalf("repo must not be called for invalid input")
+	}
+
+	in := usecase.ListArticlesPageInput{ListArticlesInput: usecase.ListArticlesInput{Tags: []string{" Go ", "go"}, Sort: domain.ArticleSortTitle}, Cursor: " abc "}
+	if _, err := uc.Execute(context.Background(), in); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if repo.query.Cursor != "abc" || len(repo.query.Tags) != 1 || repo.query.Tags[0] != "go" || repo.query.Limit != 20 {
+		t.Fatalf("unexpected query: %+v", repo.query)
+	}
+}
+
 type searchRepoFake struct{
 	query domain.SearchArticlesQuery
 	ret   []domain.Article
@@ -165,6 +236,306 @@ func TestSearchArticlesUseCase_RequiresQuery(t *testing.T) {
 	}
 }
 
+type resultSearchRepoFake struct {
+	query domain.SearchResultsQuery
+	calls int
+}
+
+func (f *resultSearchRepoFake) SearchArticleResults(ctx context.Context, q domain.SearchResultsQuery) ([]domain.SearchResult, error) {
+	f.calls++
+	f.query = q
+	return nil, nil
+}
+
+func TestSearchArticleResultsUseCase_ValidatesInput(t *testing.T) {
+	repo := &resultSearchRepoFake{}
+	uc := usecase.NewSearchArticleResultsUseCase(repo)
+	for _, in := range []usecase.SearchArticleResultsInput{
+		{SearchArticlesInput: usecase.SearchArticlesInput{Query: `"unterminated`}},
+		{SearchArticlesInput: usecase.SearchArticlesInput{Query: "go"}, HighlightOpen: "<b>"},
+		{SearchArticlesInput: usecase.SearchArticlesInput{Query: "go"}, SnippetRunes: domain.MaxSnippetRunes + 1},
+	} {
+		if _, err := uc.Execute(context.Background(), in); !errors.Is(err, domain.ErrInvalidArgument) {
+			t.Fatalf("input %+v: expected ErrInvalidArgument, got %v", in, err)
+		}
+	}
+	if repo.calls != 0 {
+		t.Fatalf("repo must not be called for invalid input")
+	}
+
+	if _, err := uc.Execute(context.Background(), usecase.SearchArticleResultsInput{SearchArticlesInput: usecase.SearchArticlesInput{Query: " go "}, SnippetRunes: 60}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if repo.query.Query != "go" || repo.query.Limit != 20 || repo.query.SnippetRunes != 60 {
+		t.Fatalf("unexpected query: %+v", repo.query)
+	}
+}
+
+type purgeRepoFake struct{ cutoff time.Time }
+
+func (f *purgeRepoFake) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	f.cutoff = deletedBefore
+	return 3, nil
+}
+
+func TestPurgeTrashUseCase_UsesRetention(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	repo := &purgeRepoFake{}
+	uc := usecase.PurgeTrashUseCase{Repo: repo, Clock: fixedClock{t: now}}
+	n, err := uc.Execute(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("execute: %d (%v)", n, err)
+	}
+	if want := now.Add(-domain.DefaultTrashRetention); !repo.cutoff.Equal(want) {
+		t.Fatalf("expected default cutoff %v, got %v", want, repo.cutoff)
+	}
+
+	uc.Retention = 7 * 24 * time.Hour
+	if _, err := uc.Execute(context.Background()); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if want := now.Add(-7 * 24 * time.Hour); !repo.cutoff.Equal(want) {
+		t.Fatalf("expected cutoff %v, got %v", want, repo.cutoff)
+	}
+
+	uc.Retention = -time.Hour
+	if _, err := uc.Execute(context.Background()); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+type versionRepoFake struct {
+	versions map[int]domain.ArticleVersion
+	current  domain.Article
+}
+
+func (f *versionRepoFake) GetVersion(ctx context.Context, articleID string, version int) (domain.ArticleVersion, error) {
+	v, ok := f.versions[version]
+	if !ok {
+		return domain.ArticleVersion{}, domain.ErrNotFound
+	}
+	return v, nil
+}
+
+func (f *versionRepoFake) GetArticle(ctx context.Context, articleID string) (domain.Article, error) {
+	return f.current, nil
+}
+
+func TestDiffVersionsUseCase_ComparesAgainstCurrent(t *testing.T) {
+	repo := &versionRepoFake{
+		versions: map[int]domain.ArticleVersion{1: {ArticleID: "a1", Version: 1, Title: "旧标题", Content: "正文"}},
+		current:  domain.Article{ID: "a1", CurrentVersion: 2, Title: "新标题", Content: "正文"},
+	}
+	uc := usecase.NewDiffVersionsUseCase(repo, repo)
+
+	d, err := uc.Execute(context.Background(), usecase.DiffVersionsInput{ArticleID: "a1", FromVersion: 1})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if d.FromVersion != 1 || d.ToVersion != 0 || !d.Changed() || len(d.Content) != 1 {
+		t.Fatalf("unexpected diff: %+v", d)
+	}
+
+	if _, err := uc.Execute(context.Background(), usecase.DiffVersionsInput{ArticleID: "a1"}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+	if _, err := uc.Execute(context.Background(), usecase.DiffVersionsInput{ArticleID: "a1", FromVersion: 1, ToVersion: 9}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+type compactRepoFake struct{ params domain.CompactVersionsParams }
+
+func (f *compactRepoFake) CompactVersions(ctx context.Context, params domain.CompactVersionsParams) (domain.CompactVersionsResult, error) {
+	f.params = params
+	return domain.CompactVersionsResult{Articles: 1, Removed: 2}, nil
+}
+
+func TestCompactVersionsUseCase_AppliesDefaults(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	repo := &compactRepoFake{}
+	uc := usecase.CompactVersionsUseCase{Repo: repo, Clock: fixedClock{t: now}, Retention: domain.VersionRetention{MaxVersions: 50}}
+	if res, err := uc.Execute(context.Background()); err != nil || res.Removed != 2 {
+		t.Fatalf("execute: %+v (%v)", res, err)
+	}
+	want := domain.VersionRetention{RecentWindow: domain.DefaultVersionRecentWindow, RecentInterval: domain.DefaultVersionRecentInterval, MaxVersions: 50}
+	if repo.params.Retention != want || !repo.params.Now.Equal(now) {
+		t.Fatalf("unexpected params: %+v", repo.params)
+	}
+
+	uc.Retention.RecentInterval = -time.Minute
+	if _, err := uc.Execute(context.Background()); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+type jobRunnerFake struct {
+	jobs     map[string]*domain.ScheduledJob
+	errs     map[string]error
+	ran      []string
+	failures []domain.JobFailureParams
+}
+
+func (f *jobRunnerFake) DueJobs(ctx context.Context, now time.Time, limit int) ([]domain.ScheduledJob, error) {
+	var out []domain.ScheduledJob
+	for _, j := range f.jobs {
+		if j.State == domain.JobStatePending && !j.RunAt.After(now) {
+			out = append(out, *j)
+		}
+	}
+	return out, nil
+}
+
+func (f *jobRunnerFake) RunJob(ctx context.Context, jobID string, now time.Time) error {
+	if err := f.errs[jobID]; err != nil {
+		return err
+	}
+	f.ran = append(f.ran, jobID)
+	f.jobs[jobID].State = domain.JobStateDone
+	return nil
+}
+
+func (f *jobRunnerFake) RecordJobFailure(ctx context.Context, params domain.JobFailureParams) error {
+	f.failures = append(f.failures, params)
+	j := f.jobs[params.JobID]
+	j.Attempts++
+	if params.RetryAt.IsZero() {
+		j.State = domain.JobStateFailed
+	} else {
+		j.RunAt = params.RetryAt
+	}
+	return nil
+}
+
+func TestScheduler_RunDueRetriesFailures(t *testing.T) {
+	base := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	pending := func(id string, at time.Time) *domain.ScheduledJob {
+		return &domain.ScheduledJob{ID: id, State: domain.JobStatePending, RunAt: at}
+	}
+	runner := &jobRunnerFake{
+		jobs: map[string]*domain.ScheduledJob{
+			"missed": pending("missed", base.Add(-24*time.Hour)),
+			"later":  pending("later", base.Add(time.Hour)),
+			"flaky":  pending("flaky", base),
+			"gone":   pending("gone", base),
+		},
+		errs: map[string]error{
+			"flaky": errors.New("database is locked"),
+			"gone":  domain.ErrNotFound,
+		},
+	}
+	s := usecase.Scheduler{Jobs: runner, Clock: fixedClock{t: base}, Retry: usecase.RetryPolicy{MaxAttempts: 3, Backoff: time.Minute}}
+	if n, err := s.RunDue(context.Background()); err != nil || n != 1 || !reflect.DeepEqual(runner.ran, []string{"missed"}) {
+		t.Fatalf("run due: %d %v (%v)", n, runner.ran, err)
+	}
+	if j := runner.jobs["gone"]; j.State != domain.JobStateFailed {
+		t.Fatalf("permanent errors must not be retried: %+v", j)
+	}
+	if j := runner.jobs["flaky"]; j.State != domain.JobStatePending || !j.RunAt.Equal(base.Add(time.Minute)) {
+		t.Fatalf("expected a retry after one minute: %+v", j)
+	}
+
+	s.Clock = fixedClock{t: base.Add(time.Minute)}
+	if _, err := s.RunDue(context.Background()); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+	if j := runner.jobs["flaky"]; !j.RunAt.Equal(base.Add(3 * time.Minute)) {
+		t.Fatalf("expected the backoff to double: %+v", j)
+	}
+	s.Clock = fixedClock{t: base.Add(3 * time.Minute)}
+	if _, err := s.RunDue(context.Background()); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+	if j := runner.jobs["flaky"]; j.State != domain.JobStateFailed || j.Attempts != 3 {
+		t.Fatalf("expected the job to fail after three attempts: %+v", j)
+	}
+	if runner.jobs["later"].State != domain.JobStatePending {
+		t.Fatalf("jobs that are not due must not run")
+	}
+}
+
+func TestSchedulePublishUseCase_RequiresFutureTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	uc := usecase.SchedulePublishUseCase{Repo: nil, Clock: fixedClock{t: now}}
+	if _, err := uc.Execute(context.Background(), usecase.SchedulePublishInput{ID: "a", At: now.Add(time.Hour)}); err == nil {
+		t.Fatalf("expected error for nil repo")
+	}
+	uc.Repo = schedulerRepoFake{}
+	if _, err := uc.Execute(context.Background(), usecase.SchedulePublishInput{ID: "a", At: now.Add(-time.Minute)}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+	job, err := uc.Execute(context.Background(), usecase.SchedulePublishInput{ID: "a", At: now.Add(time.Hour)})
+	if err != nil || !job.RunAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("execute: %+v (%v)", job, err)
+	}
+}
+
+type schedulerRepoFake struct{}
+
+func (schedulerRepoFake) SchedulePublish(ctx context.Context, params domain.SchedulePublishParams) (domain.ScheduledJob, error) {
+	return domain.ScheduledJob{ArticleID: params.ArticleID, RunAt: params.At}, nil
+}
+
+func (schedulerRepoFake) CancelScheduledPublish(ctx context.Context, articleID string, now time.Time) error {
+	return nil
+}
+
+type tagManagerFake struct {
+	domain.TagManager
+	merge domain.MergeTagsParams
+}
+
+func (f *tagManagerFake) MergeTags(ctx context.Context, params domain.MergeTagsParams) (domain.Tag, error) {
+	f.merge = params
+	return domain.Tag{Name: params.Target}, nil
+}
+
+func TestMergeTagsUseCase_NormalizesNames(t *testing.T) {
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	repo := &tagManagerFake{}
+	uc := usecase.MergeTagsUseCase{Repo: repo, Clock: fixedClock{t: now}}
+	if _, err := uc.Execute(context.Background(), usecase.MergeTagsInput{Sources: []string{" Growth ", "growth", "增长"}, Target: " 用户增长 "}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := domain.MergeTagsParams{Sources: []string{"growth", "增长"}, Target: "用户增长", Now: now}
+	if !reflect.DeepEqual(repo.merge, want) {
+		t.Fatalf("unexpected params: %+v", repo.merge)
+	}
+	if _, err := uc.Execute(context.Background(), usecase.MergeTagsInput{Target: "用户增长"}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+type collectionRepoFake struct {
+	domain.CollectionRepository
+	query domain.ListCollectionsQuery
+}
+
+func (f *collectionRepoFake) ListCollections(ctx context.Context, q domain.ListCollectionsQuery) ([]domain.Collection, error) {
+	f.query = q
+	return nil, nil
+}
+
+func TestListCollectionsUseCase_ClampsPagination(t *testing.T) {
+	repo := &collectionRepoFake{}
+	uc := usecase.NewListCollectionsUseCase(repo)
+	for _, tc := range []struct {
+		in   usecase.ListCollectionsInput
+		want domain.ListCollectionsQuery
+	}{
+		{usecase.ListCollectionsInput{}, domain.ListCollectionsQuery{Limit: 20}},
+		{usecase.ListCollectionsInput{Limit: -5, Offset: -1}, domain.ListCollectionsQuery{Limit: 20}},
+		{usecase.ListCollectionsInput{Limit: 500, Offset: 40}, domain.ListCollectionsQuery{Limit: 100, Offset: 40}},
+	} {
+		if _, err := uc.Execute(context.Background(), tc.in); err != nil {
+			t.Fatalf("%+v: unexpected error: %v", tc.in, err)
+		}
+		if repo.query != tc.want {
+			t.Fatalf("%+v: expected %+v, got %+v", tc.in, tc.want, repo.query)
+		}
+	}
+}
+
 type deleteRepoFake struct{ id string; called int; err error }
 
 func (f *deleteRepoFake) DeleteArticle(ctx context.Context, articleID string) error {
@@ -0,0 +1,30 @@
+
+package data
+
+import "github.com/Xiaoxinkeji/WX/internal/core/migrate"
+
+// MigrationFeature is the key the hot topics schema is versioned under in
+// schema_migrations.
+const MigrationFeature = "hot_topics"
+
+// Migrations returns the ordered hot topics schema migrations. Append new
+// steps; never edit one that has shipped.
+func Migrations() []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "baseline", Up: migrate.SQL(`
+CREATE TABLE IF NOT EXISTS hot_topics (
+	id TEXT PRIMARY KEY,
+	source TEXT NOT NULL,
+	rank INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	url TEXT,
+	hot_value REAL,
+	description TEXT,
+	fetched_at_ms INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_hot_topics_source_rank ON hot_topics(source, rank);
+CREATE INDEX IF NOT EXISTS idx_hot_topics_source_fetched ON hot_topics(source, fetched_at_ms DESC);
+`)},
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Xiaoxinkeji/WX/internal/core/migrate"
 	"github.com/Xiaoxinkeji/WX/internal/features/hot_topics/data/models"
 	"github.com/Xiaoxinkeji/WX/internal/features/hot_topics/data/sources"
 	"github.com/Xiaoxinkeji/WX/internal/features/hot_topics/domain"
@@ -121,22 +122,7 @@ func (r *SQLiteRepository) EnsureSchema(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	_, err := r.db.ExecContext(ctx, `
-CREATE TABLE IF NOT EXISTS hot_topics (
-	id TEXT PRIMARY KEY,
-	source TEXT NOT NULL,
-	rank INTEGER NOT NULL,
-	title TEXT NOT NULL,
-	url TEXT,
-	hot_value REAL,
-	description TEXT,
-	fetched_at_ms INTEGER NOT NULL
-);
-
-CREATE INDEX IF NOT EXISTS idx_hot_topics_source_rank ON hot_topics(source, rank);
-CREATE INDEX IF NOT EXISTS idx_hot_topics_source_fetched ON hot_topics(source, fetched_at_ms DESC);
-`)
-	return err
+	return migrate.Apply(ctx, r.db, MigrationFeature, Migrations())
 }
 
 func (r *SQLiteRepository) GetHotTopics(ctx context.Context, source *domain.Source, forceRefresh bool) ([]domain.Topic, error) {
@@ -508,14 +494,11 @@ FROM hot_topics
 `
 	args := []any{}
 	if source != nil {
-		q += " WHERE source = ?
-"
+		q += " WHERE source = ?\n"
 		args = append(args, source.Key())
-		q += " ORDER BY rank ASC
-"
+		q += " ORDER BY rank ASC\n"
 	} else {
-		q += " ORDER BY " + sourceCaseOrder("source", r.expectedSources()) + ", rank ASC
-"
+		q += " ORDER BY " + sourceCaseOrder("source", r.expectedSources()) + ", rank ASC\n"
 	}
 
 	rows, err := r.db.QueryContext(ctx, q, args...)
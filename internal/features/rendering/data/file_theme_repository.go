@@ -0,0 +1,227 @@
+﻿package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/data/models"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+// ThemeFileError describes a theme file that could not be loaded.
+type ThemeFileError struct {
+	Path string
+	Err  error
+}
+
+func (e *ThemeFileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *ThemeFileError) Unwrap() error { return e.Err }
+
+// FileThemeRepository serves themes from a directory holding one YAML or
+// JSON file per theme, next to the built-in default theme. A file without a
+// name uses its base file name. A theme may extend another one, the default
+// included, and then only lists the styles it changes. Files that fail to
+// load are reported by Errors and their themes are left out.
+type FileThemeRepository struct {
+	dir string
+
+	mu     sync.RWMutex
+	themes map[string]domain.Theme
+	errs   []ThemeFileError
+}
+
+func NewFileThemeRepository(dir string) (*FileThemeRepository, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, errors.New("file theme repository: dir is empty")
+	}
+	repo := &FileThemeRepository{dir: dir}
+	if _, err := repo.Reload(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *FileThemeRepository) GetTheme(ctx context.Context, name string) (domain.Theme, error) {
+	_ = ctx
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return domain.Theme{}, errors.Join(domain.ErrInvalidArgument, errors.New("theme name is required"))
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.themes[key]
+	if !ok {
+		return domain.Theme{}, errors.Join(domain.ErrNotFound, fmt.Errorf("theme %q not found", name))
+	}
+	return t.Clone(), nil
+}
+
+func (r *FileThemeRepository) ListThemes(ctx context.Context) ([]domain.Theme, error) {
+	_ = ctx
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.themes))
+	for name := range r.themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]domain.Theme, 0, len(names))
+	for _, name := range names {
+		out = append(out, r.themes[name].Clone())
+	}
+	return out, nil
+}
+
+// Errors returns the problems found by the most recent reload.
+func (r *FileThemeRepository) Errors() []ThemeFileError {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ThemeFileError, len(r.errs))
+	copy(out, r.errs)
+	return out
+}
+
+// Reload re-reads every theme file in the directory. The returned error is
+// only set when the directory itself cannot be read.
+func (r *FileThemeRepository) Reload() ([]ThemeFileError, error) {
+	paths, err := r.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	def := domain.DefaultTheme()
+	dtos := make(map[string]models.ThemeDTO, len(paths))
+	owners := map[string]string{def.Name: "the built-in theme"}
+	var (
+		errs   []ThemeFileError
+		loaded []string // theme names in file order
+	)
+	for _, path := range paths {
+		dto, err := loadThemeFile(path)
+		if err != nil {
+			errs = append(errs, ThemeFileError{Path: path, Err: err})
+			continue
+		}
+		if owner, dup := owners[dto.Name]; dup {
+			errs = append(errs, ThemeFileError{Path: path, Err: fmt.Errorf("duplicate theme name %q (also defined by %s)", dto.Name, filepath.Base(owner))})
+			continue
+		}
+		owners[dto.Name] = path
+		dtos[dto.Name] = dto
+		loaded = append(loaded, dto.Name)
+	}
+
+	themes := map[string]domain.Theme{def.Name: def}
+	failed := map[string]bool{}
+	var resolve func(name string, chain []string) (domain.Theme, error)
+	resolve = func(name string, chain []string) (domain.Theme, error) {
+		if t, ok := themes[name]; ok {
+			return t, nil
+		}
+		dto, ok := dtos[name]
+		if !ok || failed[name] {
+			return domain.Theme{}, fmt.Errorf("unknown theme %q", name)
+		}
+		for _, seen := range chain {
+			if seen == name {
+				return domain.Theme{}, fmt.Errorf("themes extend each other: %s", strings.Join(append(chain, name), " -> "))
+			}
+		}
+		base := domain.Theme{}
+		if dto.Extends != "" {
+			parent, err := resolve(strings.ToLower(strings.TrimSpace(dto.Extends)), append(chain, name))
+			if err != nil {
+				return domain.Theme{}, fmt.Errorf("extends %q: %w", dto.Extends, err)
+			}
+			base = parent
+		}
+		t, err := dto.ToDomain(base)
+		if err != nil {
+			return domain.Theme{}, err
+		}
+		themes[name] = t
+		return t, nil
+	}
+	for _, name := range loaded {
+		if _, err := resolve(name, nil); err != nil {
+			failed[name] = true
+			errs = append(errs, ThemeFileError{Path: owners[name], Err: err})
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.themes = themes
+	r.errs = errs
+	out := make([]ThemeFileError, len(errs))
+	copy(out, errs)
+	return out, nil
+}
+
+func (r *FileThemeRepository) scan() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !isThemeFile(name) {
+			continue
+		}
+		paths = append(paths, filepath.Join(r.dir, name))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func isThemeFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+func loadThemeFile(path string) (models.ThemeDTO, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return models.ThemeDTO{}, err
+	}
+
+	var dto models.ThemeDTO
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&dto)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		err = dec.Decode(&dto)
+	}
+	if err != nil {
+		return models.ThemeDTO{}, fmt.Errorf("parse: %w", err)
+	}
+
+	if strings.TrimSpace(dto.Name) == "" {
+		base := filepath.Base(path)
+		dto.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	dto.Name = strings.ToLower(strings.TrimSpace(dto.Name))
+	return dto, nil
+}
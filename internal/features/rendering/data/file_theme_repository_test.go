@@ -0,0 +1,126 @@
+﻿package data_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/data"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+func writeThemeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestFileThemeRepository_LoadsAndExtendsThemes(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeThemeFile(t, dir, "tech.yaml", `
+title: 技术专栏
+extends: default
+styles:
+  root: "font-size: 15px; color: #222"
+  h2: "color: #1e6bb8; font-weight: bold"
+  blockquote: ""
+`)
+	writeThemeFile(t, dir, "tech-dark.json", `{"extends": "Tech", "styles": {"pre": "background: #1e1e1e; color: #ddd"}}`)
+	writeThemeFile(t, dir, "bare.yml", `styles: {p: "margin: 0"}`)
+	writeThemeFile(t, dir, "notes.txt", "not a theme")
+
+	repo, err := data.NewFileThemeRepository(dir)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	if errs := repo.Errors(); len(errs) != 0 {
+		t.Fatalf("unexpected load errors: %v", errs)
+	}
+	all, err := repo.ListThemes(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var names []string
+	for _, th := range all {
+		names = append(names, th.Name)
+	}
+	if strings.Join(names, ",") != "bare,default,tech,tech-dark" {
+		t.Fatalf("unexpected themes: %v", names)
+	}
+
+	def := domain.DefaultTheme()
+	dark, err := repo.GetTheme(ctx, " TECH-DARK ")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if dark.Style(domain.ElementH2) != "color: #1e6bb8; font-weight: bold" || dark.Style(domain.ElementPre) != "background: #1e1e1e; color: #ddd" {
+		t.Fatalf("expected styles from both files: %+v", dark.Styles)
+	}
+	if dark.Style(domain.ElementListItem) != def.Style(domain.ElementListItem) || dark.Style(domain.ElementBlockquote) != "" {
+		t.Fatalf("expected default styles except the cleared blockquote: %+v", dark.Styles)
+	}
+	if dark.Title != "" {
+		t.Fatalf("titles are not inherited, got %q", dark.Title)
+	}
+	bare, err := repo.GetTheme(ctx, "bare")
+	if err != nil || len(bare.Styles) != 1 {
+		t.Fatalf("a theme without extends starts empty: %+v (%v)", bare, err)
+	}
+
+	// Returned themes are copies.
+	dark.Styles[domain.ElementH2] = "color: red"
+	if again, _ := repo.GetTheme(ctx, "tech-dark"); again.Style(domain.ElementH2) == "color: red" {
+		t.Fatalf("mutating a returned theme changed the repository")
+	}
+	if _, err := repo.GetTheme(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFileThemeRepository_ReportsInvalidFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeThemeFile(t, dir, "a-cycle.yaml", `extends: b-cycle`)
+	writeThemeFile(t, dir, "b-cycle.yaml", `extends: a-cycle`)
+	writeThemeFile(t, dir, "child.yaml", `extends: fixed-pos`)
+	writeThemeFile(t, dir, "default.yaml", `styles: {p: "color: red"}`)
+	writeThemeFile(t, dir, "fixed-pos.yaml", `styles: {p: "position: fixed"}`)
+	writeThemeFile(t, dir, "script.yaml", `styles: {p: "color: red\"><script>"}`)
+	writeThemeFile(t, dir, "tag.yaml", `styles: {div: "color: red"}`)
+	writeThemeFile(t, dir, "typo.yaml", `stlyes: {p: "color: red"}`)
+	writeThemeFile(t, dir, "ok.yaml", `extends: default`)
+
+	repo, err := data.NewFileThemeRepository(dir)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	failed := map[string]bool{}
+	for _, e := range repo.Errors() {
+		failed[filepath.Base(e.Path)] = true
+	}
+	for _, name := range []string{"a-cycle.yaml", "b-cycle.yaml", "child.yaml", "default.yaml", "fixed-pos.yaml", "script.yaml", "tag.yaml", "typo.yaml"} {
+		if !failed[name] {
+			t.Fatalf("expected %s to be reported, got %v", name, repo.Errors())
+		}
+	}
+	all, _ := repo.ListThemes(ctx)
+	if len(all) != 2 || all[0].Name != "default" || all[1].Name != "ok" {
+		t.Fatalf("expected only the valid themes, got %+v", all)
+	}
+	if def, _ := repo.GetTheme(ctx, "default"); def.Style(domain.ElementParagraph) == "color: red" {
+		t.Fatalf("a file must not replace the built-in default theme")
+	}
+
+	writeThemeFile(t, dir, "fixed-pos.yaml", `styles: {p: "color: #333"}`)
+	if _, err := repo.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if _, err := repo.GetTheme(ctx, "child"); err != nil {
+		t.Fatalf("expected the fixed parent to load its child: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+﻿package data
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+// codeToken is a run of code; element is empty for plain text.
+type codeToken struct {
+	element domain.Element
+	text    string
+}
+
+// codeLanguage is just enough of a language to colour keywords, strings,
+// comments and numbers. It is a lexer, not a parser: the goal is a readable
+// code block in an article, not exact highlighting.
+type codeLanguage struct {
+	keywords     map[string]bool
+	foldCase     bool // keywords match case-insensitively
+	lineComments []string
+	blockComment [2]string
+	quotes       string
+	multiline    string // quotes whose strings may span lines
+}
+
+func words(s string) map[string]bool {
+	out := map[string]bool{}
+	for _, w := range strings.Fields(s) {
+		out[w] = true
+	}
+	return out
+}
+
+var (
+	langGo = &codeLanguage{
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if import
+			interface map package range return select struct switch type var true false nil`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+	}
+	langJavaScript = &codeLanguage{
+		keywords: words(`async await break case catch class const continue debugger default delete do else enum
+			export extends false finally for function if implements import in instanceof interface let new null
+			of return super switch this throw true try type typeof undefined var void while with yield`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+	}
+	langPython = &codeLanguage{
+		keywords: words(`and as assert async await break class continue def del elif else except finally for
+			from global if import in is lambda nonlocal not or pass raise return try while with yield None True False`),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	}
+	langJava = &codeLanguage{
+		keywords: words(`abstract boolean break byte case catch char class continue default do double else enum
+			extends final finally float for if implements import instanceof int interface long new package private
+			protected public return short static super switch this throw throws try void while true false null`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+	}
+	langC = &codeLanguage{
+		keywords: words(`auto break case char class const continue default delete do double else enum extern
+			float for goto if include define int long namespace new nullptr private protected public return short
+			signed sizeof static struct switch template typedef typename union unsigned void volatile while true false`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+	}
+	langSQL = &codeLanguage{
+		keywords: words(`select from where and or not insert into values update set delete create table index
+			drop alter join left right inner outer on as group by order having limit offset null is in like
+			distinct union all primary key references default exists case when then else end`),
+		foldCase:     true,
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "'\"",
+	}
+	langShell = &codeLanguage{
+		keywords:     words(`if then else elif fi for while do done case esac function in return export local exit`),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		multiline:    "\"'",
+	}
+	langJSON = &codeLanguage{
+		keywords: words(`true false null`),
+		quotes:   "\"",
+	}
+)
+
+var codeLanguages = map[string]*codeLanguage{
+	"go":         langGo,
+	"golang":     langGo,
+	"js":         langJavaScript,
+	"javascript": langJavaScript,
+	"ts":         langJavaScript,
+	"typescript": langJavaScript,
+	"py":         langPython,
+	"python":     langPython,
+	"java":       langJava,
+	"kotlin":     langJava,
+	"c":          langC,
+	"cpp":        langC,
+	"c++":        langC,
+	"sql":        langSQL,
+	"sh":         langShell,
+	"bash":       langShell,
+	"shell":      langShell,
+	"json":       langJSON,
+}
+
+// highlight splits code into tokens. Unknown languages come back as a
+// single plain token.
+func highlight(lang, code string) []codeToken {
+	l := codeLanguages[strings.ToLower(lang)]
+	if l == nil {
+		return []codeToken{{text: code}}
+	}
+
+	var out []codeToken
+	emit := func(e domain.Element, s string) {
+		if s == "" {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].element == e {
+			out[n-1].text += s
+			return
+		}
+		out = append(out, codeToken{element: e, text: s})
+	}
+
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		if open := l.blockComment[0]; open != "" && strings.HasPrefix(rest, open) {
+			end := strings.Index(rest[len(open):], l.blockComment[1])
+			n := len(rest)
+			if end >= 0 {
+				n = len(open) + end + len(l.blockComment[1])
+			}
+			emit(domain.ElementCodeComment, rest[:n])
+			i += n
+			continue
+		}
+		if lineComment(l, rest) {
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			emit(domain.ElementCodeComment, rest[:n])
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		switch {
+		case strings.ContainsRune(l.quotes, r):
+			n := stringEnd(rest, byte(r), strings.ContainsRune(l.multiline, r))
+			emit(domain.ElementCodeString, rest[:n])
+			i += n
+		case unicode.IsDigit(r):
+			n := 0
+			for n < len(rest) && (isIdentByte(rest[n]) || rest[n] == '.') {
+				n++
+			}
+			emit(domain.ElementCodeNumber, rest[:n])
+			i += n
+		case r == '_' || unicode.IsLetter(r):
+			n := 0
+			for n < len(rest) {
+				r, size := utf8.DecodeRuneInString(rest[n:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				n += size
+			}
+			word := rest[:n]
+			key := word
+			if l.foldCase {
+				key = strings.ToLower(word)
+			}
+			if l.keywords[key] {
+				emit(domain.ElementCodeKeyword, word)
+			} else {
+				emit("", word)
+			}
+			i += n
+		default:
+			emit("", rest[:size])
+			i += size
+		}
+	}
+	return out
+}
+
+func lineComment(l *codeLanguage, s string) bool {
+	for _, prefix := range l.lineComments {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringEnd returns the length of the string literal at the start of s,
+// honouring backslash escapes. Unterminated strings end at the line end.
+func stringEnd(s string, quote byte, multiline bool) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case '\n':
+			if !multiline {
+				return i
+			}
+		case quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
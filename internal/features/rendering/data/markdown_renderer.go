@@ -0,0 +1,500 @@
+﻿package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+// MarkdownRenderer renders Markdown article content to HTML the WeChat
+// editor accepts. Styles come from the theme and are written inline, raw
+// HTML is dropped, links outside WeChat become numbered references listed
+// at the end, and images not yet on the WeChat CDN become placeholders.
+type MarkdownRenderer struct {
+	md goldmark.Markdown
+}
+
+func NewMarkdownRenderer() *MarkdownRenderer {
+	return &MarkdownRenderer{
+		md: goldmark.New(goldmark.WithExtensions(extension.Table, extension.Strikethrough)),
+	}
+}
+
+func (r *MarkdownRenderer) Render(ctx context.Context, markdown string, opts domain.RenderOptions) (domain.Rendered, error) {
+	_ = ctx
+	if err := domain.ValidateTheme(opts.Theme); err != nil {
+		return domain.Rendered{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	if opts.ReferencesTitle == "" {
+		opts.ReferencesTitle = domain.DefaultReferencesTitle
+	}
+
+	src := []byte(markdown)
+	doc := r.md.Parser().Parse(text.NewReader(src))
+	w := &htmlWriter{src: src, opts: opts, refs: map[string]int{}}
+	w.open("section", domain.ElementRoot)
+	w.buf.WriteByte('\n')
+	if err := ast.Walk(doc, w.walk); err != nil {
+		return domain.Rendered{}, err
+	}
+	w.writeReferences()
+	w.close("section")
+	return domain.Rendered{Theme: opts.Theme.Name, HTML: w.buf.String(), Images: w.images, References: w.references}, nil
+}
+
+type htmlWriter struct {
+	src  []byte
+	opts domain.RenderOptions
+	buf  bytes.Buffer
+
+	images     []domain.RenderedImage
+	references []domain.LinkReference
+	refs       map[string]int // URL to reference index
+	tableBody  bool           // a <tbody> is open
+}
+
+var headingElements = [...]domain.Element{
+	domain.ElementH1, domain.ElementH2, domain.ElementH3, domain.ElementH4, domain.ElementH5, domain.ElementH6,
+}
+
+func (w *htmlWriter) walk(node ast.Node, entering bool) (ast.WalkStatus, error) {
+	switch n := node.(type) {
+	case *ast.Document, *ast.TextBlock:
+	case *ast.Heading:
+		tag := fmt.Sprintf("h%d", n.Level)
+		w.block(entering, tag, headingElements[n.Level-1])
+	case *ast.Paragraph:
+		w.block(entering, "p", domain.ElementParagraph)
+	case *ast.Blockquote:
+		w.container(entering, "blockquote", domain.ElementBlockquote)
+	case *ast.List:
+		if n.IsOrdered() && entering && n.Start > 1 {
+			w.open("ol", domain.ElementOrdered, "start", strconv.Itoa(n.Start))
+			w.buf.WriteByte('\n')
+		} else if n.IsOrdered() {
+			w.container(entering, "ol", domain.ElementOrdered)
+		} else {
+			w.container(entering, "ul", domain.ElementList)
+		}
+	case *ast.ListItem:
+		w.block(entering, "li", domain.ElementListItem)
+	case *ast.ThematicBreak:
+		if entering {
+			w.open("hr", domain.ElementRule)
+			w.buf.WriteByte('\n')
+		}
+	case *ast.FencedCodeBlock:
+		if entering {
+			w.codeBlock(string(n.Language(w.src)), n.Lines())
+		}
+		return ast.WalkSkipChildren, nil
+	case *ast.CodeBlock:
+		if entering {
+			w.codeBlock("", n.Lines())
+		}
+		return ast.WalkSkipChildren, nil
+	case *ast.HTMLBlock, *ast.RawHTML:
+		// WeChat only keeps a small set of tags; raw HTML is dropped rather
+		// than passed through.
+		return ast.WalkSkipChildren, nil
+	case *ast.Text:
+		if entering {
+			w.text(n)
+		}
+	case *ast.String:
+		if entering {
+			w.buf.WriteString(html.EscapeString(string(n.Value)))
+		}
+	case *ast.CodeSpan:
+		if entering {
+			w.open("code", domain.ElementCode)
+			w.buf.WriteString(html.EscapeString(rawText(n, w.src)))
+			w.close("code")
+		}
+		return ast.WalkSkipChildren, nil
+	case *ast.Emphasis:
+		if n.Level >= 2 {
+			w.inline(entering, "strong", domain.ElementStrong)
+		} else {
+			w.inline(entering, "em", domain.ElementEmphasis)
+		}
+	case *extast.Strikethrough:
+		w.inline(entering, "del", domain.ElementDelete)
+	case *ast.Link:
+		w.link(n, entering)
+	case *ast.AutoLink:
+		if entering {
+			w.autoLink(n)
+		}
+		return ast.WalkSkipChildren, nil
+	case *ast.Image:
+		if entering {
+			w.image(n)
+		}
+		return ast.WalkSkipChildren, nil
+	case *extast.Table:
+		if entering {
+			w.open("table", domain.ElementTable)
+			w.buf.WriteByte('\n')
+			w.tableBody = false
+			break
+		}
+		if w.tableBody {
+			w.buf.WriteString("</tbody>\n")
+		}
+		w.close("table")
+		w.buf.WriteByte('\n')
+	case *extast.TableHeader:
+		if entering {
+			w.buf.WriteString("<thead>\n<tr>")
+		} else {
+			w.buf.WriteString("</tr>\n</thead>\n")
+		}
+	case *extast.TableRow:
+		if entering {
+			if !w.tableBody {
+				w.buf.WriteString("<tbody>\n")
+				w.tableBody = true
+			}
+			w.buf.WriteString("<tr>")
+		} else {
+			w.buf.WriteString("</tr>\n")
+		}
+	case *extast.TableCell:
+		tag, e := "td", domain.ElementTableCell
+		if _, ok := n.Parent().(*extast.TableHeader); ok {
+			tag, e = "th", domain.ElementTableHead
+		}
+		if !entering {
+			w.close(tag)
+			break
+		}
+		style := w.opts.Theme.Style(e)
+		if n.Alignment != extast.AlignNone {
+			style = joinStyles(style, "text-align: "+n.Alignment.String())
+		}
+		w.openStyled(tag, style)
+	}
+	return ast.WalkContinue, nil
+}
+
+func (w *htmlWriter) open(tag string, e domain.Element, attrs ...string) {
+	w.openStyled(tag, w.opts.Theme.Style(e), attrs...)
+}
+
+// openStyled writes an opening tag. attrs are name, value pairs.
+func (w *htmlWriter) openStyled(tag, style string, attrs ...string) {
+	w.buf.WriteString("<" + tag)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(&w.buf, ` %s="%s"`, attrs[i], html.EscapeString(attrs[i+1]))
+	}
+	if style != "" {
+		fmt.Fprintf(&w.buf, ` style="%s"`, html.EscapeString(style))
+	}
+	w.buf.WriteByte('>')
+}
+
+func (w *htmlWriter) close(tag string) {
+	w.buf.WriteString("</" + tag + ">")
+}
+
+func (w *htmlWriter) inline(entering bool, tag string, e domain.Element) {
+	if entering {
+		w.open(tag, e)
+	} else {
+		w.close(tag)
+	}
+}
+
+// block writes an element that holds inline content on one line.
+func (w *htmlWriter) block(entering bool, tag string, e domain.Element) {
+	if entering {
+		w.open(tag, e)
+		return
+	}
+	w.close(tag)
+	w.buf.WriteByte('\n')
+}
+
+// container writes an element that holds other blocks.
+func (w *htmlWriter) container(entering bool, tag string, e domain.Element) {
+	if entering {
+		w.open(tag, e)
+	} else {
+		w.close(tag)
+	}
+	w.buf.WriteByte('\n')
+}
+
+func (w *htmlWriter) text(n *ast.Text) {
+	value := textValue(n, w.src)
+	w.buf.WriteString(html.EscapeString(string(value)))
+	switch {
+	case n.HardLineBreak():
+		w.buf.WriteString("<br>")
+	case n.SoftLineBreak():
+		// A line break between two CJK characters is not a word break;
+		// rendering it as a space would put gaps inside sentences.
+		last, _ := utf8.DecodeLastRune(value)
+		next, ok := n.NextSibling().(*ast.Text)
+		if ok {
+			first, _ := utf8.DecodeRune(next.Segment.Value(w.src))
+			if isWide(last) && isWide(first) {
+				return
+			}
+		}
+		w.buf.WriteByte('\n')
+	}
+}
+
+// codeBlock writes code with <br> and &nbsp; for line breaks and
+// indentation, which the WeChat editor otherwise collapses inside <pre>.
+func (w *htmlWriter) codeBlock(lang string, lines *text.Segments) {
+	var code strings.Builder
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		code.Write(seg.Value(w.src))
+	}
+	w.open("pre", domain.ElementPre)
+	w.buf.WriteString("<code>")
+	for _, tok := range highlight(lang, strings.TrimSuffix(code.String(), "\n")) {
+		style := ""
+		if tok.element != "" {
+			style = w.opts.Theme.Style(tok.element)
+		}
+		if style != "" {
+			w.openStyled("span", style)
+		}
+		w.buf.WriteString(codeHTML(tok.text))
+		if style != "" {
+			w.close("span")
+		}
+	}
+	w.buf.WriteString("</code>")
+	w.close("pre")
+	w.buf.WriteByte('\n')
+}
+
+func codeHTML(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\n':
+			b.WriteString("<br>")
+		case ' ':
+			b.WriteString("&nbsp;")
+		case '\t':
+			b.WriteString("&nbsp;&nbsp;&nbsp;&nbsp;")
+		case '\r':
+		default:
+			b.WriteString(html.EscapeString(string(r)))
+		}
+	}
+	return b.String()
+}
+
+func (w *htmlWriter) link(n *ast.Link, entering bool) {
+	dest := destination(n.Destination)
+	switch {
+	case isWeChatArticle(dest):
+		if entering {
+			w.open("a", domain.ElementLink, "href", dest)
+		} else {
+			w.close("a")
+		}
+	case isWebURL(dest):
+		if entering {
+			w.open("span", domain.ElementLink)
+			return
+		}
+		w.close("span")
+		w.linkRef(dest, plainText(n, w.src))
+	}
+	// Anything else (relative paths, anchors, mailto) keeps only its text.
+}
+
+func (w *htmlWriter) autoLink(n *ast.AutoLink) {
+	dest := string(n.URL(w.src))
+	label := string(n.Label(w.src))
+	if isWeChatArticle(dest) {
+		w.open("a", domain.ElementLink, "href", dest)
+	} else {
+		// The URL is already visible, so it needs no reference.
+		w.open("span", domain.ElementLink)
+	}
+	w.buf.WriteString(html.EscapeString(label))
+	if isWeChatArticle(dest) {
+		w.close("a")
+	} else {
+		w.close("span")
+	}
+}
+
+func (w *htmlWriter) linkRef(dest, label string) {
+	index, ok := w.refs[dest]
+	if !ok {
+		index = len(w.references) + 1
+		w.refs[dest] = index
+		w.references = append(w.references, domain.LinkReference{Index: index, Text: label, URL: dest})
+	}
+	w.open("sup", domain.ElementLinkRef)
+	fmt.Fprintf(&w.buf, "[%d]", index)
+	w.close("sup")
+}
+
+func (w *htmlWriter) writeReferences() {
+	if len(w.references) == 0 {
+		return
+	}
+	w.open("section", domain.ElementReferences)
+	w.buf.WriteByte('\n')
+	w.open("p", domain.ElementReferencesTitle)
+	w.buf.WriteString(html.EscapeString(w.opts.ReferencesTitle))
+	w.close("p")
+	w.buf.WriteByte('\n')
+	for _, ref := range w.references {
+		w.open("p", domain.ElementReference)
+		fmt.Fprintf(&w.buf, "[%d] ", ref.Index)
+		if ref.Text != "" && ref.Text != ref.URL {
+			w.buf.WriteString(html.EscapeString(ref.Text) + "：")
+		}
+		w.buf.WriteString(html.EscapeString(ref.URL))
+		w.close("p")
+		w.buf.WriteByte('\n')
+	}
+	w.close("section")
+	w.buf.WriteByte('\n')
+}
+
+func (w *htmlWriter) image(n *ast.Image) {
+	src := destination(n.Destination)
+	img := domain.RenderedImage{Index: len(w.images) + 1, Src: src, Alt: plainText(n, w.src)}
+	if uploaded, ok := w.opts.Images[src]; ok && uploaded != "" {
+		img.URL = uploaded
+	} else if isWeChatImage(src) {
+		img.URL = src
+	}
+	w.images = append(w.images, img)
+
+	if img.URL != "" {
+		w.open("img", domain.ElementImage, "src", img.URL, "alt", img.Alt)
+		return
+	}
+	w.open("span", domain.ElementImagePlaceholder, "data-image", strconv.Itoa(img.Index))
+	label := fmt.Sprintf("图片 %d", img.Index)
+	if img.Alt != "" {
+		label += "：" + img.Alt
+	}
+	w.buf.WriteString(html.EscapeString(label))
+	w.close("span")
+}
+
+// plainText is the text of n's inline children, without markup.
+func plainText(n ast.Node, src []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			b.Write(textValue(c, src))
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(c.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// textValue is the content of a text node with backslash escapes and
+// entity and numeric character references resolved. Code keeps its raw text.
+func textValue(n *ast.Text, src []byte) []byte {
+	value := n.Segment.Value(src)
+	if n.IsRaw() {
+		return value
+	}
+	return util.ResolveEntityNames(util.ResolveNumericReferences(util.UnescapePunctuations(value)))
+}
+
+// destination resolves escapes and references in a link or image target.
+func destination(dest []byte) string {
+	return string(util.ResolveEntityNames(util.ResolveNumericReferences(util.UnescapePunctuations(dest))))
+}
+
+// rawText is the verbatim text of a code span.
+func rawText(n ast.Node, src []byte) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if t, ok := c.(*ast.Text); ok {
+			b.Write(t.Segment.Value(src))
+		}
+	}
+	return b.String()
+}
+
+func joinStyles(a, b string) string {
+	a = strings.TrimRight(strings.TrimSpace(a), ";")
+	if a == "" {
+		return b
+	}
+	return a + "; " + b
+}
+
+func isWide(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r >= 0x3000 && r <= 0x303f || r >= 0xff00 && r <= 0xffef
+}
+
+func hostOf(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return strings.ToLower(u.Hostname()), true
+}
+
+func isWebURL(raw string) bool {
+	_, ok := hostOf(raw)
+	return ok
+}
+
+// isWeChatArticle reports links WeChat keeps clickable: other articles on
+// the official account platform.
+func isWeChatArticle(raw string) bool {
+	host, ok := hostOf(raw)
+	return ok && host == "mp.weixin.qq.com"
+}
+
+// isWeChatImage reports images already on the WeChat CDN.
+func isWeChatImage(raw string) bool {
+	host, ok := hostOf(raw)
+	if !ok {
+		return false
+	}
+	for _, cdn := range []string{"qpic.cn", "qlogo.cn"} {
+		if host == cdn || strings.HasSuffix(host, "."+cdn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+﻿package data_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/data"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestMarkdownRenderer_Golden renders each testdata/*.md with the default
+// theme and compares it with the .golden.html next to it. Run with -update
+// after an intended change and review the diff.
+func TestMarkdownRenderer_Golden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.md"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no golden inputs: %v", err)
+	}
+	r := data.NewMarkdownRenderer()
+	for _, in := range inputs {
+		name := strings.TrimSuffix(filepath.Base(in), ".md")
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(in)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			got, err := r.Render(context.Background(), string(src), domain.RenderOptions{
+				Theme:  domain.DefaultTheme(),
+				Images: map[string]string{"./local.png": "https://mmbiz.qpic.cn/mmbiz_png/local/0"},
+			})
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			golden := filepath.Join("testdata", name+".golden.html")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got.HTML), 0o644); err != nil {
+					t.Fatalf("update: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden: %v", err)
+			}
+			if got.HTML != string(want) {
+				t.Fatalf("output differs from %s:\n%s", golden, got.HTML)
+			}
+		})
+	}
+}
+
+func TestMarkdownRenderer_ReportsReferencesAndImages(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "links_images.md"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	got, err := data.NewMarkdownRenderer().Render(context.Background(), string(src), domain.RenderOptions{Theme: domain.Theme{Name: "plain"}})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	wantRefs := []domain.LinkReference{
+		{Index: 1, Text: "Go 官网", URL: "https://go.dev"},
+		{Index: 2, Text: "文档", URL: "https://pkg.go.dev/net/http"},
+	}
+	if !reflect.DeepEqual(got.References, wantRefs) {
+		t.Fatalf("unexpected references: %+v", got.References)
+	}
+	pending := got.PendingImages()
+	if len(got.Images) != 3 || len(pending) != 2 || pending[1].Src != "./local.png" || pending[0].Alt != "封面图" {
+		t.Fatalf("unexpected images: %+v", got.Images)
+	}
+	if strings.Contains(got.HTML, "style=") || strings.Contains(got.HTML, "https://example.com/cover.png") {
+		t.Fatalf("expected no styles and no external image URL:\n%s", got.HTML)
+	}
+	if !strings.Contains(got.HTML, `<a href="https://mp.weixin.qq.com/s/abc">`) || strings.Contains(got.HTML, `href="https://go.dev"`) {
+		t.Fatalf("only WeChat links may stay clickable:\n%s", got.HTML)
+	}
+}
+
+func TestMarkdownRenderer_RejectsUnsafeTheme(t *testing.T) {
+	theme := domain.DefaultTheme()
+	theme.Styles[domain.ElementParagraph] = "background: url(https://example.com/x.png)"
+	_, err := data.NewMarkdownRenderer().Render(context.Background(), "text", domain.RenderOptions{Theme: theme})
+	if !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
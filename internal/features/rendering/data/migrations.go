@@ -0,0 +1,23 @@
+﻿package data
+
+import "github.com/Xiaoxinkeji/WX/internal/core/migrate"
+
+// MigrationFeature is the key the rendering schema is versioned under in
+// schema_migrations.
+const MigrationFeature = "rendering"
+
+// Migrations returns the ordered rendering schema migrations. Append new
+// steps; never edit one that has shipped.
+func Migrations() []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "theme assignments", Up: migrate.SQL(`
+CREATE TABLE IF NOT EXISTS theme_assignments (
+	scope TEXT NOT NULL,
+	scope_id TEXT NOT NULL,
+	theme TEXT NOT NULL,
+	updated_at_ms INTEGER NOT NULL,
+	PRIMARY KEY(scope, scope_id)
+);
+`)},
+	}
+}
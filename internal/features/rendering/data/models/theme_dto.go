@@ -0,0 +1,30 @@
+﻿package models
+
+import (
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+// ThemeDTO is a theme as written in a theme file. Styles are keyed by
+// element name.
+type ThemeDTO struct {
+	Name    string            `json:"name" yaml:"name"`
+	Title   string            `json:"title" yaml:"title"`
+	Extends string            `json:"extends" yaml:"extends"`
+	Styles  map[string]string `json:"styles" yaml:"styles"`
+}
+
+// ToDomain builds the theme on top of base: elements the DTO does not set
+// keep the base style, and an empty style removes it.
+func (d ThemeDTO) ToDomain(base domain.Theme) (domain.Theme, error) {
+	t := base.Clone()
+	t.Name = d.Name
+	t.Title = d.Title
+	for e, style := range d.Styles {
+		if style == "" {
+			delete(t.Styles, domain.Element(e))
+			continue
+		}
+		t.Styles[domain.Element(e)] = style
+	}
+	return t, domain.ValidateTheme(t)
+}
@@ -0,0 +1,120 @@
+﻿package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/core/migrate"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+// SQLiteThemeAssignmentStore keeps the theme chosen per account and per
+// article. It stores theme names only; a theme removed from the theme files
+// leaves its assignments in place for the caller to fall back from.
+type SQLiteThemeAssignmentStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteThemeAssignmentStore(db *sql.DB) (*SQLiteThemeAssignmentStore, error) {
+	if db == nil {
+		return nil, errors.New("theme assignment store: db is nil")
+	}
+	s := &SQLiteThemeAssignmentStore{db: db}
+	if err := s.EnsureSchema(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLiteThemeAssignmentStore) EnsureSchema(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return migrate.Apply(ctx, s.db, MigrationFeature, Migrations())
+}
+
+func (s *SQLiteThemeAssignmentStore) SetTheme(ctx context.Context, a domain.ThemeAssignment) error {
+	if err := validateScope(a.Scope, a.ScopeID); err != nil {
+		return err
+	}
+	if a.Theme == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("theme is required"))
+	}
+	updatedAt := a.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO theme_assignments(scope, scope_id, theme, updated_at_ms) VALUES(?, ?, ?, ?)
+ON CONFLICT(scope, scope_id) DO UPDATE SET theme = excluded.theme, updated_at_ms = excluded.updated_at_ms
+`, string(a.Scope), a.ScopeID, a.Theme, updatedAt.UTC().UnixMilli())
+	return err
+}
+
+func (s *SQLiteThemeAssignmentStore) GetThemeAssignment(ctx context.Context, scope domain.ThemeScope, scopeID string) (domain.ThemeAssignment, error) {
+	if err := validateScope(scope, scopeID); err != nil {
+		return domain.ThemeAssignment{}, err
+	}
+	a := domain.ThemeAssignment{Scope: scope, ScopeID: scopeID}
+	var updatedMs int64
+	err := s.db.QueryRowContext(ctx, `SELECT theme, updated_at_ms FROM theme_assignments WHERE scope = ? AND scope_id = ?`, string(scope), scopeID).Scan(&a.Theme, &updatedMs)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ThemeAssignment{}, domain.ErrNotFound
+		}
+		return domain.ThemeAssignment{}, err
+	}
+	a.UpdatedAt = time.UnixMilli(updatedMs).UTC()
+	return a, nil
+}
+
+func (s *SQLiteThemeAssignmentStore) ClearTheme(ctx context.Context, scope domain.ThemeScope, scopeID string) error {
+	if err := validateScope(scope, scopeID); err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM theme_assignments WHERE scope = ? AND scope_id = ?`, string(scope), scopeID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteArticleThemesTx removes the article assignments of articleIDs in tx.
+// It matches the articles repository's purge hook, so that purged articles do
+// not leave a theme behind for a later article with the same ID:
+//
+//	articlesData.WithPurgeHook(store.DeleteArticleThemesTx)
+func (s *SQLiteThemeAssignmentStore) DeleteArticleThemesTx(ctx context.Context, tx *sql.Tx, articleIDs []string) error {
+	if tx == nil {
+		return errors.New("theme assignment store: tx is nil")
+	}
+	if len(articleIDs) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(articleIDs)+1)
+	args = append(args, string(domain.ThemeScopeArticle))
+	for _, id := range articleIDs {
+		args = append(args, id)
+	}
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(articleIDs)), ", ")
+	_, err := tx.ExecContext(ctx, `DELETE FROM theme_assignments WHERE scope = ? AND scope_id IN (`+marks+`)`, args...)
+	return err
+}
+
+func validateScope(scope domain.ThemeScope, scopeID string) error {
+	if !scope.Valid() {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("invalid scope"))
+	}
+	if scopeID == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("scope id is required"))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+﻿package data_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/data"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	name := fmt.Sprintf("file:rendering_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := sql.Open("sqlite", name)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestSQLiteThemeAssignmentStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := data.NewSQLiteThemeAssignmentStore(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	at := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	if _, err := store.GetThemeAssignment(ctx, domain.ThemeScopeArticle, "a1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := store.SetTheme(ctx, domain.ThemeAssignment{Scope: "column", ScopeID: "a1", Theme: "tech"}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for an unknown scope, got %v", err)
+	}
+	for _, a := range []domain.ThemeAssignment{
+		{Scope: domain.ThemeScopeArticle, ScopeID: "a1", Theme: "tech", UpdatedAt: at},
+		{Scope: domain.ThemeScopeAccount, ScopeID: "a1", Theme: "default", UpdatedAt: at},
+		{Scope: domain.ThemeScopeArticle, ScopeID: "a1", Theme: "tech-dark", UpdatedAt: at.Add(time.Hour)},
+	} {
+		if err := store.SetTheme(ctx, a); err != nil {
+			t.Fatalf("set %+v: %v", a, err)
+		}
+	}
+	got, err := store.GetThemeAssignment(ctx, domain.ThemeScopeArticle, "a1")
+	want := domain.ThemeAssignment{Scope: domain.ThemeScopeArticle, ScopeID: "a1", Theme: "tech-dark", UpdatedAt: at.Add(time.Hour)}
+	if err != nil || got != want {
+		t.Fatalf("expected the latest article theme, got %+v (%v)", got, err)
+	}
+
+	if err := store.ClearTheme(ctx, domain.ThemeScopeArticle, "a1"); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if err := store.ClearTheme(ctx, domain.ThemeScopeArticle, "a1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound clearing twice, got %v", err)
+	}
+	if got, err := store.GetThemeAssignment(ctx, domain.ThemeScopeAccount, "a1"); err != nil || got.Theme != "default" {
+		t.Fatalf("clearing the article must keep the account theme: %+v (%v)", got, err)
+	}
+}
@@ -0,0 +1,66 @@
+﻿package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound        = errors.New("rendering: not found")
+	ErrInvalidArgument = errors.New("rendering: invalid argument")
+)
+
+// DefaultReferencesTitle heads the list of links that WeChat would not let
+// readers click.
+const DefaultReferencesTitle = "参考资料"
+
+type RenderOptions struct {
+	Theme Theme
+	// Images maps image sources in the Markdown to URLs already uploaded to
+	// WeChat. Other images, unless already on the WeChat CDN, are rendered
+	// as placeholders.
+	Images map[string]string
+	// ReferencesTitle defaults to DefaultReferencesTitle.
+	ReferencesTitle string
+}
+
+// RenderedImage is an image in the order it appears. URL is empty while the
+// image is still a placeholder.
+type RenderedImage struct {
+	Index int // 1-based, matches the placeholder's data-image attribute
+	Src   string
+	Alt   string
+	URL   string
+}
+
+// LinkReference is a link outside WeChat, rendered as plain text followed by
+// [Index] and listed at the end of the article. Repeated URLs share an index.
+type LinkReference struct {
+	Index int
+	Text  string
+	URL   string
+}
+
+type Rendered struct {
+	Theme      string // name of the theme it was rendered with
+	HTML       string
+	Images     []RenderedImage
+	References []LinkReference
+}
+
+// PendingImages returns the images still rendered as placeholders.
+func (r Rendered) PendingImages() []RenderedImage {
+	var out []RenderedImage
+	for _, img := range r.Images {
+		if img.URL == "" {
+			out = append(out, img)
+		}
+	}
+	return out
+}
+
+// Renderer turns Markdown article content into HTML the WeChat editor
+// accepts: inline styles only and no raw HTML.
+type Renderer interface {
+	Render(ctx context.Context, markdown string, opts RenderOptions) (Rendered, error)
+}
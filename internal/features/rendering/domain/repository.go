@@ -0,0 +1,45 @@
+﻿package domain
+
+import (
+	"context"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+// ThemeRepository serves the themes that can be chosen. The default theme is
+// always available.
+type ThemeRepository interface {
+	GetTheme(ctx context.Context, name string) (Theme, error)
+	// ListThemes lists by name, the default theme included.
+	ListThemes(ctx context.Context) ([]Theme, error)
+}
+
+type ThemeScope string
+
+const (
+	ThemeScopeAccount ThemeScope = "account"
+	ThemeScopeArticle ThemeScope = "article"
+)
+
+func (s ThemeScope) Valid() bool {
+	return s == ThemeScopeAccount || s == ThemeScopeArticle
+}
+
+// ThemeAssignment is the theme chosen for one account or one article. An
+// article's own theme wins over its account's.
+type ThemeAssignment struct {
+	Scope     ThemeScope
+	ScopeID   string
+	Theme     string
+	UpdatedAt time.Time
+}
+
+type ThemeAssignmentStore interface {
+	// SetTheme replaces any theme already chosen for the scope.
+	SetTheme(ctx context.Context, a ThemeAssignment) error
+	GetThemeAssignment(ctx context.Context, scope ThemeScope, scopeID string) (ThemeAssignment, error)
+	ClearTheme(ctx context.Context, scope ThemeScope, scopeID string) error
+}
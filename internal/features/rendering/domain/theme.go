@@ -0,0 +1,220 @@
+﻿package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Element names a part of the rendered article that a theme can style.
+type Element string
+
+const (
+	ElementRoot       Element = "root" // the <section> wrapping the article
+	ElementH1         Element = "h1"
+	ElementH2         Element = "h2"
+	ElementH3         Element = "h3"
+	ElementH4         Element = "h4"
+	ElementH5         Element = "h5"
+	ElementH6         Element = "h6"
+	ElementParagraph  Element = "p"
+	ElementStrong     Element = "strong"
+	ElementEmphasis   Element = "em"
+	ElementDelete     Element = "del"
+	ElementLink       Element = "link"     // link text, clickable or not
+	ElementLinkRef    Element = "link_ref" // the [n] after a non-clickable link
+	ElementBlockquote Element = "blockquote"
+	ElementList       Element = "ul"
+	ElementOrdered    Element = "ol"
+	ElementListItem   Element = "li"
+	ElementCode       Element = "code" // inline code
+	ElementPre        Element = "pre"  // code blocks
+	ElementRule       Element = "hr"
+	ElementTable      Element = "table"
+	ElementTableHead  Element = "th"
+	ElementTableCell  Element = "td"
+	ElementImage      Element = "img"
+
+	ElementImagePlaceholder Element = "image_placeholder"
+	ElementReferences       Element = "references"
+	ElementReferencesTitle  Element = "references_title"
+	ElementReference        Element = "reference"
+
+	ElementCodeKeyword Element = "code_keyword"
+	ElementCodeString  Element = "code_string"
+	ElementCodeComment Element = "code_comment"
+	ElementCodeNumber  Element = "code_number"
+)
+
+// Elements lists every element a theme can style.
+var Elements = []Element{
+	ElementRoot, ElementH1, ElementH2, ElementH3, ElementH4, ElementH5, ElementH6,
+	ElementParagraph, ElementStrong, ElementEmphasis, ElementDelete, ElementLink, ElementLinkRef,
+	ElementBlockquote, ElementList, ElementOrdered, ElementListItem, ElementCode, ElementPre,
+	ElementRule, ElementTable, ElementTableHead, ElementTableCell, ElementImage,
+	ElementImagePlaceholder, ElementReferences, ElementReferencesTitle, ElementReference,
+	ElementCodeKeyword, ElementCodeString, ElementCodeComment, ElementCodeNumber,
+}
+
+func (e Element) Valid() bool {
+	for _, known := range Elements {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	MaxThemeNameLength  = 64
+	MaxThemeTitleLength = 64 // runes
+	MaxStyleLength      = 1000
+)
+
+// Theme is the inline CSS written onto each element. WeChat drops <style>
+// blocks, classes and external stylesheets, so a theme is nothing more than
+// a style attribute per element; elements without one are left unstyled.
+type Theme struct {
+	Name   string
+	Title  string // display name, e.g. the column it is used for
+	Styles map[Element]string
+}
+
+func (t Theme) Style(e Element) string {
+	return t.Styles[e]
+}
+
+// Clone returns a copy that does not share the style map.
+func (t Theme) Clone() Theme {
+	styles := make(map[Element]string, len(t.Styles))
+	for e, style := range t.Styles {
+		styles[e] = style
+	}
+	t.Styles = styles
+	return t
+}
+
+var (
+	themeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	propertyPattern  = regexp.MustCompile(`^-?[a-z][a-z-]*$`)
+)
+
+// allowedStyleProperties are the CSS properties a theme may set. The list
+// follows what the WeChat editor keeps; properties that take an element out
+// of the flow (position, float, z-index) or hide text are left out.
+var allowedStyleProperties = map[string]bool{
+	"background": true, "background-color": true,
+	"border": true, "border-top": true, "border-right": true, "border-bottom": true, "border-left": true,
+	"border-color": true, "border-style": true, "border-width": true, "border-radius": true, "border-collapse": true,
+	"box-shadow": true, "color": true, "display": true,
+	"font-family": true, "font-size": true, "font-style": true, "font-weight": true,
+	"height": true, "letter-spacing": true, "line-height": true, "list-style-type": true,
+	"margin": true, "margin-top": true, "margin-right": true, "margin-bottom": true, "margin-left": true,
+	"max-width": true, "min-width": true, "overflow-x": true, "overflow-wrap": true,
+	"padding": true, "padding-top": true, "padding-right": true, "padding-bottom": true, "padding-left": true,
+	"text-align": true, "text-decoration": true, "text-indent": true, "text-shadow": true,
+	"vertical-align": true, "white-space": true, "width": true, "word-break": true,
+}
+
+// unsafeStyleValues are rejected anywhere in a declaration value; WeChat
+// strips them and a browser preview must not load anything either.
+var unsafeStyleValues = []string{"url(", "expression(", "javascript:", "@import", "<", ">", "\"", "\\", "{", "}"}
+
+func ValidateTheme(t Theme) error {
+	if !themeNamePattern.MatchString(t.Name) {
+		return errors.New("theme name must be lowercase letters, digits, '-' or '_'")
+	}
+	if len(t.Name) > MaxThemeNameLength {
+		return fmt.Errorf("theme name too long: max %d characters, got %d", MaxThemeNameLength, len(t.Name))
+	}
+	if n := utf8.RuneCountInString(t.Title); n > MaxThemeTitleLength {
+		return fmt.Errorf("theme title too long: max %d characters, got %d", MaxThemeTitleLength, n)
+	}
+	for e, style := range t.Styles {
+		if !e.Valid() {
+			return fmt.Errorf("unknown element %q", e)
+		}
+		if err := ValidateStyle(style); err != nil {
+			return fmt.Errorf("%s: %w", e, err)
+		}
+	}
+	return nil
+}
+
+// ValidateStyle checks an inline style of "property: value" declarations
+// separated by semicolons.
+func ValidateStyle(style string) error {
+	if len(style) > MaxStyleLength {
+		return fmt.Errorf("style too long: max %d characters, got %d", MaxStyleLength, len(style))
+	}
+	for _, decl := range strings.Split(style, ";") {
+		decl = strings.TrimSpace(decl)
+		if decl == "" {
+			continue
+		}
+		prop, value, ok := strings.Cut(decl, ":")
+		if !ok || strings.TrimSpace(value) == "" {
+			return fmt.Errorf("invalid declaration %q", decl)
+		}
+		prop = strings.TrimSpace(prop)
+		if !propertyPattern.MatchString(prop) {
+			return fmt.Errorf("invalid property %q", prop)
+		}
+		if !allowedStyleProperties[prop] {
+			return fmt.Errorf("property %q is not allowed", prop)
+		}
+		lower := strings.ToLower(value)
+		for _, bad := range unsafeStyleValues {
+			if strings.Contains(lower, bad) {
+				return fmt.Errorf("unsupported value %q", strings.TrimSpace(value))
+			}
+		}
+	}
+	return nil
+}
+
+// DefaultTheme is used when no theme is chosen.
+func DefaultTheme() Theme {
+	return Theme{
+		Name:  "default",
+		Title: "默认",
+		Styles: map[Element]string{
+			ElementRoot:       "font-size: 16px; color: #333; line-height: 1.75; letter-spacing: 0.5px; word-break: break-word",
+			ElementH1:         "margin: 1.2em 0 0.8em; font-size: 22px; font-weight: bold; text-align: center",
+			ElementH2:         "margin: 1.2em 0 0.8em; padding-left: 8px; border-left: 4px solid #07c160; font-size: 20px; font-weight: bold",
+			ElementH3:         "margin: 1em 0 0.6em; font-size: 18px; font-weight: bold",
+			ElementH4:         "margin: 1em 0 0.6em; font-size: 16px; font-weight: bold",
+			ElementH5:         "margin: 1em 0 0.6em; font-size: 16px; font-weight: bold",
+			ElementH6:         "margin: 1em 0 0.6em; font-size: 16px; font-weight: bold; color: #666",
+			ElementParagraph:  "margin: 0 0 1em",
+			ElementStrong:     "font-weight: bold",
+			ElementEmphasis:   "font-style: italic",
+			ElementDelete:     "text-decoration: line-through; color: #999",
+			ElementLink:       "color: #576b95",
+			ElementLinkRef:    "font-size: 12px; color: #576b95",
+			ElementBlockquote: "margin: 1em 0; padding: 0.6em 1em; border-left: 4px solid #dcdcdc; background: #f7f7f7; color: #666",
+			ElementList:       "margin: 0 0 1em; padding-left: 1.5em; list-style-type: disc",
+			ElementOrdered:    "margin: 0 0 1em; padding-left: 1.5em; list-style-type: decimal",
+			ElementListItem:   "margin: 0.3em 0",
+			ElementCode:       "padding: 2px 4px; border-radius: 4px; background: #f3f3f3; color: #d14; font-family: Menlo, Consolas, monospace; font-size: 14px",
+			ElementPre:        "margin: 1em 0; padding: 1em; border-radius: 6px; background: #f6f8fa; color: #24292e; font-family: Menlo, Consolas, monospace; font-size: 13px; line-height: 1.6; overflow-x: auto",
+			ElementRule:       "margin: 1.5em 0; border: 0; border-top: 1px solid #e5e5e5",
+			ElementTable:      "width: 100%; margin: 1em 0; border-collapse: collapse; font-size: 14px",
+			ElementTableHead:  "padding: 6px 10px; border: 1px solid #dcdcdc; background: #f7f7f7; font-weight: bold",
+			ElementTableCell:  "padding: 6px 10px; border: 1px solid #dcdcdc",
+			ElementImage:      "display: block; max-width: 100%; margin: 1em auto",
+
+			ElementImagePlaceholder: "display: block; margin: 1em 0; padding: 2em 0; border: 1px dashed #ccc; color: #999; font-size: 14px; text-align: center",
+			ElementReferences:       "margin-top: 2em; font-size: 13px; color: #888",
+			ElementReferencesTitle:  "margin: 0 0 0.5em; font-weight: bold",
+			ElementReference:        "margin: 0 0 0.3em; word-break: break-all",
+
+			ElementCodeKeyword: "color: #d73a49",
+			ElementCodeString:  "color: #032f62",
+			ElementCodeComment: "color: #6a737d; font-style: italic",
+			ElementCodeNumber:  "color: #005cc5",
+		},
+	}
+}
@@ -0,0 +1,30 @@
+﻿package domain_test
+
+import (
+	"testing"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+func TestValidateTheme(t *testing.T) {
+	if err := domain.ValidateTheme(domain.DefaultTheme()); err != nil {
+		t.Fatalf("default theme: %v", err)
+	}
+	for name, theme := range map[string]domain.Theme{
+		"missing name":    {},
+		"unknown element": {Name: "x", Styles: map[domain.Element]string{"div": "color: red"}},
+		"no value":        {Name: "x", Styles: map[domain.Element]string{domain.ElementParagraph: "color:"}},
+		"bad property":    {Name: "x", Styles: map[domain.Element]string{domain.ElementParagraph: "Color!: red"}},
+		"url":             {Name: "x", Styles: map[domain.Element]string{domain.ElementParagraph: "background: URL(x.png)"}},
+		"not allowed":     {Name: "x", Styles: map[domain.Element]string{domain.ElementParagraph: "position: fixed"}},
+		"breaks out":      {Name: "x", Styles: map[domain.Element]string{domain.ElementParagraph: `color: red" onclick="x`}},
+	} {
+		if err := domain.ValidateTheme(theme); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+	ok := domain.Theme{Name: "x", Styles: map[domain.Element]string{domain.ElementParagraph: " color: #333;; letter-spacing: 1px; "}}
+	if err := domain.ValidateTheme(ok); err != nil {
+		t.Fatalf("expected a valid theme, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	articles "github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+// MaxPreviewThemes caps how many themes one preview renders.
+const MaxPreviewThemes = 8
+
+type PreviewThemesInput struct {
+	ArticleID string
+	// Themes are rendered in the order given; empty means the first
+	// MaxPreviewThemes themes in ListThemes order.
+	Themes          []string
+	Images          map[string]string
+	ReferencesTitle string
+}
+
+// PreviewThemesUseCase renders one article in several themes so they can
+// be compared side by side.
+type PreviewThemesUseCase struct {
+	Renderer domain.Renderer
+	Articles articles.ArticleGetter
+	Themes   domain.ThemeRepository
+}
+
+func NewPreviewThemesUseCase(renderer domain.Renderer, getter articles.ArticleGetter, themes domain.ThemeRepository) PreviewThemesUseCase {
+	return PreviewThemesUseCase{Renderer: renderer, Articles: getter, Themes: themes}
+}
+
+func (uc PreviewThemesUseCase) Execute(ctx context.Context, in PreviewThemesInput) ([]domain.Rendered, error) {
+	if uc.Renderer == nil {
+		return nil, errors.New("preview themes: renderer is nil")
+	}
+	if uc.Articles == nil {
+		return nil, errors.New("preview themes: articles is nil")
+	}
+	if uc.Themes == nil {
+		return nil, errors.New("preview themes: themes is nil")
+	}
+	if in.ArticleID == "" {
+		return nil, errors.Join(domain.ErrInvalidArgument, errors.New("article id is required"))
+	}
+	if len(in.Themes) > MaxPreviewThemes {
+		return nil, errors.Join(domain.ErrInvalidArgument, fmt.Errorf("too many themes: max %d, got %d", MaxPreviewThemes, len(in.Themes)))
+	}
+
+	var themes []domain.Theme
+	if len(in.Themes) == 0 {
+		all, err := uc.Themes.ListThemes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(all) > MaxPreviewThemes {
+			all = all[:MaxPreviewThemes]
+		}
+		themes = all
+	} else {
+		seen := make(map[string]bool, len(in.Themes))
+		for _, name := range in.Themes {
+			t, err := uc.Themes.GetTheme(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			if seen[t.Name] {
+				continue
+			}
+			seen[t.Name] = true
+			themes = append(themes, t)
+		}
+	}
+
+	article, err := uc.Articles.GetArticle(ctx, in.ArticleID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.Rendered, 0, len(themes))
+	for _, t := range themes {
+		r, err := uc.Renderer.Render(ctx, article.Content, domain.RenderOptions{
+			Theme:           t,
+			Images:          in.Images,
+			ReferencesTitle: in.ReferencesTitle,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: %w", t.Name, err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
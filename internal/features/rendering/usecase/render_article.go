@@ -0,0 +1,90 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	articles "github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+type RenderArticleInput struct {
+	ArticleID string
+	// AccountID is optional; its theme applies when the article has none.
+	AccountID string
+	// Theme is optional and overrides the stored choices.
+	Theme           string
+	Images          map[string]string
+	ReferencesTitle string
+}
+
+// RenderArticleUseCase renders an article's content with the theme chosen
+// for the article, else for its account, else the default theme. A stored
+// choice whose theme no longer exists is skipped.
+type RenderArticleUseCase struct {
+	Renderer    domain.Renderer
+	Articles    articles.ArticleGetter
+	Themes      domain.ThemeRepository
+	Assignments domain.ThemeAssignmentStore // optional
+}
+
+func NewRenderArticleUseCase(renderer domain.Renderer, getter articles.ArticleGetter, themes domain.ThemeRepository, assignments domain.ThemeAssignmentStore) RenderArticleUseCase {
+	return RenderArticleUseCase{Renderer: renderer, Articles: getter, Themes: themes, Assignments: assignments}
+}
+
+func (uc RenderArticleUseCase) Execute(ctx context.Context, in RenderArticleInput) (domain.Rendered, error) {
+	if uc.Renderer == nil {
+		return domain.Rendered{}, errors.New("render article: renderer is nil")
+	}
+	if uc.Articles == nil {
+		return domain.Rendered{}, errors.New("render article: articles is nil")
+	}
+	if uc.Themes == nil {
+		return domain.Rendered{}, errors.New("render article: themes is nil")
+	}
+	if in.ArticleID == "" {
+		return domain.Rendered{}, errors.Join(domain.ErrInvalidArgument, errors.New("article id is required"))
+	}
+
+	theme, err := uc.resolveTheme(ctx, in)
+	if err != nil {
+		return domain.Rendered{}, err
+	}
+	article, err := uc.Articles.GetArticle(ctx, in.ArticleID)
+	if err != nil {
+		return domain.Rendered{}, err
+	}
+	return uc.Renderer.Render(ctx, article.Content, domain.RenderOptions{
+		Theme:           theme,
+		Images:          in.Images,
+		ReferencesTitle: in.ReferencesTitle,
+	})
+}
+
+func (uc RenderArticleUseCase) resolveTheme(ctx context.Context, in RenderArticleInput) (domain.Theme, error) {
+	if in.Theme != "" {
+		return uc.Themes.GetTheme(ctx, in.Theme)
+	}
+	if uc.Assignments != nil {
+		scopes := []domain.ThemeAssignment{{Scope: domain.ThemeScopeArticle, ScopeID: in.ArticleID}}
+		if in.AccountID != "" {
+			scopes = append(scopes, domain.ThemeAssignment{Scope: domain.ThemeScopeAccount, ScopeID: in.AccountID})
+		}
+		for _, s := range scopes {
+			a, err := uc.Assignments.GetThemeAssignment(ctx, s.Scope, s.ScopeID)
+			if errors.Is(err, domain.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return domain.Theme{}, fmt.Errorf("%s theme: %w", s.Scope, err)
+			}
+			theme, err := uc.Themes.GetTheme(ctx, a.Theme)
+			if errors.Is(err, domain.ErrNotFound) {
+				continue
+			}
+			return theme, err
+		}
+	}
+	return uc.Themes.GetTheme(ctx, domain.DefaultTheme().Name)
+}
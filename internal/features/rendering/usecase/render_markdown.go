@@ -0,0 +1,46 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+type RenderMarkdownInput struct {
+	Content string
+	// Theme is nil for the default theme.
+	Theme           *domain.Theme
+	Images          map[string]string
+	ReferencesTitle string
+}
+
+type RenderMarkdownUseCase struct {
+	Renderer domain.Renderer
+}
+
+func NewRenderMarkdownUseCase(renderer domain.Renderer) RenderMarkdownUseCase {
+	return RenderMarkdownUseCase{Renderer: renderer}
+}
+
+func (uc RenderMarkdownUseCase) Execute(ctx context.Context, in RenderMarkdownInput) (domain.Rendered, error) {
+	if uc.Renderer == nil {
+		return domain.Rendered{}, errors.New("render markdown: renderer is nil")
+	}
+	if strings.TrimSpace(in.Content) == "" {
+		return domain.Rendered{}, errors.Join(domain.ErrInvalidArgument, errors.New("content is required"))
+	}
+	theme := domain.DefaultTheme()
+	if in.Theme != nil {
+		theme = *in.Theme
+	}
+	if err := domain.ValidateTheme(theme); err != nil {
+		return domain.Rendered{}, errors.Join(domain.ErrInvalidArgument, err)
+	}
+	return uc.Renderer.Render(ctx, in.Content, domain.RenderOptions{
+		Theme:           theme,
+		Images:          in.Images,
+		ReferencesTitle: in.ReferencesTitle,
+	})
+}
@@ -0,0 +1,98 @@
+﻿package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+)
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now().UTC() }
+
+type ListThemesUseCase struct {
+	Themes domain.ThemeRepository
+}
+
+func NewListThemesUseCase(themes domain.ThemeRepository) ListThemesUseCase {
+	return ListThemesUseCase{Themes: themes}
+}
+
+func (uc ListThemesUseCase) Execute(ctx context.Context) ([]domain.Theme, error) {
+	if uc.Themes == nil {
+		return nil, errors.New("list themes: themes is nil")
+	}
+	return uc.Themes.ListThemes(ctx)
+}
+
+type SetThemeInput struct {
+	Scope   domain.ThemeScope
+	ScopeID string // account or article ID
+	Theme   string
+}
+
+// SetThemeUseCase stores the theme an account or an article renders with.
+type SetThemeUseCase struct {
+	Themes domain.ThemeRepository
+	Store  domain.ThemeAssignmentStore
+	Clock  domain.Clock
+}
+
+func NewSetThemeUseCase(themes domain.ThemeRepository, store domain.ThemeAssignmentStore) SetThemeUseCase {
+	return SetThemeUseCase{Themes: themes, Store: store, Clock: systemClock{}}
+}
+
+func (uc SetThemeUseCase) Execute(ctx context.Context, in SetThemeInput) (domain.ThemeAssignment, error) {
+	if uc.Themes == nil {
+		return domain.ThemeAssignment{}, errors.New("set theme: themes is nil")
+	}
+	if uc.Store == nil {
+		return domain.ThemeAssignment{}, errors.New("set theme: store is nil")
+	}
+	if uc.Clock == nil {
+		uc.Clock = systemClock{}
+	}
+	if !in.Scope.Valid() {
+		return domain.ThemeAssignment{}, errors.Join(domain.ErrInvalidArgument, errors.New("invalid scope"))
+	}
+	if in.ScopeID == "" {
+		return domain.ThemeAssignment{}, errors.Join(domain.ErrInvalidArgument, errors.New("scope id is required"))
+	}
+	theme, err := uc.Themes.GetTheme(ctx, in.Theme)
+	if err != nil {
+		return domain.ThemeAssignment{}, err
+	}
+	a := domain.ThemeAssignment{Scope: in.Scope, ScopeID: in.ScopeID, Theme: theme.Name, UpdatedAt: uc.Clock.Now()}
+	if err := uc.Store.SetTheme(ctx, a); err != nil {
+		return domain.ThemeAssignment{}, err
+	}
+	return a, nil
+}
+
+type ClearThemeInput struct {
+	Scope   domain.ThemeScope
+	ScopeID string
+}
+
+type ClearThemeUseCase struct {
+	Store domain.ThemeAssignmentStore
+}
+
+func NewClearThemeUseCase(store domain.ThemeAssignmentStore) ClearThemeUseCase {
+	return ClearThemeUseCase{Store: store}
+}
+
+func (uc ClearThemeUseCase) Execute(ctx context.Context, in ClearThemeInput) error {
+	if uc.Store == nil {
+		return errors.New("clear theme: store is nil")
+	}
+	if !in.Scope.Valid() {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("invalid scope"))
+	}
+	if in.ScopeID == "" {
+		return errors.Join(domain.ErrInvalidArgument, errors.New("scope id is required"))
+	}
+	return uc.Store.ClearTheme(ctx, in.Scope, in.ScopeID)
+}
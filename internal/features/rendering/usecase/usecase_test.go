@@ -0,0 +1,197 @@
+﻿package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	articles "github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/usecase"
+)
+
+type rendererFake struct{ opts domain.RenderOptions }
+
+func (f *rendererFake) Render(ctx context.Context, markdown string, opts domain.RenderOptions) (domain.Rendered, error) {
+	f.opts = opts
+	return domain.Rendered{Theme: opts.Theme.Name, HTML: markdown}, nil
+}
+
+func TestRenderMarkdownUseCase_DefaultsTheme(t *testing.T) {
+	r := &rendererFake{}
+	uc := usecase.NewRenderMarkdownUseCase(r)
+	if _, err := uc.Execute(context.Background(), usecase.RenderMarkdownInput{Content: "# 标题"}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if r.opts.Theme.Name != "default" {
+		t.Fatalf("expected the default theme, got %q", r.opts.Theme.Name)
+	}
+}
+
+func TestRenderMarkdownUseCase_ValidatesInput(t *testing.T) {
+	uc := usecase.NewRenderMarkdownUseCase(&rendererFake{})
+	bad := domain.Theme{Name: "Bad Name"}
+	for _, in := range []usecase.RenderMarkdownInput{
+		{Content: "  "},
+		{Content: "text", Theme: &bad},
+	} {
+		if _, err := uc.Execute(context.Background(), in); !errors.Is(err, domain.ErrInvalidArgument) {
+			t.Fatalf("expected ErrInvalidArgument for %+v, got %v", in, err)
+		}
+	}
+}
+
+type articleGetterFake struct{}
+
+func (articleGetterFake) GetArticle(ctx context.Context, articleID string) (articles.Article, error) {
+	if articleID != "a1" {
+		return articles.Article{}, articles.ErrNotFound
+	}
+	return articles.Article{ID: articleID, Content: "# 标题"}, nil
+}
+
+type themesFake map[string]domain.Theme
+
+func (f themesFake) GetTheme(ctx context.Context, name string) (domain.Theme, error) {
+	t, ok := f[name]
+	if !ok {
+		return domain.Theme{}, domain.ErrNotFound
+	}
+	return t, nil
+}
+
+func (f themesFake) ListThemes(ctx context.Context) ([]domain.Theme, error) {
+	out := make([]domain.Theme, 0, len(f))
+	for _, t := range f {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+type assignmentsFake map[domain.ThemeScope]string
+
+func (f assignmentsFake) SetTheme(ctx context.Context, a domain.ThemeAssignment) error {
+	f[a.Scope] = a.Theme
+	return nil
+}
+
+func (f assignmentsFake) GetThemeAssignment(ctx context.Context, scope domain.ThemeScope, scopeID string) (domain.ThemeAssignment, error) {
+	name, ok := f[scope]
+	if !ok {
+		return domain.ThemeAssignment{}, domain.ErrNotFound
+	}
+	return domain.ThemeAssignment{Scope: scope, ScopeID: scopeID, Theme: name}, nil
+}
+
+func (f assignmentsFake) ClearTheme(ctx context.Context, scope domain.ThemeScope, scopeID string) error {
+	delete(f, scope)
+	return nil
+}
+
+func TestRenderArticleUseCase_ResolvesTheme(t *testing.T) {
+	themes := themesFake{"default": domain.DefaultTheme(), "tech": {Name: "tech"}, "news": {Name: "news"}}
+	assignments := assignmentsFake{}
+	uc := usecase.NewRenderArticleUseCase(&rendererFake{}, articleGetterFake{}, themes, assignments)
+	render := func(in usecase.RenderArticleInput) string {
+		t.Helper()
+		got, err := uc.Execute(context.Background(), in)
+		if err != nil {
+			t.Fatalf("execute %+v: %v", in, err)
+		}
+		return got.Theme
+	}
+
+	in := usecase.RenderArticleInput{ArticleID: "a1", AccountID: "acc"}
+	if got := render(in); got != "default" {
+		t.Fatalf("expected the default theme, got %q", got)
+	}
+	assignments[domain.ThemeScopeAccount] = "news"
+	if got := render(in); got != "news" {
+		t.Fatalf("expected the account theme, got %q", got)
+	}
+	assignments[domain.ThemeScopeArticle] = "tech"
+	if got := render(in); got != "tech" {
+		t.Fatalf("expected the article theme, got %q", got)
+	}
+	if got := render(usecase.RenderArticleInput{ArticleID: "a1", AccountID: "acc", Theme: "news"}); got != "news" {
+		t.Fatalf("expected the explicit theme, got %q", got)
+	}
+	// A theme removed from the files falls back to the next choice.
+	delete(themes, "tech")
+	if got := render(in); got != "news" {
+		t.Fatalf("expected to fall back to the account theme, got %q", got)
+	}
+
+	if _, err := uc.Execute(context.Background(), usecase.RenderArticleInput{ArticleID: "a1", Theme: "missing"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing theme, got %v", err)
+	}
+	if _, err := uc.Execute(context.Background(), usecase.RenderArticleInput{ArticleID: "a2"}); !errors.Is(err, articles.ErrNotFound) {
+		t.Fatalf("expected the article's ErrNotFound, got %v", err)
+	}
+}
+
+func TestSetThemeUseCase_RequiresExistingTheme(t *testing.T) {
+	assignments := assignmentsFake{}
+	uc := usecase.NewSetThemeUseCase(themesFake{"tech": {Name: "tech"}}, assignments)
+	if _, err := uc.Execute(context.Background(), usecase.SetThemeInput{Scope: domain.ThemeScopeAccount, ScopeID: "acc", Theme: "missing"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := uc.Execute(context.Background(), usecase.SetThemeInput{Scope: "column", ScopeID: "acc", Theme: "tech"}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+	if _, err := uc.Execute(context.Background(), usecase.SetThemeInput{Scope: domain.ThemeScopeAccount, ScopeID: "acc", Theme: "tech"}); err != nil || assignments[domain.ThemeScopeAccount] != "tech" {
+		t.Fatalf("set: %v (%v)", assignments, err)
+	}
+}
+
+func TestPreviewThemesUseCase_RendersEachTheme(t *testing.T) {
+	themes := themesFake{"default": domain.DefaultTheme(), "tech": {Name: "tech"}, "news": {Name: "news"}}
+	uc := usecase.NewPreviewThemesUseCase(&rendererFake{}, articleGetterFake{}, themes)
+	names := func(out []domain.Rendered) []string {
+		var got []string
+		for _, r := range out {
+			got = append(got, r.Theme)
+		}
+		return got
+	}
+
+	out, err := uc.Execute(context.Background(), usecase.PreviewThemesInput{ArticleID: "a1", Themes: []string{"news", "tech", "news"}})
+	if err != nil || !reflect.DeepEqual(names(out), []string{"news", "tech"}) || out[0].HTML != "# 标题" {
+		t.Fatalf("preview: %+v (%v)", out, err)
+	}
+	out, err = uc.Execute(context.Background(), usecase.PreviewThemesInput{ArticleID: "a1"})
+	if err != nil || !reflect.DeepEqual(names(out), []string{"default", "news", "tech"}) {
+		t.Fatalf("preview all: %+v (%v)", out, err)
+	}
+	if _, err := uc.Execute(context.Background(), usecase.PreviewThemesInput{ArticleID: "a1", Themes: []string{"missing"}}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPreviewThemesUseCase_CapsThemes(t *testing.T) {
+	themes := themesFake{}
+	var all []string
+	for i := 0; i < usecase.MaxPreviewThemes+2; i++ {
+		name := fmt.Sprintf("t%02d", i)
+		themes[name] = domain.Theme{Name: name}
+		all = append(all, name)
+	}
+	uc := usecase.NewPreviewThemesUseCase(&rendererFake{}, articleGetterFake{}, themes)
+
+	// The cap is checked before any theme is looked up.
+	missing := make([]string, usecase.MaxPreviewThemes+1)
+	for i := range missing {
+		missing[i] = "missing"
+	}
+	if _, err := uc.Execute(context.Background(), usecase.PreviewThemesInput{ArticleID: "a1", Themes: missing}); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+	out, err := uc.Execute(context.Background(), usecase.PreviewThemesInput{ArticleID: "a1"})
+	if err != nil || len(out) != usecase.MaxPreviewThemes || out[0].Theme != all[0] || out[len(out)-1].Theme != all[usecase.MaxPreviewThemes-1] {
+		t.Fatalf("expected the first %d themes, got %+v (%v)", usecase.MaxPreviewThemes, out, err)
+	}
+}
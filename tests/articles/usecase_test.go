@@ -94,3 +94,81 @@ func TestUseCases_EndToEnd(t *testing.T) {
 		t.Fatalf("delete: %v", err)
 	}
 }
+
+func TestScheduler_PublishesMissedJobsOnStartup(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo, err := data.NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	base := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	created, err := usecase.CreateArticleUseCase{Repo: repo, Clock: fixedClock{t: base}, IDs: fixedIDs{id: "a1"}}.Execute(ctx, usecase.CreateArticleInput{Title: "定时发布", Content: "正文"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := (usecase.SchedulePublishUseCase{Repo: repo, Clock: fixedClock{t: base}}).Execute(ctx, usecase.SchedulePublishInput{ID: created.ID, At: base.Add(time.Hour)}); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	// The app was closed past the publish time; a new repository and
+	// scheduler on the same database pick the job up.
+	restarted, err := data.NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	s := usecase.Scheduler{Jobs: restarted, Clock: fixedClock{t: base.Add(5 * time.Hour)}}
+	if n, err := s.RunDue(ctx); err != nil || n != 1 {
+		t.Fatalf("run due: %d (%v)", n, err)
+	}
+	got, err := restarted.GetArticle(ctx, created.ID)
+	if err != nil || got.Status != domain.ArticleStatusPublished || !got.UpdatedAt.Equal(base.Add(5*time.Hour)) {
+		t.Fatalf("expected published article, got %+v (%v)", got, err)
+	}
+	if n, err := s.RunDue(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing left to run, got %d (%v)", n, err)
+	}
+}
+
+func TestCollections_ListInSeriesOrder(t *testing.T) {
+	ctx := context.Background()
+	repo, err := data.NewSQLiteRepository(openTestDB(t))
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	base := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	for i, id := range []string{"p1", "p2", "p3"} {
+		createUC := usecase.CreateArticleUseCase{Repo: repo, Clock: fixedClock{t: base.Add(time.Duration(i) * time.Hour)}, IDs: fixedIDs{id: id}}
+		if _, err := createUC.Execute(ctx, usecase.CreateArticleInput{Title: "第" + id + "篇", Content: "正文"}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+
+	createUC := usecase.CreateCollectionUseCase{Repo: repo, Clock: fixedClock{t: base}, IDs: fixedIDs{id: "series"}}
+	series, err := createUC.Execute(ctx, usecase.CreateCollectionInput{Title: " 入门系列 ", ArticleIDs: []string{"p2", "p2", "p1"}})
+	if err != nil || series.Title != "入门系列" {
+		t.Fatalf("create collection: %+v (%v)", series, err)
+	}
+	if _, err := usecase.NewAddToCollectionUseCase(repo).Execute(ctx, usecase.CollectionMembersInput{CollectionID: series.ID, ArticleIDs: []string{"p3"}}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, err := usecase.NewReorderCollectionUseCase(repo).Execute(ctx, usecase.CollectionMembersInput{CollectionID: series.ID, ArticleIDs: []string{"p1", "p2", "p3"}}); err != nil {
+		t.Fatalf("reorder: %v", err)
+	}
+
+	listed, err := usecase.NewListArticlesUseCase(repo).Execute(ctx, usecase.ListArticlesInput{CollectionID: series.ID})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var ids []string
+	for _, a := range listed {
+		ids = append(ids, a.ID)
+	}
+	if fmt.Sprint(ids) != "[p1 p2 p3]" {
+		t.Fatalf("expected series order, got %v", ids)
+	}
+	in, err := usecase.NewArticleCollectionsUseCase(repo).Execute(ctx, "p3")
+	if err != nil || len(in) != 1 || in[0].ID != series.ID {
+		t.Fatalf("collections of p3: %+v (%v)", in, err)
+	}
+}
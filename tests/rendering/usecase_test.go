@@ -0,0 +1,129 @@
+﻿package rendering_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	articlesData "github.com/Xiaoxinkeji/WX/internal/features/articles/data"
+	articles "github.com/Xiaoxinkeji/WX/internal/features/articles/domain"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/data"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/domain"
+	"github.com/Xiaoxinkeji/WX/internal/features/rendering/usecase"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	name := fmt.Sprintf("file:rendering_suite_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := sql.Open("sqlite", name)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestThemes_RenderArticleWithStoredTheme(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo, err := articlesData.NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatalf("articles repo: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	if _, err := repo.CreateArticle(ctx, articles.CreateArticleParams{
+		ID: "a1", Title: "排版", Content: "## 小标题\n\n正文", Status: articles.ArticleStatusDraft, CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create article: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tech.yaml"), []byte("title: 技术专栏\nextends: default\nstyles:\n  h2: \"color: #1e6bb8\"\n"), 0o644); err != nil {
+		t.Fatalf("write theme: %v", err)
+	}
+	themes, err := data.NewFileThemeRepository(dir)
+	if err != nil {
+		t.Fatalf("themes: %v", err)
+	}
+	store, err := data.NewSQLiteThemeAssignmentStore(db)
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	renderer := data.NewMarkdownRenderer()
+
+	if _, err := usecase.NewSetThemeUseCase(themes, store).Execute(ctx, usecase.SetThemeInput{Scope: domain.ThemeScopeAccount, ScopeID: "acc", Theme: "tech"}); err != nil {
+		t.Fatalf("set theme: %v", err)
+	}
+	got, err := usecase.NewRenderArticleUseCase(renderer, repo, themes, store).Execute(ctx, usecase.RenderArticleInput{ArticleID: "a1", AccountID: "acc"})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got.Theme != "tech" || !strings.Contains(got.HTML, `<h2 style="color: #1e6bb8">小标题</h2>`) {
+		t.Fatalf("expected the account theme, got %s:\n%s", got.Theme, got.HTML)
+	}
+
+	previews, err := usecase.NewPreviewThemesUseCase(renderer, repo, themes).Execute(ctx, usecase.PreviewThemesInput{ArticleID: "a1"})
+	if err != nil || len(previews) != 2 || previews[0].HTML == previews[1].HTML {
+		t.Fatalf("expected two different previews: %+v (%v)", previews, err)
+	}
+}
+
+func TestThemes_PurgeRemovesArticleAssignments(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	store, err := data.NewSQLiteThemeAssignmentStore(db)
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	repo, err := articlesData.NewSQLiteRepository(db, articlesData.WithPurgeHook(store.DeleteArticleThemesTx))
+	if err != nil {
+		t.Fatalf("articles repo: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a1", "a2"} {
+		if _, err := repo.CreateArticle(ctx, articles.CreateArticleParams{
+			ID: id, Title: "排版", Content: "正文", Status: articles.ArticleStatusDraft, CreatedAt: now, UpdatedAt: now,
+		}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	themes, err := data.NewFileThemeRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("themes: %v", err)
+	}
+	set := usecase.NewSetThemeUseCase(themes, store)
+	for _, in := range []usecase.SetThemeInput{
+		{Scope: domain.ThemeScopeArticle, ScopeID: "a1", Theme: "default"},
+		{Scope: domain.ThemeScopeArticle, ScopeID: "a2", Theme: "default"},
+		{Scope: domain.ThemeScopeAccount, ScopeID: "a1", Theme: "default"},
+	} {
+		if _, err := set.Execute(ctx, in); err != nil {
+			t.Fatalf("set theme %+v: %v", in, err)
+		}
+	}
+
+	if err := repo.DeleteArticle(ctx, "a1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if n, err := repo.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("purge: %d (%v)", n, err)
+	}
+	if _, err := store.GetThemeAssignment(ctx, domain.ThemeScopeArticle, "a1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected the purged article's theme to be gone, got %v", err)
+	}
+	if _, err := store.GetThemeAssignment(ctx, domain.ThemeScopeArticle, "a2"); err != nil {
+		t.Fatalf("expected the live article's theme to stay, got %v", err)
+	}
+	if _, err := store.GetThemeAssignment(ctx, domain.ThemeScopeAccount, "a1"); err != nil {
+		t.Fatalf("expected the account theme with the same ID to stay, got %v", err)
+	}
+}